@@ -4,50 +4,169 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 	"github.com/eliooooooot/picky/internal/app"
+	"github.com/eliooooooot/picky/internal/config"
 	"github.com/eliooooooot/picky/internal/fs"
 )
 
 func main() {
 	var (
 		outputPath = flag.String("o", "selected.txt", "output file path")
+		format     = flag.String("format", "text", "output format: text or xml")
+		tokenizer  = flag.String("tokenizer", "naive", "token estimator: naive or words")
+		theme      = flag.String("theme", "auto", "color theme: auto, none, a built-in theme or one defined in config")
+		emoji      = flag.Bool("emoji", false, "show emoji icons in the tree")
+		budget     = flag.Int("budget", 0, "token budget for the fit-to-budget action (0 disables it)")
+		partTokens = flag.Int("max-tokens-per-part", 0, "split output into numbered parts of at most this many tokens (0 disables splitting)")
+		depsDepth  = flag.Int("deps-depth", 1, "how many import levels the dependency actions follow (0 means no limit)")
+		maxSize    = flag.Int("max-file-size", 1<<20, "size in bytes above which files are not tokenized and are cut down in output (0 disables it)")
+		largeFiles = flag.String("large-files", "head", "how files over -max-file-size are written: head, head-tail or skip")
+		lazy       = flag.Bool("lazy", false, "read directories only when they are expanded or selected, for huge repositories")
+		follow     = flag.Bool("follow-symlinks", true, "read through symbolic links; when false links are listed but not read")
+		redact     = flag.Bool("redact", true, "replace credentials found in file contents with [REDACTED:kind] in output")
+		transforms = flag.String("transforms", "", "comma-separated lossy content transforms: comments, doc-comments, blank-lines, license")
+		countTrans = flag.Bool("count-transformed", false, "count tokens in the tree after -transforms are applied")
+		outline    = flag.Bool("outline", false, "write files as outlines: declarations and signatures without bodies")
+		lineNums   = flag.Bool("line-numbers", false, "prefix each line of file contents with its line number in the file")
+		structure  = flag.String("structure", "full", "how much of the tree the directory structure lists: full, selected, collapsed or none")
+		structDep  = flag.Int("structure-depth", 2, "how many levels -structure collapsed lists")
 	)
 	flag.Parse()
-	
+
 	args := flag.Args()
-	
+
+	// "picky config [directory]" prints the effective configuration
+	showConfig := len(args) > 0 && args[0] == "config"
+	if showConfig {
+		args = args[1:]
+	}
+
 	// Default to current directory if no argument provided
 	rootPath := "."
 	if len(args) > 0 {
 		rootPath = args[0]
 	}
-	
+
+	// Only flags given on the command line override config files and env
+	var flags config.Layer
+	var flagNames []string
+	flag.Visit(func(f *flag.Flag) {
+		flagNames = append(flagNames, f.Name)
+		switch f.Name {
+		case "o":
+			flags.Output = outputPath
+		case "format":
+			flags.Format = format
+		case "tokenizer":
+			flags.Tokenizer = tokenizer
+		case "theme":
+			flags.Theme = theme
+		case "emoji":
+			flags.Emoji = emoji
+		case "budget":
+			flags.Budget = budget
+		case "max-tokens-per-part":
+			flags.MaxTokensPerPart = partTokens
+		case "deps-depth":
+			flags.DepsDepth = depsDepth
+		case "max-file-size":
+			flags.MaxFileSize = maxSize
+		case "large-files":
+			flags.LargeFiles = largeFiles
+		case "lazy":
+			flags.Lazy = lazy
+		case "follow-symlinks":
+			flags.FollowSymlinks = follow
+		case "redact":
+			flags.Redact = redact
+		case "transforms":
+			var names []string
+			for _, name := range strings.Split(*transforms, ",") {
+				if name = strings.TrimSpace(name); name != "" {
+					names = append(names, name)
+				}
+			}
+			flags.Transforms = &names
+		case "count-transformed":
+			flags.CountTransformed = countTrans
+		case "outline":
+			flags.Outline = outline
+		case "line-numbers":
+			flags.LineNumbers = lineNums
+		case "structure":
+			flags.Structure = structure
+		case "structure-depth":
+			flags.StructureDepth = structDep
+		}
+	})
+
+	// Merge defaults, user config, project config, env and flags
+	osFS := fs.NewOSFileSystem()
+	loader := config.Loader{
+		FS:        osFS,
+		Getenv:    os.Getenv,
+		Flags:     flags,
+		FlagNames: flagNames,
+	}
+	if dir, err := config.UserDir(); err == nil {
+		loader.UserDir = dir
+	}
+	if absRoot, err := filepath.Abs(rootPath); err == nil {
+		loader.ProjectRoot = absRoot
+	}
+	cfg, sources, err := loader.Resolve()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if showConfig && len(args) <= 1 {
+		if err := config.Print(os.Stdout, cfg, sources); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	// Show help if requested
 	if len(args) > 1 {
 		fmt.Fprintln(os.Stderr, "Usage: picky [options] [directory]")
+		fmt.Fprintln(os.Stderr, "       picky [options] config [directory]")
 		fmt.Fprintln(os.Stderr, "Options:")
 		flag.PrintDefaults()
 		fmt.Fprintln(os.Stderr, "\nInteractive controls:")
-		fmt.Fprintln(os.Stderr, "  ↑/↓ or j/k   Navigate up/down")
-		fmt.Fprintln(os.Stderr, "  ←/→ or h/l   Collapse/expand directories")
-		fmt.Fprintln(os.Stderr, "  Space        Toggle selection")
-		fmt.Fprintln(os.Stderr, "  x            Exclude file/directory permanently")
-		fmt.Fprintln(os.Stderr, "  s            Settings pane")
-		fmt.Fprintln(os.Stderr, "  g            Generate output file")
-		fmt.Fprintln(os.Stderr, "  q            Quit")
+		if keys, err := app.KeyMap(cfg); err == nil {
+			for _, line := range keys.FullHelpLines() {
+				if line != "" {
+					line = "  " + line
+				}
+				fmt.Fprintln(os.Stderr, line)
+			}
+		}
+		fmt.Fprintln(os.Stderr, "\nThe command palette takes all, clear, invert, and select or deselect with a")
+		fmt.Fprintln(os.Stderr, "glob, \"ext EXT\" or \"grep REGEX\", optionally followed by \"under DIR\".")
+		fmt.Fprintln(os.Stderr, "\nSettings are read from the user config (config.yaml, config.yml or config.toml")
+		fmt.Fprintln(os.Stderr, "in $XDG_CONFIG_HOME/picky), then .picky/config.* in the target directory, then")
+		fmt.Fprintln(os.Stderr, "PICKY_* environment variables, then flags.")
 		fmt.Fprintln(os.Stderr, "\nExcluded paths are saved to .pickyignore in the target directory.")
 		os.Exit(1)
 	}
-	
+
 	// Create app with OS filesystem
 	application := &app.App{
-		FS:         fs.NewOSFileSystem(),
-		OutputPath: *outputPath,
+		FS:               osFS,
+		OutputPath:       cfg.Output,
+		Budget:           cfg.Budget,
+		MaxTokensPerPart: cfg.MaxTokensPerPart,
+		Config:           cfg,
+		UserConfigDir:    loader.UserDir,
 	}
-	
+
 	// Run the application
 	if err := application.Run(rootPath); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
-}
\ No newline at end of file
+}
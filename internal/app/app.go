@@ -3,6 +3,9 @@ package app
 import (
 	"fmt"
 	"path/filepath"
+	"sort"
+	"strings"
+	"github.com/eliooooooot/picky/internal/config"
 	"github.com/eliooooooot/picky/internal/domain"
 	"github.com/eliooooooot/picky/internal/generate"
 	"github.com/eliooooooot/picky/internal/ignore"
@@ -10,12 +13,258 @@ import (
 	"github.com/eliooooooot/picky/internal/tui"
 	
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
 )
 
 // App orchestrates the file selector application
 type App struct {
 	FS         domain.FileSystem
 	OutputPath string
+	Budget     int
+	
+	// MaxTokensPerPart splits generated output into numbered parts when positive
+	MaxTokensPerPart int
+	
+	// Config holds the merged user, project, env and flag settings
+	Config config.Config
+	
+	// UserConfigDir is where settings changed in the TUI are saved; empty disables saving
+	UserConfigDir string
+}
+
+// KeyMap builds the TUI keymap from a config's preset and overrides
+func KeyMap(cfg config.Config) (tui.KeyMap, error) {
+	keys, err := tui.KeyMapPreset(cfg.Keys.Preset)
+	if err != nil {
+		return tui.KeyMap{}, err
+	}
+	return keys.WithOverrides(cfg.Keys.Bindings)
+}
+
+// OutputOptions builds generator options from a config's format, tokenizer,
+// large file policy, redaction, content transforms, outlining, line numbers
+// and directory structure
+func OutputOptions(cfg config.Config) (generate.Options, error) {
+	format, err := generate.ParseFormat(cfg.Format)
+	if err != nil {
+		return generate.Options{}, err
+	}
+	tz, err := token.ByName(cfg.Tokenizer)
+	if err != nil {
+		return generate.Options{}, err
+	}
+	largeFiles, err := generate.ParseLargeFileMode(cfg.LargeFiles)
+	if err != nil {
+		return generate.Options{}, err
+	}
+	transforms, err := generate.ParseTransforms(cfg.Transforms)
+	if err != nil {
+		return generate.Options{}, err
+	}
+	structure, err := generate.ParseStructureMode(cfg.Structure)
+	if err != nil {
+		return generate.Options{}, err
+	}
+	return generate.Options{
+		Format:           format,
+		Tokenizer:        tz,
+		MaxTokensPerPart: cfg.MaxTokensPerPart,
+		MaxFileSize:      cfg.MaxFileSize,
+		LargeFiles:       largeFiles,
+		LargeFileLines:   cfg.LargeFileLines,
+		Redact:           cfg.Redact,
+		Transforms:       transforms,
+		Outline:          cfg.Outline,
+		LineNumbers:      cfg.LineNumbers,
+		Structure:        structure,
+		StructureDepth:   cfg.StructureDepth,
+	}, nil
+}
+
+// PairRules parses a config's source/test pairing rules
+func PairRules(cfg config.Config) ([]domain.PairRule, error) {
+	rules := make([]domain.PairRule, 0, len(cfg.PairRules))
+	for _, s := range cfg.PairRules {
+		r, err := domain.ParsePairRule(s)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, r)
+	}
+	return rules, nil
+}
+
+// Settings builds the initial TUI settings from a config
+func Settings(cfg config.Config) (tui.Settings, error) {
+	settings := tui.DefaultSettings()
+	settings.Emoji = cfg.Emoji
+	settings.ShowHidden = cfg.ShowHidden
+	settings.PairTests = cfg.PairTests
+	
+	format, err := generate.ParseFormat(cfg.Format)
+	if err != nil {
+		return settings, err
+	}
+	settings.Format = format
+	
+	if _, err := token.ByName(cfg.Tokenizer); err != nil {
+		return settings, err
+	}
+	if cfg.Tokenizer != "" {
+		settings.Tokenizer = cfg.Tokenizer
+	}
+	
+	order, err := domain.ParseSortOrder(cfg.Sort)
+	if err != nil {
+		return settings, err
+	}
+	settings.SortOrder = order
+	direction, err := domain.ParseSortDirection(cfg.SortDirection, order)
+	if err != nil {
+		return settings, err
+	}
+	settings.SortDirection = direction
+	settings.Columns = cfg.Columns
+	
+	switch display := tui.TokenDisplay(cfg.TokenDisplay); display {
+	case "":
+	case tui.TokenDisplayCompact, tui.TokenDisplayExact, tui.TokenDisplayOff:
+		settings.TokenDisplay = display
+	default:
+		return settings, fmt.Errorf("unknown token display %q (want compact, exact or off)", cfg.TokenDisplay)
+	}
+	
+	if err := registerThemes(cfg.Themes); err != nil {
+		return settings, err
+	}
+	switch {
+	case cfg.Theme == "" || strings.EqualFold(cfg.Theme, tui.AutoColorSchemeName):
+		settings.ColorScheme = tui.AutoColorScheme(hasDarkBackground())
+	default:
+		scheme, ok := tui.ColorSchemeByName(cfg.Theme)
+		if !ok {
+			return settings, fmt.Errorf("unknown theme %q", cfg.Theme)
+		}
+		settings.ColorScheme = scheme
+	}
+	return settings, nil
+}
+
+// hasDarkBackground reports whether the terminal background is dark
+var hasDarkBackground = lipgloss.HasDarkBackground
+
+// registerThemes validates custom themes from config and adds them to the settings pane
+func registerThemes(themes map[string]config.Theme) error {
+	// Sort for a stable order in the settings pane
+	names := make([]string, 0, len(themes))
+	for name := range themes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	
+	for _, name := range names {
+		theme := themes[name]
+		baseName := theme.Base
+		if baseName == "" {
+			baseName = "Ocean"
+		}
+		base, ok := tui.ColorSchemeByName(baseName)
+		if !ok {
+			return fmt.Errorf("theme %q: unknown base theme %q", name, baseName)
+		}
+		
+		scheme := tui.ColorScheme{Name: name}
+		colors := []struct {
+			dst *lipgloss.Color
+			src string
+		}{
+			{&scheme.Selected, theme.Selected},
+			{&scheme.PartiallySelected, theme.Partial},
+			{&scheme.Unselected, theme.Unselected},
+			{&scheme.CursorFg, theme.CursorFg},
+			{&scheme.CursorBg, theme.CursorBg},
+			{&scheme.Directory, theme.Directory},
+			{&scheme.File, theme.File},
+			{&scheme.Header, theme.Header},
+			{&scheme.Help, theme.Help},
+			{&scheme.Status, theme.Status},
+			{&scheme.PromptBorder, theme.PromptBorder},
+			{&scheme.PromptBorderActive, theme.PromptBorderActive},
+		}
+		for _, c := range colors {
+			color, err := tui.ParseColor(c.src)
+			if err != nil {
+				return fmt.Errorf("theme %q: %w", name, err)
+			}
+			*c.dst = color
+		}
+		tui.RegisterColorScheme(tui.NewColorScheme(scheme, base))
+	}
+	return nil
+}
+
+// settingsLayer converts the TUI settings changed since prev into the config
+// fields they persist to. Unchanged settings are left out so values that came
+// from flags, the project config, the environment or theme detection are not
+// copied into the user config.
+func settingsLayer(prev, s tui.Settings) config.Layer {
+	var l config.Layer
+	if s.ColorScheme.Name != prev.ColorScheme.Name {
+		l.Theme = &s.ColorScheme.Name
+	}
+	if s.Emoji != prev.Emoji {
+		l.Emoji = &s.Emoji
+	}
+	if s.Format != prev.Format {
+		format := string(s.Format)
+		l.Format = &format
+	}
+	if s.Tokenizer != prev.Tokenizer {
+		l.Tokenizer = &s.Tokenizer
+	}
+	if s.ShowHidden != prev.ShowHidden {
+		l.ShowHidden = &s.ShowHidden
+	}
+	if s.SortOrder != prev.SortOrder {
+		order := string(s.SortOrder)
+		l.Sort = &order
+	}
+	if s.SortDirection != prev.SortDirection {
+		direction := string(s.SortDirection)
+		l.SortDirection = &direction
+	}
+	if s.TokenDisplay != prev.TokenDisplay {
+		display := string(s.TokenDisplay)
+		l.TokenDisplay = &display
+	}
+	if s.Columns != prev.Columns {
+		l.Columns = &s.Columns
+	}
+	if s.PairTests != prev.PairTests {
+		l.PairTests = &s.PairTests
+	}
+	return l
+}
+
+// SaveSettings writes the TUI settings changed since prev into the user
+// config file, creating it if needed
+func (a *App) SaveSettings(prev, s tui.Settings) error {
+	path := config.Find(a.FS, a.UserConfigDir)
+	if path == "" {
+		path = filepath.Join(a.UserConfigDir, "config.yaml")
+	}
+	return config.Save(a.FS, path, settingsLayer(prev, s))
+}
+
+// newCounter creates a token counter honouring the size limit and, when
+// counts should match transformed output, the content transforms
+func (a *App) newCounter(tz token.Tokenizer, transforms generate.Transforms) *token.Counter {
+	tc := token.NewCounter(a.FS, tz)
+	tc.MaxFileSize = a.Config.MaxFileSize
+	if a.Config.CountTransformed {
+		tc.Transform = transforms.Apply
+	}
+	return tc
 }
 
 // Run executes the application
@@ -41,27 +290,74 @@ func (a *App) Run(rootPath string) error {
 		}
 		// Normalize path for cross-platform compatibility
 		normalizedRel := filepath.ToSlash(rel)
-		_, skip := ignores[normalizedRel]
-		return !skip
+		if _, skip := ignores[normalizedRel]; skip {
+			return false
+		}
+		return normalizedRel == "." || !ignore.MatchAny(a.Config.Ignore, normalizedRel)
 	}
 	
-	tree, err := domain.BuildTreeWithFilter(a.FS, rootPath, keep)
+	// A lazy tree reads only the root now; the TUI loads the rest on demand
+	tree, err := domain.BuildTreeWithOptions(a.FS, rootPath, domain.TreeOptions{
+		Keep:           keep,
+		FollowSymlinks: a.Config.FollowSymlinks,
+		Lazy:           a.Config.Lazy,
+	})
 	if err != nil {
 		return fmt.Errorf("build tree: %w", err)
 	}
 	
+	opts, err := OutputOptions(a.Config)
+	if err != nil {
+		return err
+	}
+	opts.MaxTokensPerPart = a.MaxTokensPerPart
+	opts.Diff = GitDiff
+	
 	// --- token counting --------------------------------------------------
-	tc := token.NewCounter(a.FS, token.NaiveTokenizer{})
+	tc := a.newCounter(opts.Tokenizer, opts.Transforms)
 	tokensMap, err := tc.BuildTreeTokenMap(tree)
 	if err != nil {
 		return fmt.Errorf("token count: %w", err)
 	}
 	// ---------------------------------------------------------------------
 	
+	keys, err := KeyMap(a.Config)
+	if err != nil {
+		return fmt.Errorf("load keymap: %w", err)
+	}
+	settings, err := Settings(a.Config)
+	if err != nil {
+		return err
+	}
+	pairRules, err := PairRules(a.Config)
+	if err != nil {
+		return err
+	}
+	
 	// Create and run the TUI
 	model := tui.NewModel(tree, &ignores)
+	model.SetKeyMap(keys)
+	model.SetFileSystem(a.FS)
 	model.SetTokens(tokensMap)
-	p := tea.NewProgram(model, tea.WithAltScreen())
+	model.SetOutputOptions(opts)
+	model.SetSettings(settings)
+	model.SetTokenRecounter(func(tz token.Tokenizer) (map[string]int, error) {
+		return a.newCounter(tz, opts.Transforms).BuildTreeTokenMap(tree)
+	})
+	if a.UserConfigDir != "" {
+		model.SetSettingsSaver(a.SaveSettings)
+	}
+	model.SetBudget(a.Budget)
+	model.SetDepsDepth(a.Config.DepsDepth)
+	model.SetPairRules(pairRules)
+	model.SetHiddenAllow(a.Config.HiddenAllow)
+	model.SetMaxFileSize(a.Config.MaxFileSize)
+	model.SetCountTransformed(a.Config.CountTransformed)
+	programOpts := []tea.ProgramOption{tea.WithAltScreen()}
+	if a.Config.Mouse {
+		programOpts = append(programOpts, tea.WithMouseCellMotion())
+	}
+	p := tea.NewProgram(model, programOpts...)
 	
 	finalModel, err := p.Run()
 	if err != nil {
@@ -90,11 +386,14 @@ func (a *App) Run(rootPath string) error {
 			outputPath = "selected.txt"
 		}
 		
-		if err := generate.Generate(outputPath, m.Prompt(), m.Tree(), m.State(), a.FS); err != nil {
+		written, err := generate.GenerateWithOptions(outputPath, m.Prompt(), m.Tree(), m.State(), a.FS, m.OutputOptions())
+		if err != nil {
 			return fmt.Errorf("generate output: %w", err)
 		}
 		
-		fmt.Printf("Output written to: %s\n", outputPath)
+		for _, path := range written {
+			fmt.Printf("Output written to: %s\n", path)
+		}
 	}
 	
 	return nil
@@ -0,0 +1,178 @@
+package app_test
+
+import (
+	"testing"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/eliooooooot/picky/internal/app"
+	"github.com/eliooooooot/picky/internal/config"
+	"github.com/eliooooooot/picky/internal/domain"
+	pickyfs "github.com/eliooooooot/picky/internal/fs"
+	"github.com/eliooooooot/picky/internal/generate"
+	"github.com/eliooooooot/picky/internal/token"
+	"github.com/eliooooooot/picky/internal/tui"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSaveSettingsWritesOnlyChanges(t *testing.T) {
+	memfs := pickyfs.NewMemFileSystem()
+	memfs.AddFile("/xdg/picky/config.yaml", "theme: auto\n")
+	a := &app.App{FS: memfs, UserConfigDir: "/xdg/picky"}
+
+	// The detected scheme and a format from -format are in effect, not chosen
+	prev := tui.DefaultSettings()
+	prev.ColorScheme = tui.AutoColorScheme(true)
+	prev.Format = generate.FormatXML
+	changed := prev
+	changed.Emoji = true
+	require.NoError(t, a.SaveSettings(prev, changed))
+
+	layer, err := config.Load(memfs, "/xdg/picky/config.yaml")
+	require.NoError(t, err)
+	require.NotNil(t, layer.Emoji)
+	assert.True(t, *layer.Emoji)
+	require.NotNil(t, layer.Theme)
+	assert.Equal(t, "auto", *layer.Theme, "a detected theme is not saved over auto")
+	assert.Nil(t, layer.Format, "unchanged settings are not saved")
+	assert.Nil(t, layer.Tokenizer)
+	assert.Nil(t, layer.ShowHidden)
+
+	// A theme picked in the pane is saved by name
+	picked := changed.NextColorScheme()
+	require.NoError(t, a.SaveSettings(changed, picked))
+	layer, err = config.Load(memfs, "/xdg/picky/config.yaml")
+	require.NoError(t, err)
+	assert.Equal(t, picked.ColorScheme.Name, *layer.Theme)
+}
+
+func TestOutputOptionsAndSettingsFromConfig(t *testing.T) {
+	cfg := config.Default()
+	cfg.Format = "xml"
+	cfg.Tokenizer = "words"
+	cfg.Theme = "forest"
+	cfg.Emoji = true
+	
+	opts, err := app.OutputOptions(cfg)
+	require.NoError(t, err)
+	assert.Equal(t, generate.FormatXML, opts.Format)
+	assert.Equal(t, token.WordTokenizer{}, opts.Tokenizer)
+	assert.Equal(t, 1<<20, opts.MaxFileSize)
+	assert.Equal(t, generate.LargeFileHead, opts.LargeFiles)
+	assert.Equal(t, 200, opts.LargeFileLines)
+	assert.True(t, opts.Redact)
+	assert.False(t, opts.Transforms.Enabled())
+	assert.False(t, opts.Outline)
+	assert.False(t, opts.LineNumbers)
+	assert.Equal(t, generate.StructureFull, opts.Structure)
+	assert.Equal(t, 2, opts.StructureDepth)
+	
+	settings, err := app.Settings(cfg)
+	require.NoError(t, err)
+	assert.True(t, settings.Emoji)
+	assert.Equal(t, "Forest", settings.ColorScheme.Name)
+	
+	cfg.Theme = "Plaid"
+	_, err = app.Settings(cfg)
+	assert.Error(t, err)
+	
+	cfg.Format = "pdf"
+	_, err = app.OutputOptions(cfg)
+	assert.Error(t, err)
+	
+	cfg.Format = "text"
+	cfg.LargeFiles = "middle"
+	_, err = app.OutputOptions(cfg)
+	assert.Error(t, err)
+	
+	cfg.LargeFiles = "head"
+	cfg.Transforms = []string{"comments", "blank-lines"}
+	opts, err = app.OutputOptions(cfg)
+	require.NoError(t, err)
+	assert.Equal(t, generate.Transforms{StripComments: true, KeepDocComments: true, CollapseBlankLines: true}, opts.Transforms)
+	
+	cfg.Transforms = []string{"minify"}
+	_, err = app.OutputOptions(cfg)
+	assert.Error(t, err)
+	
+	cfg.Transforms = nil
+	cfg.Structure = "sparse"
+	_, err = app.OutputOptions(cfg)
+	assert.Error(t, err)
+}
+
+func TestSettingsFromConfigCoverPaneOptions(t *testing.T) {
+	cfg := config.Default()
+	cfg.ShowHidden = false
+	cfg.Sort = "tokens"
+	cfg.TokenDisplay = "exact"
+	
+	settings, err := app.Settings(cfg)
+	require.NoError(t, err)
+	assert.False(t, settings.ShowHidden)
+	assert.Equal(t, domain.SortByTokens, settings.SortOrder)
+	assert.Equal(t, domain.SortDescending, settings.SortDirection, "tokens default to largest first")
+	assert.Equal(t, tui.TokenDisplayExact, settings.TokenDisplay)
+	assert.Equal(t, generate.FormatText, settings.Format)
+	assert.False(t, settings.Columns)
+	
+	cfg.SortDirection = "asc"
+	cfg.Columns = true
+	settings, err = app.Settings(cfg)
+	require.NoError(t, err)
+	assert.Equal(t, domain.SortAscending, settings.SortDirection)
+	assert.True(t, settings.Columns)
+	
+	cfg.SortDirection = "up"
+	_, err = app.Settings(cfg)
+	assert.Error(t, err)
+	
+	cfg.SortDirection = ""
+	cfg.TokenDisplay = "loud"
+	_, err = app.Settings(cfg)
+	assert.Error(t, err)
+}
+
+func TestCustomThemeFromConfig(t *testing.T) {
+	cfg := config.Default()
+	cfg.Theme = "Paper"
+	cfg.Themes = map[string]config.Theme{
+		"Paper": {Base: "Daylight", Selected: "#0055aa", Directory: "#333"},
+	}
+	
+	settings, err := app.Settings(cfg)
+	require.NoError(t, err)
+	scheme := settings.ColorScheme
+	assert.Equal(t, "Paper", scheme.Name)
+	assert.Equal(t, lipgloss.Color("#0055aa"), scheme.Selected)
+	assert.Equal(t, lipgloss.Color("#333"), scheme.Directory)
+	
+	daylight, ok := tui.ColorSchemeByName("Daylight")
+	require.True(t, ok)
+	assert.Equal(t, daylight.PartiallySelected, scheme.PartiallySelected, "unset colors come from the base theme")
+	assert.Equal(t, daylight.Header, scheme.Header)
+	
+	cfg.Themes = map[string]config.Theme{"Broken": {Selected: "blue-ish"}}
+	_, err = app.Settings(cfg)
+	assert.Error(t, err)
+}
+
+func TestNoneThemeFromConfig(t *testing.T) {
+	cfg := config.Default()
+	cfg.Theme = "none"
+	
+	settings, err := app.Settings(cfg)
+	require.NoError(t, err)
+	assert.True(t, settings.ColorScheme.NoColor)
+}
+
+func TestPairRulesFromConfig(t *testing.T) {
+	cfg := config.Default()
+	rules, err := app.PairRules(cfg)
+	require.NoError(t, err)
+	assert.Equal(t, domain.DefaultPairRules(), rules)
+
+	cfg.PairRules = []string{"*.rb"}
+	_, err = app.PairRules(cfg)
+	assert.Error(t, err)
+}
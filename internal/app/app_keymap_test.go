@@ -0,0 +1,25 @@
+package app_test
+
+import (
+	"testing"
+	
+	"github.com/eliooooooot/picky/internal/app"
+	"github.com/eliooooooot/picky/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyMapFromConfig(t *testing.T) {
+	cfg := config.Config{Keys: config.Keys{
+		Preset:   "emacs",
+		Bindings: map[string][]string{"quit": {"Q"}},
+	}}
+	
+	keys, err := app.KeyMap(cfg)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"up", "ctrl+p"}, keys.Up.Keys())
+	assert.Equal(t, []string{"Q"}, keys.Quit.Keys())
+	
+	_, err = app.KeyMap(config.Config{Keys: config.Keys{Preset: "bogus"}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,51 @@
+package app
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+// GitDiff returns a file's uncommitted changes against HEAD, for files in the
+// diff inclusion mode. Files git does not track yet are shown as added in
+// full; tracked files without changes give an empty diff.
+func GitDiff(path string) ([]byte, error) {
+	dir := filepath.Dir(path)
+	out, err := exec.Command("git", "-C", dir, "diff", "HEAD", "--", path).Output()
+	if err != nil {
+		return nil, fmt.Errorf("git diff: %w", gitError(err))
+	}
+	if len(out) > 0 {
+		return out, nil
+	}
+
+	status, err := exec.Command("git", "-C", dir, "status", "--porcelain", "--", path).Output()
+	if err != nil {
+		return nil, fmt.Errorf("git status: %w", gitError(err))
+	}
+	if !bytes.HasPrefix(status, []byte("??")) {
+		return nil, nil
+	}
+	out, err = exec.Command("git", "-C", dir, "diff", "--no-index", "--", os.DevNull, path).Output()
+	// Without an index git diff exits with 1 when the files differ
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+		err = nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("git diff: %w", gitError(err))
+	}
+	return out, nil
+}
+
+// gitError adds git's own message to a failed command's error
+func gitError(err error) error {
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+		return errors.New(string(bytes.TrimSpace(exitErr.Stderr)))
+	}
+	return err
+}
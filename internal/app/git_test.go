@@ -0,0 +1,52 @@
+package app_test
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/eliooooooot/picky/internal/app"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGitDiff(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir := t.TempDir()
+	git := func(args ...string) {
+		cmd := exec.Command("git", append([]string{"-C", dir, "-c", "user.name=t", "-c", "user.email=t@example.com"}, args...)...)
+		out, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(out))
+	}
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+		return path
+	}
+
+	git("init", "-q")
+	changed := write("changed.txt", "one\n")
+	clean := write("clean.txt", "same\n")
+	git("add", ".")
+	git("commit", "-q", "-m", "initial")
+	write("changed.txt", "two\n")
+	added := write("new.txt", "fresh\n")
+
+	diff, err := app.GitDiff(changed)
+	require.NoError(t, err)
+	assert.Contains(t, string(diff), "-one\n+two\n")
+
+	diff, err = app.GitDiff(clean)
+	require.NoError(t, err)
+	assert.Empty(t, diff)
+
+	diff, err = app.GitDiff(added)
+	require.NoError(t, err)
+	assert.Contains(t, string(diff), "+fresh\n")
+
+	_, err = app.GitDiff(filepath.Join(t.TempDir(), "outside.txt"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,722 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/eliooooooot/picky/internal/domain"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	appDirName     = "picky"
+	projectDirName = ".picky"
+	configFileName = "config.yaml"
+	envPrefix      = "PICKY_"
+)
+
+// configFileNames are tried in order when looking for a config file in a directory
+var configFileNames = []string{"config.yaml", "config.yml", "config.toml"}
+
+// Config holds the effective settings after all layers are merged
+type Config struct {
+	Output           string   `yaml:"output" toml:"output"`
+	Format           string   `yaml:"format" toml:"format"`
+	Tokenizer        string   `yaml:"tokenizer" toml:"tokenizer"`
+	Theme            string   `yaml:"theme" toml:"theme"`
+	Emoji            bool     `yaml:"emoji" toml:"emoji"`
+	Budget           int      `yaml:"budget" toml:"budget"`
+	MaxTokensPerPart int      `yaml:"max_tokens_per_part" toml:"max_tokens_per_part"`
+	DepsDepth        int      `yaml:"deps_depth" toml:"deps_depth"`
+	ShowHidden       bool     `yaml:"show_hidden" toml:"show_hidden"`
+	Sort             string   `yaml:"sort" toml:"sort"`
+	SortDirection    string   `yaml:"sort_direction" toml:"sort_direction"`
+	TokenDisplay     string   `yaml:"token_display" toml:"token_display"`
+	Columns          bool     `yaml:"columns" toml:"columns"`
+	Mouse            bool     `yaml:"mouse" toml:"mouse"`
+	PairTests        bool     `yaml:"pair_tests" toml:"pair_tests"`
+	PairRules        []string `yaml:"pair_rules" toml:"pair_rules"`
+	MaxFileSize      int      `yaml:"max_file_size" toml:"max_file_size"`
+	LargeFiles       string   `yaml:"large_files" toml:"large_files"`
+	LargeFileLines   int      `yaml:"large_file_lines" toml:"large_file_lines"`
+	Lazy             bool     `yaml:"lazy" toml:"lazy"`
+	FollowSymlinks   bool     `yaml:"follow_symlinks" toml:"follow_symlinks"`
+	HiddenAllow      []string `yaml:"hidden_allow" toml:"hidden_allow"`
+	Redact           bool     `yaml:"redact" toml:"redact"`
+	Transforms       []string `yaml:"transforms" toml:"transforms"`
+	CountTransformed bool     `yaml:"count_transformed" toml:"count_transformed"`
+	Outline          bool     `yaml:"outline" toml:"outline"`
+	LineNumbers      bool     `yaml:"line_numbers" toml:"line_numbers"`
+	Structure        string   `yaml:"structure" toml:"structure"`
+	StructureDepth   int      `yaml:"structure_depth" toml:"structure_depth"`
+	Ignore           []string `yaml:"ignore" toml:"ignore"`
+	Keys             Keys     `yaml:"keys,omitempty" toml:"keys,omitempty"`
+	// Themes defines custom color themes, selectable by name with Theme
+	Themes map[string]Theme `yaml:"themes,omitempty" toml:"themes,omitempty"`
+}
+
+// Keys configures the TUI key bindings
+type Keys struct {
+	// Preset selects the base keymap: "vim" (default) or "emacs"
+	Preset string `yaml:"preset,omitempty" toml:"preset,omitempty"`
+	// Bindings rebinds individual actions, e.g. {"generate": ["G"]}
+	Bindings map[string][]string `yaml:"bindings,omitempty" toml:"bindings,omitempty"`
+}
+
+// Theme is a custom color theme. Colors are ANSI 256 codes ("39") or hex
+// values ("#1e90ff"); unset colors are taken from Base.
+type Theme struct {
+	// Base names the built-in theme to inherit from; defaults to Ocean
+	Base               string `yaml:"base,omitempty" toml:"base,omitempty"`
+	Selected           string `yaml:"selected,omitempty" toml:"selected,omitempty"`
+	Partial            string `yaml:"partial,omitempty" toml:"partial,omitempty"`
+	Unselected         string `yaml:"unselected,omitempty" toml:"unselected,omitempty"`
+	CursorFg           string `yaml:"cursor_fg,omitempty" toml:"cursor_fg,omitempty"`
+	CursorBg           string `yaml:"cursor_bg,omitempty" toml:"cursor_bg,omitempty"`
+	Directory          string `yaml:"directory,omitempty" toml:"directory,omitempty"`
+	File               string `yaml:"file,omitempty" toml:"file,omitempty"`
+	Header             string `yaml:"header,omitempty" toml:"header,omitempty"`
+	Help               string `yaml:"help,omitempty" toml:"help,omitempty"`
+	Status             string `yaml:"status,omitempty" toml:"status,omitempty"`
+	PromptBorder       string `yaml:"prompt_border,omitempty" toml:"prompt_border,omitempty"`
+	PromptBorderActive string `yaml:"prompt_border_active,omitempty" toml:"prompt_border_active,omitempty"`
+}
+
+// Layer is one source of settings. Nil fields are left unset so lower
+// layers show through.
+type Layer struct {
+	Output           *string          `yaml:"output" toml:"output"`
+	Format           *string          `yaml:"format" toml:"format"`
+	Tokenizer        *string          `yaml:"tokenizer" toml:"tokenizer"`
+	Theme            *string          `yaml:"theme" toml:"theme"`
+	Emoji            *bool            `yaml:"emoji" toml:"emoji"`
+	Budget           *int             `yaml:"budget" toml:"budget"`
+	MaxTokensPerPart *int             `yaml:"max_tokens_per_part" toml:"max_tokens_per_part"`
+	DepsDepth        *int             `yaml:"deps_depth" toml:"deps_depth"`
+	ShowHidden       *bool            `yaml:"show_hidden" toml:"show_hidden"`
+	Sort             *string          `yaml:"sort" toml:"sort"`
+	SortDirection    *string          `yaml:"sort_direction" toml:"sort_direction"`
+	TokenDisplay     *string          `yaml:"token_display" toml:"token_display"`
+	Columns          *bool            `yaml:"columns" toml:"columns"`
+	Mouse            *bool            `yaml:"mouse" toml:"mouse"`
+	PairTests        *bool            `yaml:"pair_tests" toml:"pair_tests"`
+	PairRules        *[]string        `yaml:"pair_rules" toml:"pair_rules"`
+	MaxFileSize      *int             `yaml:"max_file_size" toml:"max_file_size"`
+	LargeFiles       *string          `yaml:"large_files" toml:"large_files"`
+	LargeFileLines   *int             `yaml:"large_file_lines" toml:"large_file_lines"`
+	Lazy             *bool            `yaml:"lazy" toml:"lazy"`
+	FollowSymlinks   *bool            `yaml:"follow_symlinks" toml:"follow_symlinks"`
+	HiddenAllow      *[]string        `yaml:"hidden_allow" toml:"hidden_allow"`
+	Redact           *bool            `yaml:"redact" toml:"redact"`
+	Transforms       *[]string        `yaml:"transforms" toml:"transforms"`
+	CountTransformed *bool            `yaml:"count_transformed" toml:"count_transformed"`
+	Outline          *bool            `yaml:"outline" toml:"outline"`
+	LineNumbers      *bool            `yaml:"line_numbers" toml:"line_numbers"`
+	Structure        *string          `yaml:"structure" toml:"structure"`
+	StructureDepth   *int             `yaml:"structure_depth" toml:"structure_depth"`
+	Ignore           *[]string        `yaml:"ignore" toml:"ignore"`
+	Keys             Keys             `yaml:"keys" toml:"keys"`
+	Themes           map[string]Theme `yaml:"themes" toml:"themes"`
+}
+
+// Default returns the built-in settings
+func Default() Config {
+	return Config{
+		Output:         "selected.txt",
+		Format:         "text",
+		Tokenizer:      "naive",
+		Theme:          "auto",
+		DepsDepth:      1,
+		ShowHidden:     true,
+		Sort:           "name",
+		TokenDisplay:   "compact",
+		Mouse:          true,
+		PairRules:      defaultPairRules(),
+		MaxFileSize:    1 << 20,
+		LargeFiles:     "head",
+		LargeFileLines: 200,
+		FollowSymlinks: true,
+		HiddenAllow:    domain.DefaultHiddenAllow(),
+		Redact:         true,
+		Structure:      "full",
+		StructureDepth: 2,
+	}
+}
+
+// defaultPairRules lists the built-in source/test pairing rules as
+// "source:test" patterns, e.g. "*.ts:*.spec.ts"
+func defaultPairRules() []string {
+	var rules []string
+	for _, r := range domain.DefaultPairRules() {
+		rules = append(rules, r.String())
+	}
+	return rules
+}
+
+// Apply returns c with every field set in l overridden. Key bindings are
+// merged per action and themes per name; the ignore, pair rule and hidden
+// allow lists are replaced as a whole.
+func (c Config) Apply(l Layer) Config {
+	if l.Output != nil {
+		c.Output = *l.Output
+	}
+	if l.Format != nil {
+		c.Format = *l.Format
+	}
+	if l.Tokenizer != nil {
+		c.Tokenizer = *l.Tokenizer
+	}
+	if l.Theme != nil {
+		c.Theme = *l.Theme
+	}
+	if l.Emoji != nil {
+		c.Emoji = *l.Emoji
+	}
+	if l.Budget != nil {
+		c.Budget = *l.Budget
+	}
+	if l.MaxTokensPerPart != nil {
+		c.MaxTokensPerPart = *l.MaxTokensPerPart
+	}
+	if l.DepsDepth != nil {
+		c.DepsDepth = *l.DepsDepth
+	}
+	if l.ShowHidden != nil {
+		c.ShowHidden = *l.ShowHidden
+	}
+	if l.Sort != nil {
+		c.Sort = *l.Sort
+	}
+	if l.SortDirection != nil {
+		c.SortDirection = *l.SortDirection
+	}
+	if l.TokenDisplay != nil {
+		c.TokenDisplay = *l.TokenDisplay
+	}
+	if l.Columns != nil {
+		c.Columns = *l.Columns
+	}
+	if l.Mouse != nil {
+		c.Mouse = *l.Mouse
+	}
+	if l.PairTests != nil {
+		c.PairTests = *l.PairTests
+	}
+	if l.PairRules != nil {
+		c.PairRules = *l.PairRules
+	}
+	if l.MaxFileSize != nil {
+		c.MaxFileSize = *l.MaxFileSize
+	}
+	if l.LargeFiles != nil {
+		c.LargeFiles = *l.LargeFiles
+	}
+	if l.LargeFileLines != nil {
+		c.LargeFileLines = *l.LargeFileLines
+	}
+	if l.Lazy != nil {
+		c.Lazy = *l.Lazy
+	}
+	if l.FollowSymlinks != nil {
+		c.FollowSymlinks = *l.FollowSymlinks
+	}
+	if l.HiddenAllow != nil {
+		c.HiddenAllow = *l.HiddenAllow
+	}
+	if l.Redact != nil {
+		c.Redact = *l.Redact
+	}
+	if l.Transforms != nil {
+		c.Transforms = *l.Transforms
+	}
+	if l.CountTransformed != nil {
+		c.CountTransformed = *l.CountTransformed
+	}
+	if l.Outline != nil {
+		c.Outline = *l.Outline
+	}
+	if l.LineNumbers != nil {
+		c.LineNumbers = *l.LineNumbers
+	}
+	if l.Structure != nil {
+		c.Structure = *l.Structure
+	}
+	if l.StructureDepth != nil {
+		c.StructureDepth = *l.StructureDepth
+	}
+	if l.Ignore != nil {
+		c.Ignore = append([]string(nil), (*l.Ignore)...)
+	}
+	if len(l.Themes) > 0 {
+		themes := make(map[string]Theme, len(c.Themes)+len(l.Themes))
+		for name, theme := range c.Themes {
+			themes[name] = theme
+		}
+		for name, theme := range l.Themes {
+			themes[name] = theme
+		}
+		c.Themes = themes
+	}
+	if l.Keys.Preset != "" {
+		c.Keys.Preset = l.Keys.Preset
+	}
+	if len(l.Keys.Bindings) > 0 {
+		bindings := make(map[string][]string, len(c.Keys.Bindings)+len(l.Keys.Bindings))
+		for action, keys := range c.Keys.Bindings {
+			bindings[action] = keys
+		}
+		for action, keys := range l.Keys.Bindings {
+			bindings[action] = keys
+		}
+		c.Keys.Bindings = bindings
+	}
+	return c
+}
+
+// UserDir returns the directory holding the user config, honouring XDG_CONFIG_HOME
+func UserDir() (string, error) {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		var err error
+		dir, err = os.UserConfigDir()
+		if err != nil {
+			return "", fmt.Errorf("locate user config dir: %w", err)
+		}
+	}
+	return filepath.Join(dir, appDirName), nil
+}
+
+// UserPath returns the default path of the user config file
+func UserPath() (string, error) {
+	dir, err := UserDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, configFileName), nil
+}
+
+// ProjectDir returns the directory holding a project's config
+func ProjectDir(root string) string {
+	return filepath.Join(root, projectDirName)
+}
+
+// Load reads a YAML or TOML config file, chosen by extension.
+// A missing file yields an empty Layer.
+func Load(fsys domain.FileSystem, path string) (Layer, error) {
+	var layer Layer
+
+	data, err := fsys.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return layer, nil
+		}
+		return layer, fmt.Errorf("read %s: %w", path, err)
+	}
+
+	if strings.EqualFold(filepath.Ext(path), ".toml") {
+		err = toml.Unmarshal(data, &layer)
+	} else {
+		err = yaml.Unmarshal(data, &layer)
+	}
+	if err != nil {
+		return layer, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return layer, nil
+}
+
+// Save writes the fields set in layer into the config file at path, keeping
+// any other settings already there. The file's format follows its extension.
+// Comments in an existing file are not preserved.
+func Save(fsys domain.FileSystem, path string, layer Layer) error {
+	isTOML := strings.EqualFold(filepath.Ext(path), ".toml")
+
+	values := make(map[string]any)
+	data, err := fsys.ReadFile(path)
+	switch {
+	case err == nil && isTOML:
+		err = toml.Unmarshal(data, &values)
+	case err == nil:
+		err = yaml.Unmarshal(data, &values)
+	case errors.Is(err, fs.ErrNotExist):
+		err = nil
+	}
+	if err != nil {
+		return fmt.Errorf("read %s: %w", path, err)
+	}
+	if values == nil {
+		values = make(map[string]any)
+	}
+
+	for k, v := range layer.values() {
+		values[k] = v
+	}
+
+	var buf bytes.Buffer
+	if isTOML {
+		err = toml.NewEncoder(&buf).Encode(values)
+	} else {
+		enc := yaml.NewEncoder(&buf)
+		enc.SetIndent(2)
+		err = enc.Encode(values)
+		if err == nil {
+			err = enc.Close()
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("encode %s: %w", path, err)
+	}
+
+	if err := fsys.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("create %s: %w", filepath.Dir(path), err)
+	}
+	return fsys.WriteFile(path, buf.Bytes(), 0644)
+}
+
+// values returns the set scalar and list fields of l keyed by their config name
+func (l Layer) values() map[string]any {
+	values := make(map[string]any)
+	set := func(name string, ok bool, v any) {
+		if ok {
+			values[name] = v
+		}
+	}
+	set("output", l.Output != nil, deref(l.Output))
+	set("format", l.Format != nil, deref(l.Format))
+	set("tokenizer", l.Tokenizer != nil, deref(l.Tokenizer))
+	set("theme", l.Theme != nil, deref(l.Theme))
+	set("emoji", l.Emoji != nil, deref(l.Emoji))
+	set("budget", l.Budget != nil, deref(l.Budget))
+	set("max_tokens_per_part", l.MaxTokensPerPart != nil, deref(l.MaxTokensPerPart))
+	set("deps_depth", l.DepsDepth != nil, deref(l.DepsDepth))
+	set("show_hidden", l.ShowHidden != nil, deref(l.ShowHidden))
+	set("sort", l.Sort != nil, deref(l.Sort))
+	set("sort_direction", l.SortDirection != nil, deref(l.SortDirection))
+	set("token_display", l.TokenDisplay != nil, deref(l.TokenDisplay))
+	set("columns", l.Columns != nil, deref(l.Columns))
+	set("mouse", l.Mouse != nil, deref(l.Mouse))
+	set("pair_tests", l.PairTests != nil, deref(l.PairTests))
+	set("pair_rules", l.PairRules != nil, deref(l.PairRules))
+	set("max_file_size", l.MaxFileSize != nil, deref(l.MaxFileSize))
+	set("large_files", l.LargeFiles != nil, deref(l.LargeFiles))
+	set("large_file_lines", l.LargeFileLines != nil, deref(l.LargeFileLines))
+	set("lazy", l.Lazy != nil, deref(l.Lazy))
+	set("follow_symlinks", l.FollowSymlinks != nil, deref(l.FollowSymlinks))
+	set("hidden_allow", l.HiddenAllow != nil, deref(l.HiddenAllow))
+	set("redact", l.Redact != nil, deref(l.Redact))
+	set("transforms", l.Transforms != nil, deref(l.Transforms))
+	set("count_transformed", l.CountTransformed != nil, deref(l.CountTransformed))
+	set("outline", l.Outline != nil, deref(l.Outline))
+	set("line_numbers", l.LineNumbers != nil, deref(l.LineNumbers))
+	set("structure", l.Structure != nil, deref(l.Structure))
+	set("structure_depth", l.StructureDepth != nil, deref(l.StructureDepth))
+	set("ignore", l.Ignore != nil, deref(l.Ignore))
+	return values
+}
+
+func deref[T any](p *T) T {
+	var zero T
+	if p == nil {
+		return zero
+	}
+	return *p
+}
+
+// Find returns the first config file present in dir, or "" if there is none
+func Find(fsys domain.FileSystem, dir string) string {
+	for _, name := range configFileNames {
+		path := filepath.Join(dir, name)
+		if _, err := fsys.Stat(path); err == nil {
+			return path
+		}
+	}
+	return ""
+}
+
+// EnvLayer reads PICKY_* environment variables through getenv.
+// It returns the layer and the names of the variables that were set.
+func EnvLayer(getenv func(string) string) (Layer, []string, error) {
+	var layer Layer
+	var set []string
+
+	lookup := func(name string) (string, bool) {
+		v := getenv(envPrefix + name)
+		if v != "" {
+			set = append(set, envPrefix+name)
+		}
+		return v, v != ""
+	}
+
+	if v, ok := lookup("OUTPUT"); ok {
+		layer.Output = &v
+	}
+	if v, ok := lookup("FORMAT"); ok {
+		layer.Format = &v
+	}
+	if v, ok := lookup("TOKENIZER"); ok {
+		layer.Tokenizer = &v
+	}
+	// NO_COLOR (https://no-color.org) selects the colorless theme,
+	// unless PICKY_THEME or a flag asks for another
+	if v := getenv("NO_COLOR"); v != "" {
+		none := "none"
+		layer.Theme = &none
+		set = append(set, "NO_COLOR")
+	}
+	if v, ok := lookup("THEME"); ok {
+		layer.Theme = &v
+	}
+	if v, ok := lookup("EMOJI"); ok {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return layer, set, fmt.Errorf("%sEMOJI: %w", envPrefix, err)
+		}
+		layer.Emoji = &b
+	}
+	if v, ok := lookup("BUDGET"); ok {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return layer, set, fmt.Errorf("%sBUDGET: %w", envPrefix, err)
+		}
+		layer.Budget = &n
+	}
+	if v, ok := lookup("MAX_TOKENS_PER_PART"); ok {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return layer, set, fmt.Errorf("%sMAX_TOKENS_PER_PART: %w", envPrefix, err)
+		}
+		layer.MaxTokensPerPart = &n
+	}
+	if v, ok := lookup("DEPS_DEPTH"); ok {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return layer, set, fmt.Errorf("%sDEPS_DEPTH: %w", envPrefix, err)
+		}
+		layer.DepsDepth = &n
+	}
+	if v, ok := lookup("SHOW_HIDDEN"); ok {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return layer, set, fmt.Errorf("%sSHOW_HIDDEN: %w", envPrefix, err)
+		}
+		layer.ShowHidden = &b
+	}
+	if v, ok := lookup("SORT"); ok {
+		layer.Sort = &v
+	}
+	if v, ok := lookup("SORT_DIRECTION"); ok {
+		layer.SortDirection = &v
+	}
+	if v, ok := lookup("TOKEN_DISPLAY"); ok {
+		layer.TokenDisplay = &v
+	}
+	if v, ok := lookup("COLUMNS"); ok {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return layer, set, fmt.Errorf("%sCOLUMNS: %w", envPrefix, err)
+		}
+		layer.Columns = &b
+	}
+	if v, ok := lookup("MOUSE"); ok {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return layer, set, fmt.Errorf("%sMOUSE: %w", envPrefix, err)
+		}
+		layer.Mouse = &b
+	}
+	if v, ok := lookup("PAIR_TESTS"); ok {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return layer, set, fmt.Errorf("%sPAIR_TESTS: %w", envPrefix, err)
+		}
+		layer.PairTests = &b
+	}
+	if v, ok := lookup("PAIR_RULES"); ok {
+		rules := splitList(v)
+		layer.PairRules = &rules
+	}
+	if v, ok := lookup("MAX_FILE_SIZE"); ok {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return layer, set, fmt.Errorf("%sMAX_FILE_SIZE: %w", envPrefix, err)
+		}
+		layer.MaxFileSize = &n
+	}
+	if v, ok := lookup("LARGE_FILES"); ok {
+		layer.LargeFiles = &v
+	}
+	if v, ok := lookup("LARGE_FILE_LINES"); ok {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return layer, set, fmt.Errorf("%sLARGE_FILE_LINES: %w", envPrefix, err)
+		}
+		layer.LargeFileLines = &n
+	}
+	if v, ok := lookup("LAZY"); ok {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return layer, set, fmt.Errorf("%sLAZY: %w", envPrefix, err)
+		}
+		layer.Lazy = &b
+	}
+	if v, ok := lookup("FOLLOW_SYMLINKS"); ok {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return layer, set, fmt.Errorf("%sFOLLOW_SYMLINKS: %w", envPrefix, err)
+		}
+		layer.FollowSymlinks = &b
+	}
+	if v, ok := lookup("HIDDEN_ALLOW"); ok {
+		patterns := splitList(v)
+		layer.HiddenAllow = &patterns
+	}
+	if v, ok := lookup("REDACT"); ok {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return layer, set, fmt.Errorf("%sREDACT: %w", envPrefix, err)
+		}
+		layer.Redact = &b
+	}
+	if v, ok := lookup("TRANSFORMS"); ok {
+		names := splitList(v)
+		layer.Transforms = &names
+	}
+	if v, ok := lookup("COUNT_TRANSFORMED"); ok {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return layer, set, fmt.Errorf("%sCOUNT_TRANSFORMED: %w", envPrefix, err)
+		}
+		layer.CountTransformed = &b
+	}
+	if v, ok := lookup("OUTLINE"); ok {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return layer, set, fmt.Errorf("%sOUTLINE: %w", envPrefix, err)
+		}
+		layer.Outline = &b
+	}
+	if v, ok := lookup("LINE_NUMBERS"); ok {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return layer, set, fmt.Errorf("%sLINE_NUMBERS: %w", envPrefix, err)
+		}
+		layer.LineNumbers = &b
+	}
+	if v, ok := lookup("STRUCTURE"); ok {
+		layer.Structure = &v
+	}
+	if v, ok := lookup("STRUCTURE_DEPTH"); ok {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return layer, set, fmt.Errorf("%sSTRUCTURE_DEPTH: %w", envPrefix, err)
+		}
+		layer.StructureDepth = &n
+	}
+	if v, ok := lookup("IGNORE"); ok {
+		patterns := splitList(v)
+		layer.Ignore = &patterns
+	}
+	return layer, set, nil
+}
+
+// splitList splits a comma-separated environment value, dropping empty items
+func splitList(v string) []string {
+	var items []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// Loader resolves the effective configuration from every layer
+type Loader struct {
+	FS domain.FileSystem
+	// UserDir holds the user config; empty skips it
+	UserDir string
+	// ProjectRoot is the directory being browsed; empty skips the project config
+	ProjectRoot string
+	// Getenv reads environment variables; nil skips them
+	Getenv func(string) string
+	// Flags is the command-line layer, applied last
+	Flags Layer
+	// FlagNames lists the flags that were set, for reporting sources
+	FlagNames []string
+}
+
+// Resolve merges defaults, the user file, the project file, environment
+// variables and flags, in increasing precedence. It also returns a
+// description of each layer that contributed.
+func (l Loader) Resolve() (Config, []string, error) {
+	cfg := Default()
+	sources := []string{"defaults"}
+
+	for _, dir := range []string{l.UserDir, l.projectDir()} {
+		if dir == "" {
+			continue
+		}
+		path := Find(l.FS, dir)
+		if path == "" {
+			continue
+		}
+		layer, err := Load(l.FS, path)
+		if err != nil {
+			return cfg, sources, err
+		}
+		cfg = cfg.Apply(layer)
+		sources = append(sources, path)
+	}
+
+	if l.Getenv != nil {
+		layer, set, err := EnvLayer(l.Getenv)
+		if err != nil {
+			return cfg, sources, err
+		}
+		cfg = cfg.Apply(layer)
+		if len(set) > 0 {
+			sources = append(sources, "env: "+strings.Join(set, ", "))
+		}
+	}
+
+	cfg = cfg.Apply(l.Flags)
+	if len(l.FlagNames) > 0 {
+		sources = append(sources, "flags: -"+strings.Join(l.FlagNames, ", -"))
+	}
+	return cfg, sources, nil
+}
+
+func (l Loader) projectDir() string {
+	if l.ProjectRoot == "" {
+		return ""
+	}
+	return ProjectDir(l.ProjectRoot)
+}
+
+// Print writes cfg as YAML, preceded by a comment listing its sources
+func Print(w io.Writer, cfg Config, sources []string) error {
+	var buf bytes.Buffer
+	buf.WriteString("# Effective picky configuration\n")
+	buf.WriteString("# Sources (lowest to highest precedence):\n")
+	for _, s := range sources {
+		fmt.Fprintf(&buf, "#   %s\n", s)
+	}
+
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(cfg); err != nil {
+		return err
+	}
+	if err := enc.Close(); err != nil {
+		return err
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
@@ -0,0 +1,380 @@
+package config_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/eliooooooot/picky/internal/config"
+	"github.com/eliooooooot/picky/internal/fs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadMissingFile(t *testing.T) {
+	memfs := fs.NewMemFileSystem()
+
+	layer, err := config.Load(memfs, "/home/user/.config/picky/config.yaml")
+	require.NoError(t, err)
+	assert.Equal(t, config.Layer{}, layer)
+}
+
+func TestLoadKeys(t *testing.T) {
+	memfs := fs.NewMemFileSystem()
+	memfs.AddFile("/cfg/config.yaml", `
+keys:
+  preset: emacs
+  bindings:
+    generate: [G]
+    copy: ["y", "ctrl+y"]
+`)
+
+	cfg, err := config.Load(memfs, "/cfg/config.yaml")
+	require.NoError(t, err)
+	assert.Equal(t, "emacs", cfg.Keys.Preset)
+	assert.Equal(t, []string{"G"}, cfg.Keys.Bindings["generate"])
+	assert.Equal(t, []string{"y", "ctrl+y"}, cfg.Keys.Bindings["copy"])
+}
+
+func TestLoadInvalidYAML(t *testing.T) {
+	memfs := fs.NewMemFileSystem()
+	memfs.AddFile("/cfg/config.yaml", "keys: [unterminated")
+
+	_, err := config.Load(memfs, "/cfg/config.yaml")
+	assert.Error(t, err)
+}
+
+func TestUserPathHonoursXDG(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", "/xdg")
+
+	path, err := config.UserPath()
+	require.NoError(t, err)
+	assert.Equal(t, "/xdg/picky/config.yaml", path)
+}
+
+func TestLoadTOML(t *testing.T) {
+	memfs := fs.NewMemFileSystem()
+	memfs.AddFile("/proj/.picky/config.toml", `
+format = "xml"
+budget = 8000
+ignore = ["*.lock", "dist"]
+
+[keys.bindings]
+generate = ["G"]
+`)
+
+	layer, err := config.Load(memfs, "/proj/.picky/config.toml")
+	require.NoError(t, err)
+	require.NotNil(t, layer.Format)
+	assert.Equal(t, "xml", *layer.Format)
+	require.NotNil(t, layer.Budget)
+	assert.Equal(t, 8000, *layer.Budget)
+	require.NotNil(t, layer.Ignore)
+	assert.Equal(t, []string{"*.lock", "dist"}, *layer.Ignore)
+	assert.Nil(t, layer.Output, "unset fields stay nil")
+	assert.Equal(t, []string{"G"}, layer.Keys.Bindings["generate"])
+}
+
+func TestResolveLayersInOrder(t *testing.T) {
+	memfs := fs.NewMemFileSystem()
+	memfs.AddFile("/xdg/picky/config.yaml", `
+output: user.txt
+format: xml
+theme: Forest
+emoji: true
+keys:
+  preset: emacs
+  bindings:
+    copy: ["y"]
+`)
+	memfs.AddFile("/proj/.picky/config.toml", `
+output = "project.txt"
+tokenizer = "words"
+
+[keys.bindings]
+generate = ["G"]
+`)
+	env := map[string]string{
+		"PICKY_THEME":  "Neon",
+		"PICKY_IGNORE": "*.lock, vendor",
+	}
+	budget := 500
+
+	loader := config.Loader{
+		FS:          memfs,
+		UserDir:     "/xdg/picky",
+		ProjectRoot: "/proj",
+		Getenv:      func(k string) string { return env[k] },
+		Flags:       config.Layer{Budget: &budget},
+		FlagNames:   []string{"budget"},
+	}
+	cfg, sources, err := loader.Resolve()
+	require.NoError(t, err)
+
+	assert.Equal(t, "project.txt", cfg.Output, "project overrides user")
+	assert.Equal(t, "xml", cfg.Format, "user overrides defaults")
+	assert.Equal(t, "words", cfg.Tokenizer)
+	assert.Equal(t, "Neon", cfg.Theme, "env overrides user")
+	assert.True(t, cfg.Emoji)
+	assert.Equal(t, 500, cfg.Budget, "flags override everything")
+	assert.Equal(t, []string{"*.lock", "vendor"}, cfg.Ignore)
+	assert.Equal(t, "emacs", cfg.Keys.Preset)
+	assert.Equal(t, map[string][]string{"copy": {"y"}, "generate": {"G"}}, cfg.Keys.Bindings, "bindings merge per action")
+
+	assert.Equal(t, []string{
+		"defaults",
+		"/xdg/picky/config.yaml",
+		"/proj/.picky/config.toml",
+		"env: PICKY_THEME, PICKY_IGNORE",
+		"flags: -budget",
+	}, sources)
+}
+
+func TestResolveDefaults(t *testing.T) {
+	cfg, sources, err := config.Loader{FS: fs.NewMemFileSystem()}.Resolve()
+	require.NoError(t, err)
+	assert.Equal(t, config.Default(), cfg)
+	assert.Equal(t, []string{"defaults"}, sources)
+}
+
+func TestEnvLayerInvalidNumber(t *testing.T) {
+	_, _, err := config.EnvLayer(func(k string) string {
+		if k == "PICKY_BUDGET" {
+			return "lots"
+		}
+		return ""
+	})
+	assert.Error(t, err)
+}
+
+func TestPrint(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, config.Print(&buf, config.Default(), []string{"defaults"}))
+
+	out := buf.String()
+	assert.Contains(t, out, "#   defaults\n")
+	assert.Contains(t, out, "output: selected.txt\n")
+	assert.Contains(t, out, "max_tokens_per_part: 0\n")
+}
+
+func TestSaveMergesIntoExistingFile(t *testing.T) {
+	memfs := fs.NewMemFileSystem()
+	memfs.AddFile("/xdg/picky/config.yaml", `
+output: mine.txt
+keys:
+  preset: emacs
+`)
+
+	theme := "Forest"
+	hidden := false
+	require.NoError(t, config.Save(memfs, "/xdg/picky/config.yaml", config.Layer{Theme: &theme, ShowHidden: &hidden}))
+
+	layer, err := config.Load(memfs, "/xdg/picky/config.yaml")
+	require.NoError(t, err)
+	require.NotNil(t, layer.Output)
+	assert.Equal(t, "mine.txt", *layer.Output, "other settings are kept")
+	assert.Equal(t, "emacs", layer.Keys.Preset)
+	require.NotNil(t, layer.Theme)
+	assert.Equal(t, "Forest", *layer.Theme)
+	require.NotNil(t, layer.ShowHidden)
+	assert.False(t, *layer.ShowHidden)
+}
+
+func TestSaveCreatesTOMLFile(t *testing.T) {
+	memfs := fs.NewMemFileSystem()
+	format := "xml"
+	require.NoError(t, config.Save(memfs, "/xdg/picky/config.toml", config.Layer{Format: &format}))
+
+	layer, err := config.Load(memfs, "/xdg/picky/config.toml")
+	require.NoError(t, err)
+	require.NotNil(t, layer.Format)
+	assert.Equal(t, "xml", *layer.Format)
+}
+
+func TestThemesMergeAcrossLayers(t *testing.T) {
+	memfs := fs.NewMemFileSystem()
+	memfs.AddFile("/xdg/picky/config.yaml", `
+theme: mine
+themes:
+  mine:
+    selected: "#00ff88"
+    cursor_bg: "236"
+  other:
+    base: Forest
+`)
+	memfs.AddFile("/proj/.picky/config.toml", `
+[themes.mine]
+selected = "#112233"
+header = "#445566"
+`)
+
+	cfg, _, err := config.Loader{FS: memfs, UserDir: "/xdg/picky", ProjectRoot: "/proj"}.Resolve()
+	require.NoError(t, err)
+	assert.Equal(t, "mine", cfg.Theme)
+	assert.Equal(t, "#112233", cfg.Themes["mine"].Selected, "a later layer replaces the whole theme")
+	assert.Empty(t, cfg.Themes["mine"].CursorBg)
+	assert.Equal(t, "Forest", cfg.Themes["other"].Base)
+}
+
+func TestNoColorEnv(t *testing.T) {
+	env := map[string]string{"NO_COLOR": "1"}
+	cfg, sources, err := config.Loader{FS: fs.NewMemFileSystem(), Getenv: func(k string) string { return env[k] }}.Resolve()
+	require.NoError(t, err)
+	assert.Equal(t, "none", cfg.Theme)
+	assert.Contains(t, sources, "env: NO_COLOR")
+
+	env["PICKY_THEME"] = "Neon"
+	cfg, _, err = config.Loader{FS: fs.NewMemFileSystem(), Getenv: func(k string) string { return env[k] }}.Resolve()
+	require.NoError(t, err)
+	assert.Equal(t, "Neon", cfg.Theme, "an explicit theme wins over NO_COLOR")
+}
+
+func TestPairRulesDefaultAndEnv(t *testing.T) {
+	assert.Contains(t, config.Default().PairRules, "*.go:*_test.go")
+
+	layer, set, err := config.EnvLayer(func(k string) string {
+		switch k {
+		case "PICKY_PAIR_TESTS":
+			return "true"
+		case "PICKY_PAIR_RULES":
+			return "*.rb:*_spec.rb, *.ts:*.spec.ts"
+		}
+		return ""
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"PICKY_PAIR_TESTS", "PICKY_PAIR_RULES"}, set)
+
+	cfg := config.Default().Apply(layer)
+	assert.True(t, cfg.PairTests)
+	assert.Equal(t, []string{"*.rb:*_spec.rb", "*.ts:*.spec.ts"}, cfg.PairRules, "configured rules replace the built-ins")
+}
+
+func TestLargeFileSettingsFromEnv(t *testing.T) {
+	layer, _, err := config.EnvLayer(func(k string) string {
+		return map[string]string{
+			"PICKY_MAX_FILE_SIZE":    "4096",
+			"PICKY_LARGE_FILES":      "head-tail",
+			"PICKY_LARGE_FILE_LINES": "50",
+		}[k]
+	})
+	require.NoError(t, err)
+
+	cfg := config.Default().Apply(layer)
+	assert.Equal(t, 4096, cfg.MaxFileSize)
+	assert.Equal(t, "head-tail", cfg.LargeFiles)
+	assert.Equal(t, 50, cfg.LargeFileLines)
+
+	_, _, err = config.EnvLayer(func(k string) string {
+		if k == "PICKY_MAX_FILE_SIZE" {
+			return "1MB"
+		}
+		return ""
+	})
+	assert.Error(t, err)
+}
+
+func TestLazyFromEnv(t *testing.T) {
+	assert.False(t, config.Default().Lazy)
+
+	layer, _, err := config.EnvLayer(func(k string) string {
+		if k == "PICKY_LAZY" {
+			return "true"
+		}
+		return ""
+	})
+	require.NoError(t, err)
+	assert.True(t, config.Default().Apply(layer).Lazy)
+
+	_, _, err = config.EnvLayer(func(k string) string {
+		if k == "PICKY_LAZY" {
+			return "sometimes"
+		}
+		return ""
+	})
+	assert.Error(t, err)
+}
+
+func TestHiddenAllowDefaultAndEnv(t *testing.T) {
+	assert.Contains(t, config.Default().HiddenAllow, ".github/")
+
+	layer, _, err := config.EnvLayer(func(k string) string {
+		if k == "PICKY_HIDDEN_ALLOW" {
+			return ".env.example, .tool-versions"
+		}
+		return ""
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{".env.example", ".tool-versions"}, config.Default().Apply(layer).HiddenAllow)
+}
+
+func TestRedactDefaultAndEnv(t *testing.T) {
+	assert.True(t, config.Default().Redact)
+
+	layer, _, err := config.EnvLayer(func(k string) string {
+		if k == "PICKY_REDACT" {
+			return "false"
+		}
+		return ""
+	})
+	require.NoError(t, err)
+	assert.False(t, config.Default().Apply(layer).Redact)
+}
+
+func TestTransformsAndOutlineFromEnv(t *testing.T) {
+	assert.Empty(t, config.Default().Transforms)
+
+	layer, _, err := config.EnvLayer(func(k string) string {
+		switch k {
+		case "PICKY_TRANSFORMS":
+			return "comments,license"
+		case "PICKY_COUNT_TRANSFORMED":
+			return "1"
+		case "PICKY_OUTLINE":
+			return "true"
+		}
+		return ""
+	})
+	require.NoError(t, err)
+	cfg := config.Default().Apply(layer)
+	assert.Equal(t, []string{"comments", "license"}, cfg.Transforms)
+	assert.True(t, cfg.CountTransformed)
+	assert.True(t, cfg.Outline)
+}
+
+func TestLineNumbersFromEnv(t *testing.T) {
+	assert.False(t, config.Default().LineNumbers)
+
+	layer, _, err := config.EnvLayer(func(k string) string {
+		if k == "PICKY_LINE_NUMBERS" {
+			return "true"
+		}
+		return ""
+	})
+	require.NoError(t, err)
+	assert.True(t, config.Default().Apply(layer).LineNumbers)
+
+	_, _, err = config.EnvLayer(func(k string) string {
+		if k == "PICKY_LINE_NUMBERS" {
+			return "sometimes"
+		}
+		return ""
+	})
+	assert.Error(t, err)
+}
+
+func TestStructureFromEnv(t *testing.T) {
+	assert.Equal(t, "full", config.Default().Structure)
+
+	layer, _, err := config.EnvLayer(func(k string) string {
+		switch k {
+		case "PICKY_STRUCTURE":
+			return "collapsed"
+		case "PICKY_STRUCTURE_DEPTH":
+			return "3"
+		}
+		return ""
+	})
+	require.NoError(t, err)
+	cfg := config.Default().Apply(layer)
+	assert.Equal(t, "collapsed", cfg.Structure)
+	assert.Equal(t, 3, cfg.StructureDepth)
+}
@@ -0,0 +1,166 @@
+package domain
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// BudgetPolicy decides which selected files are given up first when fitting a budget
+type BudgetPolicy struct {
+	// TestsLast gives test files the lowest priority, so they are cut first
+	TestsLast bool
+	// LargestFirst cuts larger files before smaller ones
+	LargestFirst bool
+	// AllowTruncate trims the last file cut instead of dropping it entirely
+	AllowTruncate bool
+	// Overhead counts toward the budget on top of the selected files but
+	// is never cut, such as the directory structure written with them
+	Overhead int
+}
+
+// DefaultBudgetPolicy returns the policy used by the TUI
+func DefaultBudgetPolicy() BudgetPolicy {
+	return BudgetPolicy{
+		TestsLast:     true,
+		LargestFirst:  true,
+		AllowTruncate: true,
+	}
+}
+
+// BudgetPlan is a proposed change that brings a selection within a token budget
+type BudgetPlan struct {
+	Budget int
+	Before int
+	After  int
+
+	// Drop lists files to deselect, in the order they were cut
+	Drop []string
+
+	// Truncate maps files to the number of tokens they keep
+	Truncate map[string]int
+}
+
+// IsEmpty returns true if the plan changes nothing
+func (p BudgetPlan) IsEmpty() bool {
+	return len(p.Drop) == 0 && len(p.Truncate) == 0
+}
+
+// Fits returns true if the selection fits the budget once the plan is applied
+func (p BudgetPlan) Fits() bool {
+	return p.After <= p.Budget
+}
+
+// Apply returns a new state with the plan's drops and truncations applied
+func (p BudgetPlan) Apply(state ViewState) ViewState {
+	newState := state
+	for _, path := range p.Drop {
+		newState = newState.SetSelected(path, false)
+	}
+	for path, tokens := range p.Truncate {
+		newState = newState.SetTruncated(path, tokens)
+	}
+	return newState
+}
+
+// FitToBudget proposes which selected files to drop or truncate so the selection
+// fits within budget tokens. Pinned files are never touched.
+func FitToBudget(root *Node, state ViewState, tokens map[string]int, budget int, policy BudgetPolicy) BudgetPlan {
+	plan := BudgetPlan{
+		Budget:   budget,
+		Truncate: make(map[string]int),
+	}
+
+	plan.Before = SelectedTokens(root, state, tokens) + policy.Overhead
+	plan.After = plan.Before
+	if plan.After <= budget {
+		return plan
+	}
+
+	// Candidates are ordered from least to most important. Skipped and
+	// path-only files cost nothing, so dropping them would not help.
+	var candidates []string
+	for _, node := range GetSelectedFiles(root, state) {
+		if !state.IsPinned(node.Path) && !IsSkipped(node, state) && state.Mode(node.Path) != IncludePath {
+			candidates = append(candidates, node.Path)
+		}
+	}
+	cost := func(path string) int {
+		return EffectiveTokens(path, state, tokens)
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if policy.TestsLast {
+			if ta, tb := IsTestFile(a), IsTestFile(b); ta != tb {
+				return ta
+			}
+		}
+		if policy.LargestFirst {
+			if ca, cb := cost(a), cost(b); ca != cb {
+				return ca > cb
+			}
+		}
+		return false
+	})
+
+	for _, path := range candidates {
+		if plan.After <= budget {
+			break
+		}
+		c := cost(path)
+		excess := plan.After - budget
+		if policy.AllowTruncate && c > excess {
+			// Keeping part of this file is enough to fit
+			plan.Truncate[path] = c - excess
+			plan.After = budget
+			break
+		}
+		plan.Drop = append(plan.Drop, path)
+		plan.After -= c
+	}
+
+	return plan
+}
+
+// SelectedTokens sums the tokens of all selected files, honouring truncations.
+// Binary and generated files count only when forced.
+func SelectedTokens(root *Node, state ViewState, tokens map[string]int) int {
+	if tokens == nil {
+		return 0
+	}
+	total := 0
+	for _, node := range GetSelectedFiles(root, state) {
+		if !IsSkipped(node, state) {
+			total += EffectiveTokens(node.Path, state, tokens)
+		}
+	}
+	return total
+}
+
+// EffectiveTokens returns a file's token count after any truncation. A
+// path-only file costs nothing; callers that count other inclusion modes
+// pass the mode's counts in tokens.
+func EffectiveTokens(path string, state ViewState, tokens map[string]int) int {
+	if state.Mode(path) == IncludePath {
+		return 0
+	}
+	n := tokens[path]
+	if limit, ok := state.TruncatedTokens(path); ok && limit < n {
+		return limit
+	}
+	return n
+}
+
+// IsTestFile reports whether a path looks like a test file in common ecosystems
+func IsTestFile(path string) bool {
+	name := filepath.Base(path)
+	switch {
+	case strings.HasSuffix(name, "_test.go"):
+		return true
+	case strings.Contains(name, ".test.") || strings.Contains(name, ".spec."):
+		return true
+	case strings.HasPrefix(name, "test_") && strings.HasSuffix(name, ".py"):
+		return true
+	}
+	return false
+}
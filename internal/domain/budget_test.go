@@ -0,0 +1,146 @@
+package domain_test
+
+import (
+	"testing"
+
+	"github.com/eliooooooot/picky/internal/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func budgetTree() *domain.Node {
+	return &domain.Node{
+		Path:  "/root",
+		Name:  "root",
+		IsDir: true,
+		Children: []*domain.Node{
+			{Path: "/root/big.go", Name: "big.go"},
+			{Path: "/root/big_test.go", Name: "big_test.go"},
+			{Path: "/root/small.go", Name: "small.go"},
+			{Path: "/root/medium.go", Name: "medium.go"},
+		},
+	}
+}
+
+func selectAll(root *domain.Node) domain.ViewState {
+	state := domain.NewViewState(root.Path)
+	for _, child := range root.Children {
+		state = state.SetSelected(child.Path, true)
+	}
+	return state
+}
+
+func TestFitToBudget(t *testing.T) {
+	tokens := map[string]int{
+		"/root/big.go":      500,
+		"/root/big_test.go": 300,
+		"/root/small.go":    50,
+		"/root/medium.go":   200,
+	}
+
+	t.Run("already within budget", func(t *testing.T) {
+		root := budgetTree()
+		plan := domain.FitToBudget(root, selectAll(root), tokens, 2000, domain.DefaultBudgetPolicy())
+
+		assert.True(t, plan.IsEmpty())
+		assert.True(t, plan.Fits())
+		assert.Equal(t, 1050, plan.Before)
+		assert.Equal(t, 1050, plan.After)
+	})
+
+	t.Run("drops tests first then largest files", func(t *testing.T) {
+		root := budgetTree()
+		policy := domain.DefaultBudgetPolicy()
+		policy.AllowTruncate = false
+
+		plan := domain.FitToBudget(root, selectAll(root), tokens, 300, policy)
+
+		assert.Equal(t, []string{"/root/big_test.go", "/root/big.go"}, plan.Drop)
+		assert.Empty(t, plan.Truncate)
+		assert.Equal(t, 250, plan.After)
+		assert.True(t, plan.Fits())
+	})
+
+	t.Run("truncates the last file instead of dropping it", func(t *testing.T) {
+		root := budgetTree()
+		plan := domain.FitToBudget(root, selectAll(root), tokens, 600, domain.DefaultBudgetPolicy())
+
+		assert.Equal(t, []string{"/root/big_test.go"}, plan.Drop)
+		assert.Equal(t, map[string]int{"/root/big.go": 350}, plan.Truncate)
+		assert.Equal(t, 600, plan.After)
+	})
+
+	t.Run("pinned files are kept", func(t *testing.T) {
+		root := budgetTree()
+		state := selectAll(root).SetPinned("/root/big_test.go", true)
+		policy := domain.DefaultBudgetPolicy()
+		policy.AllowTruncate = false
+
+		plan := domain.FitToBudget(root, state, tokens, 400, policy)
+
+		assert.NotContains(t, plan.Drop, "/root/big_test.go")
+		assert.Equal(t, []string{"/root/big.go", "/root/medium.go"}, plan.Drop)
+		assert.Equal(t, 350, plan.After)
+	})
+
+	t.Run("reports when pinned files alone exceed the budget", func(t *testing.T) {
+		root := budgetTree()
+		state := domain.NewViewState(root.Path).
+			SetSelected("/root/big.go", true).
+			SetPinned("/root/big.go", true)
+
+		plan := domain.FitToBudget(root, state, tokens, 100, domain.DefaultBudgetPolicy())
+
+		assert.True(t, plan.IsEmpty())
+		assert.False(t, plan.Fits())
+	})
+
+	t.Run("overhead counts but is never cut", func(t *testing.T) {
+		root := budgetTree()
+		policy := domain.DefaultBudgetPolicy()
+		policy.AllowTruncate = false
+		policy.Overhead = 100
+
+		plan := domain.FitToBudget(root, selectAll(root), tokens, 1100, policy)
+
+		assert.Equal(t, 1150, plan.Before)
+		assert.Equal(t, []string{"/root/big_test.go"}, plan.Drop)
+		assert.Equal(t, 850, plan.After)
+	})
+
+	t.Run("path-only files cost nothing", func(t *testing.T) {
+		root := budgetTree()
+		state := selectAll(root).SetMode("/root/big.go", domain.IncludePath)
+		policy := domain.DefaultBudgetPolicy()
+		policy.AllowTruncate = false
+
+		plan := domain.FitToBudget(root, state, tokens, 300, policy)
+
+		assert.Equal(t, 550, plan.Before)
+		assert.Equal(t, []string{"/root/big_test.go"}, plan.Drop)
+		assert.Equal(t, 250, plan.After)
+	})
+
+	t.Run("apply deselects and truncates", func(t *testing.T) {
+		root := budgetTree()
+		state := selectAll(root)
+		plan := domain.FitToBudget(root, state, tokens, 600, domain.DefaultBudgetPolicy())
+
+		applied := plan.Apply(state)
+
+		assert.False(t, applied.IsSelected("/root/big_test.go"))
+		assert.True(t, applied.IsSelected("/root/big.go"))
+		limit, ok := applied.TruncatedTokens("/root/big.go")
+		assert.True(t, ok)
+		assert.Equal(t, 350, limit)
+		assert.Equal(t, 600, domain.SelectedTokens(root, applied, tokens))
+	})
+}
+
+func TestIsTestFile(t *testing.T) {
+	assert.True(t, domain.IsTestFile("/root/foo_test.go"))
+	assert.True(t, domain.IsTestFile("/root/foo.spec.ts"))
+	assert.True(t, domain.IsTestFile("/root/foo.test.js"))
+	assert.True(t, domain.IsTestFile("/root/test_foo.py"))
+	assert.False(t, domain.IsTestFile("/root/foo.go"))
+	assert.False(t, domain.IsTestFile("/root/testdata.go"))
+}
@@ -0,0 +1,130 @@
+package domain
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Matcher reports whether a bulk selection applies to a file
+type Matcher func(file *Node) bool
+
+// SelectAll selects every visible file under dir; pass the root for the whole tree
+func SelectAll(dir *Node, state ViewState) ViewState {
+	return SetRangeSelected([]*Node{dir}, state, true)
+}
+
+// ClearAll deselects every visible file under dir; pass the root for the whole tree
+func ClearAll(dir *Node, state ViewState) ViewState {
+	return SetRangeSelected([]*Node{dir}, state, false)
+}
+
+// InvertSubtree flips the selection of every file under the cursor node
+func InvertSubtree(root *Node, state ViewState) ViewState {
+	cursor := FindNodeByPath(root, state.CursorPath)
+	if cursor == nil {
+		return state
+	}
+	return InvertRange([]*Node{cursor}, state)
+}
+
+// SetSelectedWhere selects or deselects the visible files under dir that
+// match. It returns the new state and the number of files that matched.
+func SetSelectedWhere(dir *Node, state ViewState, match Matcher, selected bool) (ViewState, int) {
+	var paths []string
+	var walk func(node *Node)
+	walk = func(node *Node) {
+		if !node.IsDir {
+			if match(node) {
+				paths = append(paths, node.Path)
+			}
+			return
+		}
+		node.Load()
+		for _, child := range VisibleChildren(node, state) {
+			walk(child)
+		}
+	}
+	walk(dir)
+	if len(paths) == 0 {
+		return state, 0
+	}
+
+	newState := syncDirSelection(dir, state.setSelectedPaths(paths, selected))
+	return refreshDirSelection([]*Node{dir}, newState), len(paths)
+}
+
+// GlobMatcher matches files against a shell pattern such as "*_test.go".
+// Patterns containing a slash match the path relative to root, others the file name.
+func GlobMatcher(root *Node, pattern string) (Matcher, error) {
+	pattern = filepath.ToSlash(pattern)
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, fmt.Errorf("invalid glob %q: %w", pattern, err)
+	}
+	return func(file *Node) bool {
+		if !strings.Contains(pattern, "/") {
+			ok, _ := path.Match(pattern, file.Name)
+			return ok
+		}
+		rel, err := filepath.Rel(root.Path, file.Path)
+		if err != nil {
+			return false
+		}
+		ok, _ := path.Match(pattern, filepath.ToSlash(rel))
+		return ok
+	}, nil
+}
+
+// ExtensionMatcher matches files by extension, with or without the leading dot
+func ExtensionMatcher(ext string) Matcher {
+	if !strings.HasPrefix(ext, ".") {
+		ext = "." + ext
+	}
+	return func(file *Node) bool {
+		return strings.EqualFold(filepath.Ext(file.Name), ext)
+	}
+}
+
+// ContentMatcher matches files whose contents match re. Files that cannot
+// be read or are over maxSize bytes never match; a maxSize of zero or less
+// reads every file.
+func ContentMatcher(fsys FileSystem, re *regexp.Regexp, maxSize int) Matcher {
+	return func(file *Node) bool {
+		data, err := readWithin(fsys, file.Path, maxSize)
+		if err != nil {
+			return false
+		}
+		return re.Match(data)
+	}
+}
+
+// syncDirSelection sets the flag of every directory under node to whether
+// all of its files are selected, in one bottom-up pass. Unloaded directories
+// keep their flag and count as one file.
+func syncDirSelection(node *Node, state ViewState) ViewState {
+	var full, partial []string
+	var walk func(node *Node) (selected, total int)
+	walk = func(node *Node) (selected, total int) {
+		if !node.IsDir || !node.IsLoaded() {
+			if state.IsSelected(node.Path) {
+				return 1, 1
+			}
+			return 0, 1
+		}
+		for _, child := range VisibleChildren(node, state) {
+			s, t := walk(child)
+			selected += s
+			total += t
+		}
+		if selected > 0 && selected == total {
+			full = append(full, node.Path)
+		} else {
+			partial = append(partial, node.Path)
+		}
+		return selected, total
+	}
+	walk(node)
+	return state.setSelectedPaths(full, true).setSelectedPaths(partial, false)
+}
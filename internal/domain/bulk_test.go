@@ -0,0 +1,106 @@
+package domain_test
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/eliooooooot/picky/internal/domain"
+	pickyfs "github.com/eliooooooot/picky/internal/fs"
+)
+
+func selectedSet(root *domain.Node, state domain.ViewState) map[string]bool {
+	set := make(map[string]bool)
+	for _, p := range domain.GetSelectedPaths(root, state) {
+		set[p] = true
+	}
+	return set
+}
+
+func TestSelectAllAndClearAll(t *testing.T) {
+	root := rangeTree()
+	state := domain.NewViewState("/r")
+
+	state = domain.SelectAll(root, state)
+	if got := len(domain.GetSelectedPaths(root, state)); got != 5 {
+		t.Fatalf("SelectAll selected %d files, want 5", got)
+	}
+	if !state.IsSelected("/r/lib") {
+		t.Error("directories should be marked selected")
+	}
+
+	state = domain.ClearAll(root, state)
+	if got := domain.GetSelectedPaths(root, state); len(got) != 0 {
+		t.Errorf("ClearAll left %v selected", got)
+	}
+}
+
+func TestInvertSubtree(t *testing.T) {
+	root := rangeTree()
+	state := domain.NewViewState("/r").SetSelected("/r/lib/x.go", true).SetSelected("/r/a.go", true)
+	state = state.SetCursor("/r/lib")
+
+	state = domain.InvertSubtree(root, state)
+	got := selectedSet(root, state)
+	want := map[string]bool{"/r/lib/y.go": true, "/r/a.go": true}
+	if len(got) != len(want) || !got["/r/lib/y.go"] || !got["/r/a.go"] {
+		t.Errorf("InvertSubtree selected %v, want %v", got, want)
+	}
+}
+
+func TestSetSelectedWhereGlob(t *testing.T) {
+	root := rangeTree()
+	state := domain.NewViewState("/r")
+
+	match, err := domain.GlobMatcher(root, "lib/*.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	state, n := domain.SetSelectedWhere(root, state, match, true)
+	if n != 2 || !state.IsSelected("/r/lib/x.go") || !state.IsSelected("/r/lib/y.go") || state.IsSelected("/r/a.go") {
+		t.Errorf("glob with a slash should match relative paths, matched %d", n)
+	}
+	if !state.IsSelected("/r/lib") {
+		t.Error("lib is fully selected and should be marked so")
+	}
+
+	// Name-only patterns, limited to a subtree
+	match, _ = domain.GlobMatcher(root, "x.*")
+	state, n = domain.SetSelectedWhere(root.Children[0], state, match, false)
+	if n != 1 || state.IsSelected("/r/lib/x.go") || state.IsSelected("/r/lib") {
+		t.Errorf("deselect by name failed, matched %d", n)
+	}
+
+	if _, err := domain.GlobMatcher(root, "[bad"); err == nil {
+		t.Error("expected an error for a malformed glob")
+	}
+}
+
+func TestSetSelectedWhereExtensionAndContent(t *testing.T) {
+	fs := pickyfs.NewMemFileSystem()
+	fs.WriteFile("/p/main.go", []byte("package main\n// TODO: tidy\n"), 0644)
+	fs.WriteFile("/p/util.go", []byte("package main\n"), 0644)
+	fs.WriteFile("/p/README.MD", []byte("# TODO\n"), 0644)
+
+	tree, err := domain.BuildTree(fs, "/p")
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := tree.Root
+
+	state, n := domain.SetSelectedWhere(root, domain.NewViewState("/p"), domain.ExtensionMatcher("md"), true)
+	if n != 1 || !state.IsSelected("/p/README.MD") {
+		t.Errorf("extension match should ignore case and the missing dot, matched %d", n)
+	}
+
+	state, n = domain.SetSelectedWhere(root, domain.NewViewState("/p"), domain.ContentMatcher(fs, regexp.MustCompile(`TODO`), 0), true)
+	got := selectedSet(root, state)
+	if n != 2 || !got["/p/main.go"] || !got["/p/README.MD"] || got["/p/util.go"] {
+		t.Errorf("content match selected %v", got)
+	}
+
+	// Files over the size limit never match
+	state, n = domain.SetSelectedWhere(root, domain.NewViewState("/p"), domain.ContentMatcher(fs, regexp.MustCompile(`TODO`), 10), true)
+	if got := selectedSet(root, state); n != 1 || !got["/p/README.MD"] {
+		t.Errorf("content match with a size limit selected %v", got)
+	}
+}
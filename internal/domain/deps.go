@@ -0,0 +1,188 @@
+package domain
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ImportGraph links the Go files in a tree to the in-module packages they import.
+// Packages are identified by their directory path.
+type ImportGraph struct {
+	// fileImports maps each Go file to the package directories it imports
+	fileImports map[string][]string
+	// packageFiles maps each package directory to its non-test Go files
+	packageFiles map[string][]*Node
+	// files maps every Go file path to its node, tests included
+	files map[string]*Node
+}
+
+// ModulePath reads the module path from the go.mod file in dir
+func ModulePath(fsys FileSystem, dir string) (string, error) {
+	data, err := fsys.ReadFile(filepath.Join(dir, "go.mod"))
+	if err != nil {
+		return "", err
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 2 && fields[0] == "module" {
+			if path, err := strconv.Unquote(fields[1]); err == nil {
+				return path, nil
+			}
+			return fields[1], nil
+		}
+	}
+	return "", fmt.Errorf("no module directive in %s", filepath.Join(dir, "go.mod"))
+}
+
+// BuildImportGraph parses the imports of every Go file under root. Import
+// paths are resolved against the module path of root's go.mod; imports of
+// other modules and the standard library are ignored. Files over maxSize
+// bytes are not parsed, unless maxSize is zero or less. A lazy tree is
+// loaded in full, since an import can point anywhere in the module.
+func BuildImportGraph(root *Node, fsys FileSystem, maxSize int) (*ImportGraph, error) {
+	modPath, err := ModulePath(fsys, root.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	g := &ImportGraph{
+		fileImports:  make(map[string][]string),
+		packageFiles: make(map[string][]*Node),
+		files:        make(map[string]*Node),
+	}
+	fset := token.NewFileSet()
+	var walk func(node *Node)
+	walk = func(node *Node) {
+		if node.IsDir {
+			node.Load()
+			for _, child := range node.Children {
+				walk(child)
+			}
+			return
+		}
+		if !strings.HasSuffix(node.Name, ".go") {
+			return
+		}
+		g.files[node.Path] = node
+		if !strings.HasSuffix(node.Name, "_test.go") {
+			dir := filepath.Dir(node.Path)
+			g.packageFiles[dir] = append(g.packageFiles[dir], node)
+		}
+
+		src, err := readWithin(fsys, node.Path, maxSize)
+		if err != nil {
+			return
+		}
+		f, err := parser.ParseFile(fset, node.Path, src, parser.ImportsOnly)
+		if err != nil {
+			return
+		}
+		for _, imp := range f.Imports {
+			path, err := strconv.Unquote(imp.Path.Value)
+			if err != nil {
+				continue
+			}
+			if path != modPath && !strings.HasPrefix(path, modPath+"/") {
+				continue
+			}
+			dir := filepath.Join(root.Path, filepath.FromSlash(strings.TrimPrefix(path, modPath)))
+			g.fileImports[node.Path] = append(g.fileImports[node.Path], dir)
+		}
+	}
+	walk(root)
+	return g, nil
+}
+
+// GoFilesAt returns the Go files a dependency action starts from: the file
+// itself, or the Go files directly inside a directory
+func GoFilesAt(node *Node) []*Node {
+	if !node.IsDir {
+		if strings.HasSuffix(node.Name, ".go") {
+			return []*Node{node}
+		}
+		return nil
+	}
+	var files []*Node
+	for _, child := range node.Children {
+		if !child.IsDir && strings.HasSuffix(child.Name, ".go") {
+			files = append(files, child)
+		}
+	}
+	return files
+}
+
+// Dependencies returns the files of the in-module packages imported by the
+// start files, following imports up to depth levels (0 means no limit).
+// The start files' own package is not included.
+func (g *ImportGraph) Dependencies(start []*Node, depth int) []*Node {
+	seen := make(map[string]bool)
+	for _, file := range start {
+		seen[filepath.Dir(file.Path)] = true
+	}
+
+	var result []*Node
+	frontier := start
+	for level := 1; len(frontier) > 0 && (depth <= 0 || level <= depth); level++ {
+		var next []*Node
+		for _, file := range frontier {
+			for _, dir := range g.fileImports[file.Path] {
+				if seen[dir] {
+					continue
+				}
+				seen[dir] = true
+				result = append(result, g.packageFiles[dir]...)
+				next = append(next, g.packageFiles[dir]...)
+			}
+		}
+		frontier = next
+	}
+	return result
+}
+
+// Dependents returns the Go files, tests included, that import the start
+// files' packages, following importers up to depth levels (0 means no limit)
+func (g *ImportGraph) Dependents(start []*Node, depth int) []*Node {
+	targets := make(map[string]bool)
+	chosen := make(map[string]bool)
+	for _, file := range start {
+		targets[filepath.Dir(file.Path)] = true
+		chosen[file.Path] = true
+	}
+	searched := make(map[string]bool)
+	for dir := range targets {
+		searched[dir] = true
+	}
+
+	var result []*Node
+	for level := 1; len(targets) > 0 && (depth <= 0 || level <= depth); level++ {
+		next := make(map[string]bool)
+		for path, imports := range g.fileImports {
+			if chosen[path] {
+				continue
+			}
+			for _, dir := range imports {
+				if targets[dir] {
+					chosen[path] = true
+					result = append(result, g.files[path])
+					if dir := filepath.Dir(path); !searched[dir] {
+						searched[dir] = true
+						next[dir] = true
+					}
+					break
+				}
+			}
+		}
+		targets = next
+	}
+	// Map iteration order is random, keep the result stable
+	sort.Slice(result, func(i, j int) bool { return result[i].Path < result[j].Path })
+	return result
+}
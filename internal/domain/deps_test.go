@@ -0,0 +1,103 @@
+package domain_test
+
+import (
+	"testing"
+
+	"github.com/eliooooooot/picky/internal/domain"
+	pickyfs "github.com/eliooooooot/picky/internal/fs"
+)
+
+// depsTree builds a module where main imports api, api imports store and
+// store imports only the standard library and another module
+func depsTree(t *testing.T) (*domain.Tree, *pickyfs.MemFileSystem) {
+	fs := pickyfs.NewMemFileSystem()
+	fs.WriteFile("/m/go.mod", []byte("module example.com/m\n\ngo 1.23\n"), 0644)
+	fs.WriteFile("/m/main.go", []byte("package main\n\nimport (\n\t\"fmt\"\n\t\"example.com/m/api\"\n)\n"), 0644)
+	fs.WriteFile("/m/api/api.go", []byte("package api\n\nimport \"example.com/m/store\"\n"), 0644)
+	fs.WriteFile("/m/api/api_test.go", []byte("package api\n\nimport \"testing\"\n"), 0644)
+	fs.WriteFile("/m/store/store.go", []byte("package store\n\nimport \"github.com/other/lib\"\n"), 0644)
+	fs.WriteFile("/m/store/store_test.go", []byte("package store_test\n\nimport \"example.com/m/store\"\n"), 0644)
+
+	tree, err := domain.BuildTree(fs, "/m")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tree, fs
+}
+
+func paths(nodes []*domain.Node) []string {
+	out := make([]string, len(nodes))
+	for i, n := range nodes {
+		out[i] = n.Path
+	}
+	return out
+}
+
+func TestModulePath(t *testing.T) {
+	_, fs := depsTree(t)
+	mod, err := domain.ModulePath(fs, "/m")
+	if err != nil || mod != "example.com/m" {
+		t.Errorf("ModulePath = %q, %v", mod, err)
+	}
+	if _, err := domain.ModulePath(fs, "/m/api"); err == nil {
+		t.Error("expected an error without go.mod")
+	}
+}
+
+func TestDependencies(t *testing.T) {
+	tree, fs := depsTree(t)
+	g, err := domain.BuildImportGraph(tree.Root, fs, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	main := domain.FindNodeByPath(tree.Root, "/m/main.go")
+
+	got := paths(g.Dependencies([]*domain.Node{main}, 1))
+	if len(got) != 1 || got[0] != "/m/api/api.go" {
+		t.Errorf("depth 1 = %v, want [/m/api/api.go]", got)
+	}
+
+	got = paths(g.Dependencies([]*domain.Node{main}, 0))
+	if len(got) != 2 || got[1] != "/m/store/store.go" {
+		t.Errorf("unlimited depth = %v, want api.go and store.go", got)
+	}
+}
+
+func TestDependents(t *testing.T) {
+	tree, fs := depsTree(t)
+	g, err := domain.BuildImportGraph(tree.Root, fs, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := domain.GoFilesAt(domain.FindNodeByPath(tree.Root, "/m/store"))
+	if len(start) != 2 {
+		t.Fatalf("GoFilesAt(store) = %v", paths(start))
+	}
+
+	got := paths(g.Dependents(start, 1))
+	if len(got) != 1 || got[0] != "/m/api/api.go" {
+		t.Errorf("depth 1 = %v, want [/m/api/api.go]", got)
+	}
+
+	got = paths(g.Dependents(start, 2))
+	if len(got) != 2 || got[0] != "/m/api/api.go" || got[1] != "/m/main.go" {
+		t.Errorf("depth 2 = %v, want api.go and main.go", got)
+	}
+}
+
+func TestImportGraphSkipsOversizedFiles(t *testing.T) {
+	tree, fs := depsTree(t)
+	// main.go is over the limit, api.go is not
+	g, err := domain.BuildImportGraph(tree.Root, fs, 46)
+	if err != nil {
+		t.Fatal(err)
+	}
+	main := domain.FindNodeByPath(tree.Root, "/m/main.go")
+	if got := paths(g.Dependencies([]*domain.Node{main}, 0)); len(got) != 0 {
+		t.Errorf("oversized main.go was parsed: %v", got)
+	}
+	api := domain.FindNodeByPath(tree.Root, "/m/api/api.go")
+	if got := paths(g.Dependencies([]*domain.Node{api}, 0)); len(got) != 1 || got[0] != "/m/store/store.go" {
+		t.Errorf("api.go dependencies = %v, want [/m/store/store.go]", got)
+	}
+}
@@ -0,0 +1,112 @@
+package domain
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// sniffLen is how much of the start of a file is checked to classify it
+const sniffLen = 8000
+
+// FileKind classifies a file's contents for output
+type FileKind string
+
+const (
+	// KindText is ordinary source or text, written in full
+	KindText FileKind = ""
+	// KindBinary is an image, executable or other non-text file
+	KindBinary FileKind = "binary"
+	// KindGenerated is text produced by a tool, such as generated code or a lockfile
+	KindGenerated FileKind = "generated"
+	// KindSymlink is a symbolic link that is not followed; its target is never read
+	KindSymlink FileKind = "symlink"
+)
+
+// generatedHeader matches the marker tools put at the top of generated code,
+// as in "// Code generated by stringer; DO NOT EDIT."
+var generatedHeader = regexp.MustCompile(`(?m)^\s*(//|#|--|/\*|\*)\s*Code generated .* DO NOT EDIT\.?`)
+
+// lockfiles are dependency lockfiles, which are generated but carry no header
+var lockfiles = map[string]bool{
+	"package-lock.json":   true,
+	"npm-shrinkwrap.json": true,
+	"yarn.lock":           true,
+	"pnpm-lock.yaml":      true,
+	"bun.lockb":           true,
+	"go.sum":              true,
+	"Cargo.lock":          true,
+	"Gemfile.lock":        true,
+	"composer.lock":       true,
+	"poetry.lock":         true,
+	"Pipfile.lock":        true,
+	"uv.lock":             true,
+	"Podfile.lock":        true,
+	"pubspec.lock":        true,
+	"mix.lock":            true,
+	"flake.lock":          true,
+}
+
+// DetectKind classifies a file from its name and the start of its content
+func DetectKind(name string, head []byte) FileKind {
+	switch {
+	case IsBinary(head):
+		return KindBinary
+	case lockfiles[name] || IsGenerated(head):
+		return KindGenerated
+	}
+	return KindText
+}
+
+// SniffKind reads the start of the file at path and classifies it.
+// Files that cannot be read are treated as text, so errors surface on output.
+func SniffKind(fsys FileSystem, path string, name string) FileKind {
+	f, err := fsys.Open(path)
+	if err != nil {
+		return KindText
+	}
+	defer f.Close()
+	head, err := io.ReadAll(io.LimitReader(f, sniffLen))
+	if err != nil {
+		return KindText
+	}
+	return DetectKind(name, head)
+}
+
+// IsBinary reports whether content looks binary: a NUL byte near the start,
+// or a sniffed content type that is not text
+func IsBinary(content []byte) bool {
+	if len(content) > sniffLen {
+		content = content[:sniffLen]
+	}
+	if bytes.IndexByte(content, 0) >= 0 {
+		return true
+	}
+	return !strings.HasPrefix(http.DetectContentType(content), "text/")
+}
+
+// IsGenerated reports whether content starts with a "Code generated ... DO NOT EDIT." header
+func IsGenerated(content []byte) bool {
+	if len(content) > sniffLen {
+		content = content[:sniffLen]
+	}
+	return generatedHeader.Match(content)
+}
+
+// IsSkipped reports whether a file is left out of token totals and output:
+// it is binary or generated and has not been forced in, or it is a link
+// that is not followed
+func IsSkipped(node *Node, state ViewState) bool {
+	if node.Kind == KindSymlink {
+		return true
+	}
+	return !node.IsDir && node.Kind != KindText && !state.IsForced(node.Path)
+}
+
+// IsOversized reports whether a file is larger than limit bytes.
+// A non-positive limit disables the check.
+func IsOversized(node *Node, limit int) bool {
+	return !node.IsDir && limit > 0 && node.Size > int64(limit)
+}
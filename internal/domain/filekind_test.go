@@ -0,0 +1,81 @@
+package domain_test
+
+import (
+	"testing"
+
+	"github.com/eliooooooot/picky/internal/domain"
+	pickyfs "github.com/eliooooooot/picky/internal/fs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetectKind(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		want    domain.FileKind
+	}{
+		{"source", "main.go", "package main\n", domain.KindText},
+		{"empty", "empty.txt", "", domain.KindText},
+		{"nul byte", "data.bin", "abc\x00def", domain.KindBinary},
+		{"png magic", "logo.png", "\x89PNG\r\n\x1a\nIHDR", domain.KindBinary},
+		{"pdf magic", "doc.pdf", "%PDF-1.7\n", domain.KindBinary},
+		{"go generated", "api.pb.go", "// Code generated by protoc-gen-go. DO NOT EDIT.\n\npackage api\n", domain.KindGenerated},
+		{"generated after license", "z.go", "// Copyright 2024\n\n// Code generated by stringer; DO NOT EDIT.\npackage z\n", domain.KindGenerated},
+		{"hash comment", "gen.py", "# Code generated by tool. DO NOT EDIT.\n", domain.KindGenerated},
+		{"mention in prose", "notes.md", "The header says Code generated ... DO NOT EDIT.\n", domain.KindText},
+		{"lockfile", "package-lock.json", "{}\n", domain.KindGenerated},
+		{"go.sum", "go.sum", "example.com/x v1.0.0 h1:abc=\n", domain.KindGenerated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, domain.DetectKind(tt.file, []byte(tt.content)))
+		})
+	}
+}
+
+func TestBuildTreeMarksFileKinds(t *testing.T) {
+	fs := pickyfs.NewMemFileSystem()
+	fs.WriteFile("/p/main.go", []byte("package main\n"), 0644)
+	fs.WriteFile("/p/yarn.lock", []byte("# yarn lockfile v1\n"), 0644)
+	fs.WriteFile("/p/app.exe", []byte("MZ\x90\x00\x03"), 0644)
+
+	tree, err := domain.BuildTree(fs, "/p")
+	require.NoError(t, err)
+
+	kinds := make(map[string]domain.FileKind)
+	for _, n := range tree.Root.Children {
+		kinds[n.Name] = n.Kind
+	}
+	assert.Equal(t, map[string]domain.FileKind{
+		"main.go":   domain.KindText,
+		"yarn.lock": domain.KindGenerated,
+		"app.exe":   domain.KindBinary,
+	}, kinds)
+}
+
+func TestSelectedTokensSkipsUnforcedFiles(t *testing.T) {
+	fs := pickyfs.NewMemFileSystem()
+	fs.WriteFile("/p/main.go", []byte("package main\n"), 0644)
+	fs.WriteFile("/p/go.sum", []byte("example.com/x v1.0.0 h1:abc=\n"), 0644)
+
+	tree, err := domain.BuildTree(fs, "/p")
+	require.NoError(t, err)
+	tokens := map[string]int{"/p/main.go": 10, "/p/go.sum": 500}
+
+	state := domain.NewViewState("/p")
+	state = state.SetSelected("/p/main.go", true).SetSelected("/p/go.sum", true)
+	assert.Equal(t, 10, domain.SelectedTokens(tree.Root, state, tokens))
+
+	// Skipped files are never budget candidates
+	plan := domain.FitToBudget(tree.Root, state, tokens, 100, domain.DefaultBudgetPolicy())
+	assert.True(t, plan.IsEmpty())
+
+	state = state.SetForced("/p/go.sum", true)
+	assert.Equal(t, 510, domain.SelectedTokens(tree.Root, state, tokens))
+
+	// Deselecting a file drops its force
+	state = state.SetSelected("/p/go.sum", false)
+	assert.False(t, state.IsForced("/p/go.sum"))
+}
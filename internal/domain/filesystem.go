@@ -1,6 +1,7 @@
 package domain
 
 import (
+	"errors"
 	"io"
 	"io/fs"
 )
@@ -14,4 +15,41 @@ type FileSystem interface {
 	ReadFile(path string) ([]byte, error)
 	WriteFile(path string, data []byte, perm fs.FileMode) error
 	MkdirAll(path string, perm fs.FileMode) error
-}
\ No newline at end of file
+}
+
+// LinkFileSystem is a FileSystem that knows about symbolic links. Trees
+// built from one mark links with their target and detect link loops.
+type LinkFileSystem interface {
+	FileSystem
+	// Lstat is like Stat but describes a link itself rather than its target
+	Lstat(path string) (fs.FileInfo, error)
+	Readlink(path string) (string, error)
+	// FileID identifies the file described by info; the zero FileID means unknown
+	FileID(info fs.FileInfo) FileID
+}
+
+// FileID identifies a file by device and inode, which stay the same
+// whichever path the file is reached through
+type FileID struct {
+	Dev uint64
+	Ino uint64
+}
+
+// errTooLarge is returned by readWithin for files over its size limit
+var errTooLarge = errors.New("file too large")
+
+// readWithin reads a file unless it is larger than limit bytes. The size
+// is checked with Stat first, so oversized files are never loaded. A
+// non-positive limit reads every file.
+func readWithin(fsys FileSystem, path string, limit int) ([]byte, error) {
+	if limit > 0 {
+		info, err := fsys.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if info.Size() > int64(limit) {
+			return nil, errTooLarge
+		}
+	}
+	return fsys.ReadFile(path)
+}
@@ -1,7 +1,11 @@
 package domain
 
 // Flatten returns a depth-first slice of all visible nodes
+// For the root of an indexed tree the result is cached and must not be modified
 func Flatten(root *Node, state ViewState) []*Node {
+	if root.index != nil {
+		return root.index.flattenVisible(state)
+	}
 	var result []*Node
 	flatten(root, state, &result)
 	return result
@@ -11,8 +15,38 @@ func flatten(node *Node, state ViewState, result *[]*Node) {
 	*result = append(*result, node)
 	
 	if node.IsDir && state.IsOpen(node.Path) {
-		for _, child := range node.Children {
+		for _, child := range VisibleChildren(node, state) {
 			flatten(child, state, result)
 		}
 	}
+}
+
+// VisibleChildren returns the children of node that the view state shows
+func VisibleChildren(node *Node, state ViewState) []*Node {
+	if !state.HideHidden {
+		return node.Children
+	}
+	visible := make([]*Node, 0, len(node.Children))
+	for _, child := range node.Children {
+		if state.IsVisible(child) {
+			visible = append(visible, child)
+		}
+	}
+	return visible
+}
+
+// NearestVisible returns path if its node and all its ancestors are visible,
+// otherwise the path of the closest ancestor that is
+func NearestVisible(root *Node, state ViewState, path string) string {
+	node := FindNodeByPath(root, path)
+	if node == nil {
+		return root.Path
+	}
+	result := node
+	for cur := node; cur != nil; cur = cur.Parent {
+		if !state.IsVisible(cur) {
+			result = cur.Parent
+		}
+	}
+	return result.Path
 }
\ No newline at end of file
@@ -0,0 +1,90 @@
+package domain
+
+import "fmt"
+
+// InclusionMode says how much of a selected file goes into the output
+type InclusionMode string
+
+const (
+	// IncludeFull writes the whole file
+	IncludeFull InclusionMode = "full"
+	// IncludeOutline writes only the file's declarations and signatures
+	IncludeOutline InclusionMode = "outline"
+	// IncludeHead writes the first lines of the file
+	IncludeHead InclusionMode = "head"
+	// IncludeDiff writes the file's uncommitted changes
+	IncludeDiff InclusionMode = "diff"
+	// IncludePath lists the file in the structure without its content
+	IncludePath InclusionMode = "path"
+)
+
+// InclusionModes lists the inclusion modes in the order they are cycled
+var InclusionModes = []InclusionMode{IncludeFull, IncludeOutline, IncludeHead, IncludeDiff, IncludePath}
+
+// ParseInclusionMode validates an inclusion mode name; the empty string means full
+func ParseInclusionMode(name string) (InclusionMode, error) {
+	switch m := InclusionMode(name); m {
+	case "":
+		return IncludeFull, nil
+	case IncludeFull, IncludeOutline, IncludeHead, IncludeDiff, IncludePath:
+		return m, nil
+	}
+	return "", fmt.Errorf("unknown inclusion mode %q (want full, outline, head, diff or path)", name)
+}
+
+// Next returns the mode after m in InclusionModes, wrapping around
+func (m InclusionMode) Next() InclusionMode {
+	for i, mode := range InclusionModes {
+		if mode == m {
+			return InclusionModes[(i+1)%len(InclusionModes)]
+		}
+	}
+	return IncludeFull
+}
+
+// CycleMode selects the node at the cursor and moves it to the next
+// inclusion mode. The files under a directory all move to the mode after the
+// one they share, or after full when theirs differ. It returns the new state
+// and the mode set; directories and unfollowed links are left unchanged.
+func CycleMode(root *Node, state ViewState) (ViewState, InclusionMode) {
+	cursor := FindNodeByPath(root, state.CursorPath)
+	if cursor == nil || cursor.Kind == KindSymlink {
+		return state, ""
+	}
+	if !cursor.IsDir {
+		next := state.Mode(cursor.Path).Next()
+		return state.SetSelected(cursor.Path, true).SetMode(cursor.Path, next), next
+	}
+
+	newState := state.SetSelected(cursor.Path, true)
+	newState = setSelectionRecursive(cursor, newState, true)
+	var files []*Node
+	var stack []*Node
+	stack = append(stack, VisibleChildren(cursor, newState)...)
+	for len(stack) > 0 {
+		cur := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		switch {
+		case cur.IsDir:
+			stack = append(stack, VisibleChildren(cur, newState)...)
+		case cur.Kind != KindSymlink:
+			files = append(files, cur)
+		}
+	}
+	if len(files) == 0 {
+		return state, ""
+	}
+
+	shared := state.Mode(files[0].Path)
+	for _, f := range files[1:] {
+		if state.Mode(f.Path) != shared {
+			shared = IncludeFull
+			break
+		}
+	}
+	next := shared.Next()
+	for _, f := range files {
+		newState = newState.SetMode(f.Path, next)
+	}
+	return newState, next
+}
@@ -0,0 +1,39 @@
+package domain_test
+
+import (
+	"testing"
+
+	"github.com/eliooooooot/picky/internal/domain"
+	"github.com/eliooooooot/picky/internal/fs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCycleMode(t *testing.T) {
+	memfs := fs.NewMemFileSystem()
+	memfs.AddFile("/root/pkg/a.go", "package pkg")
+	memfs.AddFile("/root/pkg/b.go", "package pkg")
+	memfs.AddFile("/root/main.go", "package main")
+
+	tree, err := domain.BuildTree(memfs, "/root")
+	require.NoError(t, err)
+
+	state := domain.NewViewState("/root").SetCursor("/root/main.go")
+	state, mode := domain.CycleMode(tree.Root, state)
+	assert.Equal(t, domain.IncludeOutline, mode)
+	assert.True(t, state.IsSelected("/root/main.go"))
+	assert.Equal(t, domain.IncludeOutline, state.Mode("/root/main.go"))
+
+	// A directory's files move together, from full when their modes differ
+	state = state.SetSelected("/root/pkg/a.go", true).SetMode("/root/pkg/a.go", domain.IncludeHead)
+	state = state.SetCursor("/root/pkg")
+	state, mode = domain.CycleMode(tree.Root, state)
+	assert.Equal(t, domain.IncludeOutline, mode)
+	assert.True(t, state.IsSelected("/root/pkg/b.go"))
+	assert.Equal(t, domain.IncludeOutline, state.Mode("/root/pkg/a.go"))
+	assert.Equal(t, domain.IncludeOutline, state.Mode("/root/pkg/b.go"))
+
+	state, mode = domain.CycleMode(tree.Root, state)
+	assert.Equal(t, domain.IncludeHead, mode)
+	assert.Equal(t, domain.IncludeHead, state.Mode("/root/pkg/b.go"))
+}
@@ -0,0 +1,227 @@
+package domain
+
+import (
+	"reflect"
+	"time"
+)
+
+// treeIndex caches lookups over a tree so navigation and rendering don't
+// walk it on every keypress. The Tree and its root node share one index,
+// which lets functions given only the root use it too. Anything that
+// changes the tree's shape must call invalidate.
+type treeIndex struct {
+	root *Node
+	gen  int
+
+	byPath map[string]*Node
+	all    []*Node
+
+	visibleKey stateKey
+	visible    []*Node
+
+	stats *Aggregates
+}
+
+// stateKey identifies the tree and view state a cache was built for. Every
+// ViewState setter gives its result a new version, so a key matches only
+// the state it was made from and the copies SetCursor makes of it.
+type stateKey struct {
+	gen     int
+	version uint64
+}
+
+// keyFor returns the key of state in a tree at generation gen, and false
+// for states built by hand, whose contents have no version to key on
+func keyFor(gen int, state ViewState) (stateKey, bool) {
+	return stateKey{gen: gen, version: state.version}, state.version != 0
+}
+
+// sameMap reports whether a and b are the same map. A cache that keeps a
+// reference to the map it was built from can compare it this way, since
+// the address cannot be reused while the map is still referenced.
+func sameMap(a, b map[string]int) bool {
+	return reflect.ValueOf(a).Pointer() == reflect.ValueOf(b).Pointer()
+}
+
+// DirStats sums the visible files at or under a node
+type DirStats struct {
+	// Selected and Total count files; an unloaded directory counts as one
+	// file that is selected when the directory itself is
+	Selected int
+	Total    int
+
+	// Tokens sums the files that are not skipped as binary or generated
+	Tokens int
+
+	// Complete is false while unloaded directories are below the node
+	Complete bool
+
+	// Size sums the sizes of the files and ModTime is the newest of their
+	// modification times
+	Size    int64
+	ModTime time.Time
+}
+
+// Aggregates holds the DirStats of every node in a tree for one view state
+type Aggregates struct {
+	key    stateKey
+	state  ViewState
+	tokens map[string]int
+	stats  map[*Node]DirStats
+}
+
+// Of returns the stats of node. Nodes the pass did not reach, such as
+// hidden ones, are counted on first use.
+func (a *Aggregates) Of(node *Node) DirStats {
+	if s, ok := a.stats[node]; ok {
+		return s
+	}
+	return aggregate(node, a.state, a.tokens, a.stats)
+}
+
+// indexOf returns the index of the tree node belongs to, or nil when the
+// tree was not made by NewTree
+func indexOf(node *Node) *treeIndex {
+	for node.Parent != nil {
+		node = node.Parent
+	}
+	return node.index
+}
+
+// invalidate drops every cache of the tree node belongs to, after its
+// children were added, removed or reordered
+func invalidate(node *Node) {
+	ix := indexOf(node)
+	if ix == nil {
+		return
+	}
+	ix.gen++
+	ix.byPath = nil
+	ix.all = nil
+	ix.visible = nil
+	ix.stats = nil
+}
+
+// lookup returns the node at path. A miss or a node no longer under the
+// root falls back to a search, for trees changed without invalidate.
+func (ix *treeIndex) lookup(path string) *Node {
+	if ix.byPath == nil {
+		ix.byPath = make(map[string]*Node)
+		for _, node := range ix.flattenAll() {
+			ix.byPath[node.Path] = node
+		}
+	}
+	if node := ix.byPath[path]; node != nil && indexOf(node) == ix {
+		return node
+	}
+	return findNodeByPath(ix.root, path)
+}
+
+func (ix *treeIndex) flattenAll() []*Node {
+	if ix.all == nil {
+		flattenNode(ix.root, &ix.all)
+	}
+	return ix.all[:len(ix.all):len(ix.all)]
+}
+
+func (ix *treeIndex) flattenVisible(state ViewState) []*Node {
+	key, ok := keyFor(ix.gen, state)
+	if !ok {
+		var visible []*Node
+		flatten(ix.root, state, &visible)
+		return visible
+	}
+	if ix.visible == nil || ix.visibleKey != key {
+		ix.visible = nil
+		flatten(ix.root, state, &ix.visible)
+		ix.visibleKey = key
+	}
+	return ix.visible[:len(ix.visible):len(ix.visible)]
+}
+
+// Reindex drops the tree's cached lookups and aggregates. Callers that
+// change Children directly must call it; the domain functions that change
+// the tree do so themselves.
+func (t *Tree) Reindex() {
+	invalidate(t.Root)
+}
+
+// Generation counts the changes to the tree's shape, for caches kept
+// outside the domain. It also moves on at each Reindex.
+func (t *Tree) Generation() int {
+	if t.Root.index == nil {
+		return 0
+	}
+	return t.Root.index.gen
+}
+
+// Aggregates returns the stats of every node under the view state in one
+// bottom-up pass. The result is reused until the state, the token map or
+// the tree change, so rendering every row costs one walk. Callers that add
+// counts to the token map in place must call Reindex.
+func (t *Tree) Aggregates(state ViewState, tokens map[string]int) *Aggregates {
+	ix := t.Root.index
+	var key stateKey
+	cache := false
+	if ix != nil {
+		key, cache = keyFor(ix.gen, state)
+		if cache && ix.stats != nil && ix.stats.key == key && sameMap(ix.stats.tokens, tokens) {
+			return ix.stats
+		}
+	}
+
+	agg := &Aggregates{key: key, state: state, tokens: tokens, stats: make(map[*Node]DirStats)}
+	aggregate(t.Root, state, tokens, agg.stats)
+	if cache {
+		ix.stats = agg
+	}
+	return agg
+}
+
+func aggregate(node *Node, state ViewState, tokens map[string]int, stats map[*Node]DirStats) DirStats {
+	var s DirStats
+	switch {
+	case !node.IsDir:
+		s = DirStats{Total: 1, Complete: true, Size: node.Size, ModTime: node.ModTime}
+		if state.IsSelected(node.Path) {
+			s.Selected = 1
+		}
+		if !IsSkipped(node, state) {
+			s.Tokens = tokens[node.Path]
+		}
+	case !node.IsLoaded():
+		s = DirStats{Total: 1}
+		if state.IsSelected(node.Path) {
+			s.Selected = 1
+		}
+	default:
+		s.Complete = true
+		for _, child := range VisibleChildren(node, state) {
+			c := aggregate(child, state, tokens, stats)
+			s.Selected += c.Selected
+			s.Total += c.Total
+			s.Tokens += c.Tokens
+			s.Complete = s.Complete && c.Complete
+			s.Size += c.Size
+			if c.ModTime.After(s.ModTime) {
+				s.ModTime = c.ModTime
+			}
+		}
+	}
+	stats[node] = s
+	return s
+}
+
+// cachedSelectionCounts returns a directory's selected and total files from
+// the last Aggregates when it was computed for the same state
+func cachedSelectionCounts(node *Node, state ViewState) (selected, total int, ok bool) {
+	ix := indexOf(node)
+	if ix == nil || ix.stats == nil {
+		return 0, 0, false
+	}
+	if key, cache := keyFor(ix.gen, state); !cache || ix.stats.key != key {
+		return 0, 0, false
+	}
+	s, ok := ix.stats.stats[node]
+	return s.Selected, s.Total, ok
+}
@@ -0,0 +1,135 @@
+package domain_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/eliooooooot/picky/internal/domain"
+	pickyfs "github.com/eliooooooot/picky/internal/fs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newIndexedTree(t *testing.T) *domain.Tree {
+	fs := pickyfs.NewMemFileSystem()
+	require.NoError(t, fs.WriteFile("/root/src/a.go", []byte("a"), 0644))
+	require.NoError(t, fs.WriteFile("/root/src/b.go", []byte("b"), 0644))
+	require.NoError(t, fs.WriteFile("/root/docs/readme.md", []byte("r"), 0644))
+	require.NoError(t, fs.WriteFile("/root/main.go", []byte("m"), 0644))
+	tree, err := domain.BuildTree(fs, "/root")
+	require.NoError(t, err)
+	return tree
+}
+
+func TestIndexFollowsExcludeAndSort(t *testing.T) {
+	tree := newIndexedTree(t)
+	src := domain.FindNodeByPath(tree.Root, "/root/src")
+	require.NotNil(t, src)
+	assert.Len(t, tree.Flatten(), 7)
+
+	tree.ExcludeNode("/root/src")
+	assert.Nil(t, domain.FindNodeByPath(tree.Root, "/root/src"))
+	assert.Nil(t, domain.FindNodeByPath(tree.Root, "/root/src/a.go"))
+	assert.Len(t, tree.Flatten(), 4)
+
+	state := domain.NewViewState("/root").SetOpen("/root", true)
+	assert.Equal(t, "docs", domain.Flatten(tree.Root, state)[1].Name)
+	domain.SortTree(tree.Root, domain.SortByName, domain.SortDescending, nil)
+	assert.Equal(t, "docs", domain.Flatten(tree.Root, state)[1].Name, "directories stay first")
+	assert.Equal(t, "main.go", domain.Flatten(tree.Root, state)[2].Name)
+}
+
+func TestIndexFollowsLazyLoads(t *testing.T) {
+	tree, _ := newLazyTree(t)
+	assert.Nil(t, domain.FindNodeByPath(tree.Root, "/root/a/one.go"))
+
+	state := domain.NavigateIn(tree.Root, domain.NewViewState("/root/a"))
+	node := domain.FindNodeByPath(tree.Root, "/root/a/one.go")
+	require.NotNil(t, node)
+	assert.Contains(t, domain.Flatten(tree.Root, state.SetOpen("/root", true)), node)
+}
+
+func TestReindexAfterDirectChange(t *testing.T) {
+	tree := newIndexedTree(t)
+	assert.Len(t, tree.Flatten(), 7)
+
+	extra := &domain.Node{Path: "/root/extra.go", Name: "extra.go", Parent: tree.Root}
+	tree.Root.Children = append(tree.Root.Children, extra)
+	assert.Same(t, extra, domain.FindNodeByPath(tree.Root, "/root/extra.go"), "misses fall back to a search")
+
+	tree.Reindex()
+	assert.Len(t, tree.Flatten(), 8)
+}
+
+func TestFlattenResultCanBeAppendedTo(t *testing.T) {
+	tree := newIndexedTree(t)
+	state := domain.NewViewState("/root").SetOpen("/root", true)
+
+	rows := domain.Flatten(tree.Root, state)
+	_ = append(rows, &domain.Node{Name: "bogus"})
+	_ = append(domain.NodesBetween(tree.Root, state, "/root", "/root/docs"), &domain.Node{Name: "bogus"})
+
+	for _, node := range domain.Flatten(tree.Root, state) {
+		assert.NotEqual(t, "bogus", node.Name)
+	}
+}
+
+func TestAggregates(t *testing.T) {
+	tree := newIndexedTree(t)
+	tokens := map[string]int{"/root/src/a.go": 10, "/root/src/b.go": 20, "/root/docs/readme.md": 5, "/root/main.go": 1}
+	state := domain.NewViewState("/root/src")
+	state = domain.ToggleSelection(tree.Root, state)
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, path := range []string{"/root/src/a.go", "/root/src/b.go", "/root/main.go", "/root/docs/readme.md"} {
+		domain.FindNodeByPath(tree.Root, path).ModTime = base.Add(time.Duration(i) * time.Hour)
+	}
+
+	agg := tree.Aggregates(state, tokens)
+	src := domain.FindNodeByPath(tree.Root, "/root/src")
+	assert.Equal(t, domain.DirStats{Selected: 2, Total: 2, Tokens: 30, Complete: true, Size: 2, ModTime: base.Add(time.Hour)}, agg.Of(src))
+	assert.Equal(t, domain.DirStats{Selected: 2, Total: 4, Tokens: 36, Complete: true, Size: 4, ModTime: base.Add(3 * time.Hour)}, agg.Of(tree.Root))
+	assert.True(t, domain.HasFullSelection(src, state))
+	assert.True(t, domain.HasPartialSelection(tree.Root, state))
+
+	// Moving the cursor keeps the aggregates; changing selections recomputes them
+	moved := domain.NavigateDown(tree.Root, state)
+	assert.Same(t, agg, tree.Aggregates(moved, tokens))
+	cleared := domain.ToggleSelection(tree.Root, state)
+	assert.NotSame(t, agg, tree.Aggregates(cleared, tokens))
+	assert.Equal(t, 0, tree.Aggregates(cleared, tokens).Of(tree.Root).Selected)
+	assert.False(t, domain.HasFullSelection(src, cleared))
+}
+
+func TestAggregatesOfLazyTree(t *testing.T) {
+	tree, _ := newLazyTree(t)
+	tokens := map[string]int{"/root/main.go": 4}
+	state := domain.NewViewState("/root")
+
+	root := tree.Aggregates(state, tokens).Of(tree.Root)
+	main := domain.FindNodeByPath(tree.Root, "/root/main.go")
+	assert.Equal(t, domain.DirStats{Selected: 0, Total: 3, Tokens: 4, Complete: false, Size: 4, ModTime: main.ModTime}, root,
+		"unloaded directories add no size")
+}
+
+func TestAggregatesFollowStateVersions(t *testing.T) {
+	tree := newIndexedTree(t)
+	tokens := map[string]int{"/root/main.go": 5}
+	state := domain.NewViewState("/root").SetSelected("/root/main.go", true)
+
+	agg := tree.Aggregates(state, tokens)
+	assert.Same(t, agg, tree.Aggregates(state.SetCursor("/root/main.go"), tokens), "moving the cursor keeps the cache")
+
+	// Sibling states made from the same parent never share a cache entry
+	src := domain.FindNodeByPath(tree.Root, "/root/src")
+	a := state.SetSelected("/root/src/a.go", true)
+	b := state.SetSelected("/root/docs/readme.md", true)
+	assert.Equal(t, 1, tree.Aggregates(a, tokens).Of(src).Selected)
+	assert.Equal(t, 0, tree.Aggregates(b, tokens).Of(src).Selected)
+	assert.Equal(t, 2, tree.Aggregates(b, tokens).Of(tree.Root).Selected)
+
+	// A state built by hand is never cached, so changing its maps shows
+	hand := domain.ViewState{Selected: map[string]bool{"/root/main.go": true}}
+	assert.Equal(t, 1, tree.Aggregates(hand, tokens).Of(tree.Root).Selected)
+	hand.Selected["/root/src/a.go"] = true
+	assert.Equal(t, 2, tree.Aggregates(hand, tokens).Of(tree.Root).Selected)
+}
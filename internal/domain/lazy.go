@@ -0,0 +1,84 @@
+package domain
+
+// BuildLazyTree creates a tree that reads only the root's entries up front
+func BuildLazyTree(fs FileSystem, rootPath string) (*Tree, error) {
+	return BuildLazyTreeWithFilter(fs, rootPath, nil)
+}
+
+// BuildLazyTreeWithFilter creates a tree that reads a directory's children
+// only when it is first expanded or selected. Huge repositories open at once.
+func BuildLazyTreeWithFilter(fs FileSystem, rootPath string, keep PathFilter) (*Tree, error) {
+	return BuildTreeWithOptions(fs, rootPath, TreeOptions{Keep: keep, FollowSymlinks: true, Lazy: true})
+}
+
+// IsLoaded reports whether a node's children have been read. Files and the
+// directories of an eagerly built tree are always loaded.
+func (n *Node) IsLoaded() bool {
+	return n.loader == nil
+}
+
+// ReadChildren reads the children of an unloaded directory without attaching
+// them, so the read can happen off the UI goroutine. It returns nil once the
+// node is loaded.
+func (n *Node) ReadChildren() []*Node {
+	if read := n.ChildReader(); read != nil {
+		return read()
+	}
+	return nil
+}
+
+// ChildReader captures what reading an unloaded directory's children needs
+// and returns the read, or nil once the node is loaded. The returned
+// function touches no fields of the tree, so it can run on another
+// goroutine while the directory is loaded or the tree changes; Attach
+// ignores its result once the directory is loaded.
+func (n *Node) ChildReader() func() []*Node {
+	b := n.loader
+	if b == nil {
+		return nil
+	}
+	path, ancestors := n.Path, ancestorIDs(n)
+	return func() []*Node {
+		return b.children(n, path, ancestors)
+	}
+}
+
+// Attach stores children from ReadChildren and marks the directory loaded
+func (n *Node) Attach(children []*Node) {
+	if n.loader == nil {
+		return
+	}
+	n.Children = children
+	n.loader.loaded = append(n.loader.loaded, n)
+	n.loader = nil
+	invalidate(n)
+}
+
+// Load reads the children of an unloaded directory in place
+func (n *Node) Load() {
+	if n.loader != nil {
+		n.Attach(n.ReadChildren())
+	}
+}
+
+// LoadAll loads every directory under node, for operations that need the
+// whole subtree such as selecting it
+func LoadAll(node *Node) {
+	node.Load()
+	for _, child := range node.Children {
+		if child.IsDir {
+			LoadAll(child)
+		}
+	}
+}
+
+// TakeLoaded returns the directories loaded since the last call, so callers
+// can count and sort their new children
+func (t *Tree) TakeLoaded() []*Node {
+	if t.loader == nil {
+		return nil
+	}
+	loaded := t.loader.loaded
+	t.loader.loaded = nil
+	return loaded
+}
@@ -0,0 +1,129 @@
+package domain_test
+
+import (
+	"io/fs"
+	"testing"
+
+	"github.com/eliooooooot/picky/internal/domain"
+	pickyfs "github.com/eliooooooot/picky/internal/fs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// readDirCounter records which directories were listed
+type readDirCounter struct {
+	*pickyfs.MemFileSystem
+	read []string
+}
+
+func (c *readDirCounter) ReadDir(path string) ([]fs.DirEntry, error) {
+	c.read = append(c.read, path)
+	return c.MemFileSystem.ReadDir(path)
+}
+
+func newLazyTree(t *testing.T) (*domain.Tree, *readDirCounter) {
+	mem := pickyfs.NewMemFileSystem()
+	require.NoError(t, mem.WriteFile("/root/a/one.go", []byte("one"), 0644))
+	require.NoError(t, mem.WriteFile("/root/a/deep/two.go", []byte("two"), 0644))
+	require.NoError(t, mem.WriteFile("/root/b/three.go", []byte("three"), 0644))
+	require.NoError(t, mem.WriteFile("/root/main.go", []byte("main"), 0644))
+	fsys := &readDirCounter{MemFileSystem: mem}
+
+	tree, err := domain.BuildLazyTreeWithFilter(fsys, "/root", nil)
+	require.NoError(t, err)
+	return tree, fsys
+}
+
+func TestBuildLazyTreeReadsOnlyTheRoot(t *testing.T) {
+	tree, fsys := newLazyTree(t)
+
+	assert.Equal(t, []string{"/root"}, fsys.read)
+	require.Len(t, tree.Root.Children, 3)
+	a := tree.Root.Children[0]
+	assert.Equal(t, "a", a.Name)
+	assert.False(t, a.IsLoaded())
+	assert.Empty(t, a.Children)
+	assert.True(t, tree.Root.Children[2].IsLoaded(), "files are always loaded")
+	assert.Empty(t, tree.TakeLoaded(), "the root is not reported as loaded")
+}
+
+func TestNavigateInLoadsDirectory(t *testing.T) {
+	tree, fsys := newLazyTree(t)
+	state := domain.NewViewState("/root/a")
+
+	state = domain.NavigateIn(tree.Root, state)
+
+	a := tree.Root.Children[0]
+	assert.True(t, state.IsOpen("/root/a"))
+	assert.True(t, a.IsLoaded())
+	assert.Equal(t, []string{"/root", "/root/a"}, fsys.read)
+	require.Len(t, a.Children, 2)
+	assert.False(t, a.Children[0].IsLoaded(), "only one level is read")
+	assert.Equal(t, []*domain.Node{a}, tree.TakeLoaded())
+	assert.Empty(t, tree.TakeLoaded())
+}
+
+func TestSelectionLoadsWholeSubtree(t *testing.T) {
+	tree, _ := newLazyTree(t)
+	state := domain.NewViewState("/root/a")
+
+	state = domain.ToggleSelection(tree.Root, state)
+
+	assert.True(t, state.IsSelected("/root/a/one.go"))
+	assert.True(t, state.IsSelected("/root/a/deep/two.go"))
+	assert.Equal(t, []string{"/root/a/deep/two.go", "/root/a/one.go"}, domain.GetSelectedPaths(tree.Root, state))
+	assert.Len(t, tree.TakeLoaded(), 2)
+	assert.False(t, tree.Root.Children[1].IsLoaded(), "siblings stay unread")
+}
+
+func TestFullSelectionCountsUnloadedDirectories(t *testing.T) {
+	tree, _ := newLazyTree(t)
+	state := domain.NewViewState("/root/main.go")
+	state = domain.ToggleSelection(tree.Root, state)
+
+	// Unloaded a and b hold files that are not selected
+	assert.True(t, domain.HasPartialSelection(tree.Root, state))
+	assert.False(t, domain.HasFullSelection(tree.Root, state))
+
+	// Selecting everything loads it all
+	state = domain.SelectAll(tree.Root, state)
+	assert.True(t, domain.HasFullSelection(tree.Root, state))
+	for _, node := range tree.Flatten() {
+		assert.True(t, node.IsLoaded(), node.Path)
+	}
+}
+
+func TestLazyTreeKeepsFilter(t *testing.T) {
+	mem := pickyfs.NewMemFileSystem()
+	require.NoError(t, mem.WriteFile("/root/a/keep.go", []byte("x"), 0644))
+	require.NoError(t, mem.WriteFile("/root/a/skip.log", []byte("x"), 0644))
+	keep := func(path string, isDir bool) bool {
+		return isDir || path != "/root/a/skip.log"
+	}
+
+	tree, err := domain.BuildLazyTreeWithFilter(mem, "/root", keep)
+	require.NoError(t, err)
+	a := tree.Root.Children[0]
+	a.Load()
+
+	require.Len(t, a.Children, 1)
+	assert.Equal(t, "keep.go", a.Children[0].Name)
+	assert.Same(t, a, a.Children[0].Parent)
+}
+
+func TestChildReaderOutlivesLoad(t *testing.T) {
+	tree, _ := newLazyTree(t)
+	a := tree.Root.Children[0]
+
+	read := a.ChildReader()
+	require.NotNil(t, read)
+	a.Load()
+	loaded := a.Children
+	assert.Nil(t, a.ChildReader(), "a loaded directory has nothing to read")
+
+	children := read()
+	require.Len(t, children, 2)
+	assert.Same(t, a, children[0].Parent)
+	a.Attach(children)
+	assert.Equal(t, loaded, a.Children, "a late read does not replace the loaded children")
+}
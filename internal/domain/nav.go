@@ -40,11 +40,12 @@ func NavigateIn(root *Node, state ViewState) ViewState {
 	}
 	
 	if !state.IsOpen(cursor.Path) {
-		// Expand the directory
+		// Expand the directory, reading it first in a lazy tree
+		cursor.Load()
 		return state.SetOpen(cursor.Path, true)
-	} else if len(cursor.Children) > 0 {
+	} else if children := VisibleChildren(cursor, state); len(children) > 0 {
 		// Move to first child
-		return state.SetCursor(cursor.Children[0].Path)
+		return state.SetCursor(children[0].Path)
 	}
 	
 	return state
@@ -68,14 +69,23 @@ func NavigateOut(root *Node, state ViewState) ViewState {
 	return state
 }
 
-// FindNodeByPath recursively searches for a node by its path
+// FindNodeByPath finds a node by its path, through the tree's index when
+// root is the root of a tree made by NewTree
 func FindNodeByPath(root *Node, path string) *Node {
+	if root.index != nil {
+		return root.index.lookup(path)
+	}
+	return findNodeByPath(root, path)
+}
+
+// findNodeByPath recursively searches for a node by its path
+func findNodeByPath(root *Node, path string) *Node {
 	if root.Path == path {
 		return root
 	}
 	
 	for _, child := range root.Children {
-		if found := FindNodeByPath(child, path); found != nil {
+		if found := findNodeByPath(child, path); found != nil {
 			return found
 		}
 	}
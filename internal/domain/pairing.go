@@ -0,0 +1,83 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+)
+
+// PairRule links source files to their tests by name. Each pattern has one
+// "*" standing for the shared stem, e.g. "*.go" and "*_test.go".
+// Paired files must live in the same directory.
+type PairRule struct {
+	Source string
+	Test   string
+}
+
+// DefaultPairRules returns the built-in rules for common ecosystems
+func DefaultPairRules() []PairRule {
+	return []PairRule{
+		{Source: "*.go", Test: "*_test.go"},
+		{Source: "*.ts", Test: "*.spec.ts"},
+		{Source: "*.ts", Test: "*.test.ts"},
+		{Source: "*.tsx", Test: "*.test.tsx"},
+		{Source: "*.js", Test: "*.spec.js"},
+		{Source: "*.js", Test: "*.test.js"},
+		{Source: "*.py", Test: "test_*.py"},
+		{Source: "*.py", Test: "*_test.py"},
+	}
+}
+
+// String formats the rule as "source:test", the form ParsePairRule reads
+func (r PairRule) String() string {
+	return r.Source + ":" + r.Test
+}
+
+// ParsePairRule reads a rule written as "source:test", e.g. "*.ts:*.spec.ts"
+func ParsePairRule(s string) (PairRule, error) {
+	source, test, ok := strings.Cut(s, ":")
+	r := PairRule{Source: strings.TrimSpace(source), Test: strings.TrimSpace(test)}
+	if !ok || strings.Count(r.Source, "*") != 1 || strings.Count(r.Test, "*") != 1 {
+		return PairRule{}, fmt.Errorf("invalid pair rule %q (want source:test with one * in each, e.g. *.go:*_test.go)", s)
+	}
+	return r, nil
+}
+
+// PairedFiles returns the siblings of file that pair with it under any rule:
+// its tests when it is a source file, its source when it is a test
+func PairedFiles(file *Node, rules []PairRule) []*Node {
+	if file.IsDir || file.Parent == nil {
+		return nil
+	}
+
+	want := make(map[string]bool)
+	for _, r := range rules {
+		if stem, ok := matchStem(r.Test, file.Name); ok {
+			want[strings.Replace(r.Source, "*", stem, 1)] = true
+		}
+		if stem, ok := matchStem(r.Source, file.Name); ok {
+			want[strings.Replace(r.Test, "*", stem, 1)] = true
+		}
+	}
+	delete(want, file.Name)
+
+	var paired []*Node
+	for _, sibling := range file.Parent.Children {
+		if !sibling.IsDir && want[sibling.Name] {
+			paired = append(paired, sibling)
+		}
+	}
+	return paired
+}
+
+// matchStem matches name against a pattern with one "*" and returns what the
+// "*" stood for. The stem must not be empty.
+func matchStem(pattern, name string) (string, bool) {
+	prefix, suffix, ok := strings.Cut(pattern, "*")
+	if !ok || len(name) <= len(prefix)+len(suffix) {
+		return "", false
+	}
+	if !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, suffix) {
+		return "", false
+	}
+	return name[len(prefix) : len(name)-len(suffix)], true
+}
@@ -0,0 +1,61 @@
+package domain_test
+
+import (
+	"testing"
+
+	"github.com/eliooooooot/picky/internal/domain"
+)
+
+func pairTree() *domain.Node {
+	root := &domain.Node{Path: "/p", Name: "p", IsDir: true}
+	for _, name := range []string{"foo.go", "foo_test.go", "bar.go", "api.ts", "api.spec.ts", "models.py", "test_models.py"} {
+		root.Children = append(root.Children, &domain.Node{Path: "/p/" + name, Name: name, Parent: root})
+	}
+	return root
+}
+
+func TestPairedFiles(t *testing.T) {
+	root := pairTree()
+	rules := domain.DefaultPairRules()
+
+	tests := []struct {
+		file string
+		want string
+	}{
+		{"foo.go", "foo_test.go"},
+		{"foo_test.go", "foo.go"},
+		{"api.ts", "api.spec.ts"},
+		{"api.spec.ts", "api.ts"},
+		{"models.py", "test_models.py"},
+		{"test_models.py", "models.py"},
+		{"bar.go", ""},
+	}
+	for _, tt := range tests {
+		file := domain.FindNodeByPath(root, "/p/"+tt.file)
+		got := names(domain.PairedFiles(file, rules))
+		if tt.want == "" {
+			if len(got) != 0 {
+				t.Errorf("PairedFiles(%s) = %v, want none", tt.file, got)
+			}
+			continue
+		}
+		if len(got) != 1 || got[0] != tt.want {
+			t.Errorf("PairedFiles(%s) = %v, want [%s]", tt.file, got, tt.want)
+		}
+	}
+}
+
+func TestParsePairRule(t *testing.T) {
+	r, err := domain.ParsePairRule("*.rb: *_spec.rb")
+	if err != nil || r.Source != "*.rb" || r.Test != "*_spec.rb" {
+		t.Errorf("ParsePairRule = %+v, %v", r, err)
+	}
+	if r.String() != "*.rb:*_spec.rb" {
+		t.Errorf("String() = %q", r.String())
+	}
+	for _, bad := range []string{"*.rb", "*.rb:spec.rb", "a*b*:*_x"} {
+		if _, err := domain.ParsePairRule(bad); err == nil {
+			t.Errorf("ParsePairRule(%q) should fail", bad)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package domain
+
+// NodesBetween returns the visible nodes from one path to another, inclusive,
+// in Flatten order. The paths may be given in either order.
+func NodesBetween(root *Node, state ViewState, from, to string) []*Node {
+	flat := Flatten(root, state)
+	start, end := -1, -1
+	for i, node := range flat {
+		if node.Path == from {
+			start = i
+		}
+		if node.Path == to {
+			end = i
+		}
+	}
+	if start < 0 || end < 0 {
+		return nil
+	}
+	if start > end {
+		start, end = end, start
+	}
+	// Cap the slice so appending to it can't write into Flatten's cache
+	return flat[start : end+1 : end+1]
+}
+
+// SetRangeSelected selects or deselects every node in nodes
+// Directories apply to all their descendants
+func SetRangeSelected(nodes []*Node, state ViewState, selected bool) ViewState {
+	var paths []string
+	for _, node := range nodes {
+		paths = append(paths, node.Path)
+		if node.IsDir {
+			paths = append(paths, subtreePaths(node, state)...)
+		}
+	}
+	return refreshDirSelection(nodes, state.setSelectedPaths(paths, selected))
+}
+
+// InvertRange flips the selection of every file in nodes, including the
+// files inside any directories among them. Each file flips exactly once.
+func InvertRange(nodes []*Node, state ViewState) ViewState {
+	seen := make(map[string]bool)
+	var files []*Node
+	var collect func(node *Node)
+	collect = func(node *Node) {
+		if !node.IsDir {
+			if !seen[node.Path] {
+				seen[node.Path] = true
+				files = append(files, node)
+			}
+			return
+		}
+		node.Load()
+		for _, child := range VisibleChildren(node, state) {
+			collect(child)
+		}
+	}
+	for _, node := range nodes {
+		collect(node)
+	}
+
+	var on, off []string
+	for _, file := range files {
+		if state.IsSelected(file.Path) {
+			off = append(off, file.Path)
+		} else {
+			on = append(on, file.Path)
+		}
+	}
+	return refreshDirSelection(nodes, state.setSelectedPaths(on, true).setSelectedPaths(off, false))
+}
+
+// TopmostNodes drops every node that has an ancestor also in nodes
+func TopmostNodes(nodes []*Node) []*Node {
+	in := make(map[*Node]bool, len(nodes))
+	for _, node := range nodes {
+		in[node] = true
+	}
+	var result []*Node
+	for _, node := range nodes {
+		covered := false
+		for p := node.Parent; p != nil; p = p.Parent {
+			if in[p] {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			result = append(result, node)
+		}
+	}
+	return result
+}
+
+// refreshDirSelection keeps each directory's own flag in line with its files,
+// so a later ToggleSelection on it goes the expected way. Each directory is
+// counted once, however many of the nodes it holds.
+func refreshDirSelection(nodes []*Node, state ViewState) ViewState {
+	seen := make(map[*Node]bool)
+	counts := make(map[*Node][2]int)
+	var full, partial []string
+	for _, node := range nodes {
+		// Directories above one already seen were seen with it
+		for dir := node; dir != nil && !seen[dir]; dir = dir.Parent {
+			seen[dir] = true
+			if !dir.IsDir {
+				continue
+			}
+			if selected, total := countSelectedOnce(dir, state, counts); selected > 0 && selected == total {
+				full = append(full, dir.Path)
+			} else {
+				partial = append(partial, dir.Path)
+			}
+		}
+	}
+	return state.setSelectedPaths(full, true).setSelectedPaths(partial, false)
+}
+
+// countSelectedOnce is countSelectedFiles keeping the counts of every
+// directory it passes in counts, so nested directories are counted once
+func countSelectedOnce(node *Node, state ViewState, counts map[*Node][2]int) (selected, total int) {
+	if !node.IsDir || !node.IsLoaded() {
+		return countSelectedFiles(node, state)
+	}
+	if c, ok := counts[node]; ok {
+		return c[0], c[1]
+	}
+	for _, child := range VisibleChildren(node, state) {
+		s, t := countSelectedOnce(child, state, counts)
+		selected += s
+		total += t
+	}
+	counts[node] = [2]int{selected, total}
+	return selected, total
+}
@@ -0,0 +1,113 @@
+package domain_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/eliooooooot/picky/internal/domain"
+)
+
+// rangeTree builds /r with a.go, closed dir lib/{x.go,y.go}, b.go, c.go
+func rangeTree() *domain.Node {
+	root := &domain.Node{Path: "/r", Name: "r", IsDir: true}
+	lib := &domain.Node{Path: "/r/lib", Name: "lib", IsDir: true, Parent: root}
+	lib.Children = []*domain.Node{
+		{Path: "/r/lib/x.go", Name: "x.go", Parent: lib},
+		{Path: "/r/lib/y.go", Name: "y.go", Parent: lib},
+	}
+	root.Children = []*domain.Node{
+		lib,
+		{Path: "/r/a.go", Name: "a.go", Parent: root},
+		{Path: "/r/b.go", Name: "b.go", Parent: root},
+		{Path: "/r/c.go", Name: "c.go", Parent: root},
+	}
+	return root
+}
+
+func TestNodesBetween(t *testing.T) {
+	root := rangeTree()
+	state := domain.NewViewState("/r").SetOpen("/r", true)
+
+	nodes := domain.NodesBetween(root, state, "/r/b.go", "/r/lib")
+	if got := names(nodes); len(got) != 3 || got[0] != "lib" || got[2] != "b.go" {
+		t.Fatalf("NodesBetween = %v, want [lib a.go b.go]", got)
+	}
+	if nodes := domain.NodesBetween(root, state, "/r/missing", "/r/a.go"); nodes != nil {
+		t.Error("expected nil for unknown path")
+	}
+}
+
+func TestSetRangeSelected(t *testing.T) {
+	root := rangeTree()
+	state := domain.NewViewState("/r").SetOpen("/r", true)
+
+	state = domain.SetRangeSelected(domain.NodesBetween(root, state, "/r/lib", "/r/a.go"), state, true)
+	for _, p := range []string{"/r/lib/x.go", "/r/lib/y.go", "/r/a.go"} {
+		if !state.IsSelected(p) {
+			t.Errorf("%s should be selected", p)
+		}
+	}
+	if state.IsSelected("/r/b.go") {
+		t.Error("b.go is outside the range")
+	}
+
+	state = domain.SetRangeSelected(domain.NodesBetween(root, state, "/r/lib", "/r/lib"), state, false)
+	if state.IsSelected("/r/lib/x.go") || !state.IsSelected("/r/a.go") {
+		t.Error("deselecting lib should only clear its files")
+	}
+}
+
+func TestSetRangeSelectedCopiesStateOnce(t *testing.T) {
+	// Two directories of 500 files each under one parent
+	root := &domain.Node{Path: "/r", Name: "r", IsDir: true}
+	for _, name := range []string{"a", "b"} {
+		dir := &domain.Node{Path: "/r/" + name, Name: name, IsDir: true, Parent: root}
+		for i := 0; i < 500; i++ {
+			file := fmt.Sprintf("f%d.go", i)
+			dir.Children = append(dir.Children, &domain.Node{Path: dir.Path + "/" + file, Name: file, Parent: dir})
+		}
+		root.Children = append(root.Children, dir)
+	}
+	state := domain.NewViewState("/r")
+
+	// Each copy of the state takes a new version, so the versions spent
+	// count the copies; one per file would make the selection quadratic
+	before := state.Version()
+	state = domain.SetRangeSelected(root.Children[:1], state, true)
+	if copies := state.Version() - before; copies > 3 {
+		t.Errorf("selecting 500 files copied the state %d times", copies)
+	}
+	if !state.IsSelected("/r/a") || !state.IsSelected("/r/a/f499.go") || state.IsSelected("/r") {
+		t.Error("a and its files should be selected, the partly selected root not")
+	}
+
+	state = domain.InvertRange(root.Children, state)
+	if !state.IsSelected("/r/b/f0.go") || state.IsSelected("/r/a/f0.go") || state.IsSelected("/r/a") || !state.IsSelected("/r/b") {
+		t.Error("inverting both directories should swap which one is selected")
+	}
+}
+
+func TestInvertRange(t *testing.T) {
+	root := rangeTree()
+	state := domain.NewViewState("/r").SetOpen("/r", true).SetOpen("/r/lib", true)
+	state = state.SetSelected("/r/lib/x.go", true).SetSelected("/r/a.go", true)
+
+	// The range covers lib and both its children; each file flips once
+	state = domain.InvertRange(domain.NodesBetween(root, state, "/r/lib", "/r/b.go"), state)
+	want := map[string]bool{"/r/lib/x.go": false, "/r/lib/y.go": true, "/r/a.go": false, "/r/b.go": true, "/r/c.go": false}
+	for p, sel := range want {
+		if state.IsSelected(p) != sel {
+			t.Errorf("%s selected = %v, want %v", p, !sel, sel)
+		}
+	}
+}
+
+func TestTopmostNodes(t *testing.T) {
+	root := rangeTree()
+	state := domain.NewViewState("/r").SetOpen("/r", true).SetOpen("/r/lib", true)
+
+	top := domain.TopmostNodes(domain.NodesBetween(root, state, "/r/lib", "/r/a.go"))
+	if got := names(top); len(got) != 2 || got[0] != "lib" || got[1] != "a.go" {
+		t.Errorf("TopmostNodes = %v, want [lib a.go]", got)
+	}
+}
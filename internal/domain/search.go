@@ -0,0 +1,266 @@
+package domain
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// LineRange is an inclusive, 1-based span of lines in a file
+type LineRange struct {
+	Start int
+	End   int
+}
+
+// SearchMatch is one line that matched a content search
+type SearchMatch struct {
+	Line int
+	Text string
+}
+
+// SearchResult holds the matching lines of one file
+type SearchResult struct {
+	Node    *Node
+	Matches []SearchMatch
+}
+
+// SearchContent scans the visible files under root for lines matching re,
+// in Flatten order. Binary and unreadable files are skipped, as are files
+// over maxSize bytes unless maxSize is zero or less. Excluded paths are not
+// searched since they are no longer part of the tree; unloaded directories
+// of a lazy tree are read as the search reaches them, and those leading to
+// a match are added to the tree.
+func SearchContent(root *Node, state ViewState, fsys FileSystem, re *regexp.Regexp, maxSize int) []SearchResult {
+	search := NewContentSearch(root, state, fsys, re, maxSize)
+	return search.Attach(root, search.Run())
+}
+
+// ContentSearch is a SearchContent split so the files are read off the
+// goroutine that owns the tree. NewContentSearch notes what to read, Run
+// reads it on any goroutine, and Attach takes the results back into the
+// tree on the owning goroutine.
+type ContentSearch struct {
+	state   ViewState
+	fsys    FileSystem
+	re      *regexp.Regexp
+	maxSize int
+	// steps lists the files and unloaded directories under the root in
+	// Flatten order; read is set on the directories
+	steps []searchStep
+	// children holds what Run read of the directories not loaded yet
+	children map[*Node][]*Node
+}
+
+// searchStep is a file to search or an unloaded directory to read
+type searchStep struct {
+	node *Node
+	read func() []*Node
+}
+
+// NewContentSearch notes the visible files under root and captures the
+// reads of its unloaded directories. It must run on the goroutine that
+// owns the tree.
+func NewContentSearch(root *Node, state ViewState, fsys FileSystem, re *regexp.Regexp, maxSize int) *ContentSearch {
+	s := &ContentSearch{state: state, fsys: fsys, re: re, maxSize: maxSize}
+	var walk func(node *Node)
+	walk = func(node *Node) {
+		if !node.IsDir {
+			s.steps = append(s.steps, searchStep{node: node})
+			return
+		}
+		if read := node.ChildReader(); read != nil {
+			s.steps = append(s.steps, searchStep{node: node, read: read})
+			return
+		}
+		for _, child := range VisibleChildren(node, state) {
+			walk(child)
+		}
+	}
+	walk(root)
+	return s
+}
+
+// Run searches the files and reads the unloaded directories. It touches
+// nothing the tree holds, so it can run on another goroutine; the nodes of
+// matches under unloaded directories are not in the tree until Attach.
+func (s *ContentSearch) Run() []SearchResult {
+	s.children = make(map[*Node][]*Node)
+	var results []SearchResult
+	var walk func(node *Node, read func() []*Node)
+	walk = func(node *Node, read func() []*Node) {
+		if !node.IsDir {
+			if matches := s.searchFile(node); len(matches) > 0 {
+				results = append(results, SearchResult{Node: node, Matches: matches})
+			}
+			return
+		}
+		// Only directories read here are walked through their own children
+		children := node.Children
+		if read != nil {
+			children = read()
+			s.children[node] = children
+		}
+		for _, child := range children {
+			if s.state.IsVisible(child) {
+				walk(child, child.ChildReader())
+			}
+		}
+	}
+	for _, step := range s.steps {
+		walk(step.node, step.read)
+	}
+	return results
+}
+
+// searchFile returns the lines of a file matching the search
+func (s *ContentSearch) searchFile(node *Node) []SearchMatch {
+	content, err := readWithin(s.fsys, node.Path, s.maxSize)
+	if err != nil || IsBinary(content) {
+		return nil
+	}
+	var matches []SearchMatch
+	for i, line := range strings.Split(string(content), "\n") {
+		if s.re.MatchString(line) {
+			matches = append(matches, SearchMatch{Line: i + 1, Text: strings.TrimRight(line, "\r")})
+		}
+	}
+	return matches
+}
+
+// Attach adds the directories Run read on the way to each match to the
+// tree, leaving the others unloaded, and returns the results with their
+// nodes from the tree. A directory loaded another way since the search
+// started keeps its children; its matches are looked up by path and
+// dropped if the tree no longer holds them.
+func (s *ContentSearch) Attach(root *Node, results []SearchResult) []SearchResult {
+	attached := make(map[*Node]bool)
+	var kept []SearchResult
+	for _, r := range results {
+		var chain []*Node
+		for dir := r.Node.Parent; dir != nil; dir = dir.Parent {
+			if _, ok := s.children[dir]; !ok {
+				break
+			}
+			chain = append(chain, dir)
+		}
+		for i := len(chain) - 1; i >= 0; i-- {
+			dir := chain[i]
+			if attached[dir] {
+				continue
+			}
+			if dir.IsLoaded() {
+				break
+			}
+			dir.Attach(s.children[dir])
+			attached[dir] = true
+		}
+		if node := FindNodeByPath(root, r.Node.Path); node != nil {
+			kept = append(kept, SearchResult{Node: node, Matches: r.Matches})
+		}
+	}
+	return kept
+}
+
+// GoDeclRanges returns the top-level declarations of a Go source file that
+// contain any of the given lines, including their doc comments. Lines outside
+// every declaration are returned as single-line ranges.
+func GoDeclRanges(src []byte, lines []int) ([]LineRange, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", src, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+
+	var decls []LineRange
+	for _, decl := range file.Decls {
+		start := decl.Pos()
+		var doc *ast.CommentGroup
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			doc = d.Doc
+		case *ast.GenDecl:
+			doc = d.Doc
+		}
+		if doc != nil {
+			start = doc.Pos()
+		}
+		decls = append(decls, LineRange{
+			Start: fset.Position(start).Line,
+			End:   fset.Position(decl.End()).Line,
+		})
+	}
+
+	var ranges []LineRange
+	for _, line := range lines {
+		r := LineRange{Start: line, End: line}
+		for _, d := range decls {
+			if line >= d.Start && line <= d.End {
+				r = d
+				break
+			}
+		}
+		ranges = append(ranges, r)
+	}
+	return MergeRanges(ranges), nil
+}
+
+// MergeRanges sorts ranges and joins any that overlap or touch
+func MergeRanges(ranges []LineRange) []LineRange {
+	if len(ranges) == 0 {
+		return nil
+	}
+	sorted := append([]LineRange(nil), ranges...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Start < sorted[j].Start })
+
+	merged := []LineRange{sorted[0]}
+	for _, r := range sorted[1:] {
+		last := &merged[len(merged)-1]
+		if r.Start <= last.End+1 {
+			if r.End > last.End {
+				last.End = r.End
+			}
+			continue
+		}
+		merged = append(merged, r)
+	}
+	return merged
+}
+
+// SelectMatches selects every file in results. With enclosingDecls, Go files
+// that are not already selected whole are limited to the declarations around
+// their matches; other files are always selected whole.
+func SelectMatches(results []SearchResult, state ViewState, fsys FileSystem, enclosingDecls bool) ViewState {
+	newState := state
+	nodes := make([]*Node, 0, len(results))
+	for _, result := range results {
+		path := result.Node.Path
+		nodes = append(nodes, result.Node)
+
+		wholeAlready := state.IsSelected(path) && len(state.LineRanges(path)) == 0
+		if !enclosingDecls || wholeAlready || !strings.HasSuffix(path, ".go") {
+			newState = newState.SetSelected(path, true).SetLineRanges(path, nil)
+			continue
+		}
+
+		src, err := fsys.ReadFile(path)
+		lines := make([]int, len(result.Matches))
+		for i, m := range result.Matches {
+			lines[i] = m.Line
+		}
+		var ranges []LineRange
+		if err == nil {
+			ranges, err = GoDeclRanges(src, lines)
+		}
+		if err != nil {
+			// Unparseable files fall back to the whole file
+			newState = newState.SetSelected(path, true).SetLineRanges(path, nil)
+			continue
+		}
+		ranges = MergeRanges(append(ranges, state.LineRanges(path)...))
+		newState = newState.SetSelected(path, true).SetLineRanges(path, ranges)
+	}
+	return refreshDirSelection(nodes, newState)
+}
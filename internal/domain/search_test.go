@@ -0,0 +1,151 @@
+package domain_test
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/eliooooooot/picky/internal/domain"
+	pickyfs "github.com/eliooooooot/picky/internal/fs"
+)
+
+const limiterSrc = `package rate
+
+import "time"
+
+// RateLimiter throttles calls
+type RateLimiter struct {
+	every time.Duration
+}
+
+// New creates a RateLimiter
+func New(every time.Duration) *RateLimiter {
+	return &RateLimiter{every: every}
+}
+
+func unrelated() {}
+`
+
+func searchTree(t *testing.T) (*domain.Tree, *pickyfs.MemFileSystem) {
+	fs := pickyfs.NewMemFileSystem()
+	fs.WriteFile("/p/rate.go", []byte(limiterSrc), 0644)
+	fs.WriteFile("/p/docs.md", []byte("Use the RateLimiter.\n"), 0644)
+	fs.WriteFile("/p/blob.bin", []byte("RateLimiter\x00\x01"), 0644)
+	fs.WriteFile("/p/other.go", []byte("package rate\n"), 0644)
+
+	tree, err := domain.BuildTree(fs, "/p")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tree, fs
+}
+
+func TestSearchContent(t *testing.T) {
+	tree, fs := searchTree(t)
+	state := domain.NewViewState("/p")
+
+	results := domain.SearchContent(tree.Root, state, fs, regexp.MustCompile(`RateLimiter`), 0)
+	counts := make(map[string]int)
+	for _, r := range results {
+		counts[r.Node.Name] = len(r.Matches)
+	}
+	if len(counts) != 2 || counts["rate.go"] != 5 || counts["docs.md"] != 1 {
+		t.Errorf("SearchContent matches = %v, want rate.go:5 docs.md:1 and no binaries", counts)
+	}
+	for _, r := range results {
+		if r.Node.Name == "docs.md" && (r.Matches[0].Line != 1 || r.Matches[0].Text != "Use the RateLimiter.") {
+			t.Errorf("unexpected match %+v", r.Matches[0])
+		}
+	}
+
+	// Excluded files are no longer in the tree and are not searched
+	tree.ExcludeNode("/p/docs.md")
+	if results := domain.SearchContent(tree.Root, state, fs, regexp.MustCompile(`Use the`), 0); len(results) != 0 {
+		t.Errorf("excluded file was searched: %v", results)
+	}
+
+	// Files over the size limit are not read
+	results = domain.SearchContent(tree.Root, state, fs, regexp.MustCompile(`RateLimiter`), 64)
+	if len(results) != 0 {
+		t.Errorf("oversized file was searched: %v", results)
+	}
+}
+
+func TestGoDeclRanges(t *testing.T) {
+	// Line 5 is inside the type's doc comment, line 12 inside New
+	ranges, err := domain.GoDeclRanges([]byte(limiterSrc), []int{5, 12, 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []domain.LineRange{{Start: 3, End: 3}, {Start: 5, End: 8}, {Start: 10, End: 13}}
+	if len(ranges) != len(want) {
+		t.Fatalf("GoDeclRanges = %v, want %v", ranges, want)
+	}
+	for i := range want {
+		if ranges[i] != want[i] {
+			t.Errorf("range %d = %v, want %v", i, ranges[i], want[i])
+		}
+	}
+
+	if _, err := domain.GoDeclRanges([]byte("not go"), []int{1}); err == nil {
+		t.Error("expected a parse error")
+	}
+}
+
+func TestMergeRanges(t *testing.T) {
+	got := domain.MergeRanges([]domain.LineRange{{Start: 8, End: 9}, {Start: 1, End: 3}, {Start: 4, End: 5}, {Start: 2, End: 2}})
+	want := []domain.LineRange{{Start: 1, End: 5}, {Start: 8, End: 9}}
+	if len(got) != 2 || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("MergeRanges = %v, want %v", got, want)
+	}
+}
+
+func TestSelectMatches(t *testing.T) {
+	tree, fs := searchTree(t)
+	state := domain.NewViewState("/p")
+	results := domain.SearchContent(tree.Root, state, fs, regexp.MustCompile(`func New`), 0)
+
+	whole := domain.SelectMatches(results, state, fs, false)
+	if !whole.IsSelected("/p/rate.go") || whole.LineRanges("/p/rate.go") != nil {
+		t.Error("selecting files should take the whole file")
+	}
+
+	funcs := domain.SelectMatches(results, state, fs, true)
+	if got := funcs.LineRanges("/p/rate.go"); len(got) != 1 || got[0] != (domain.LineRange{Start: 10, End: 13}) {
+		t.Errorf("enclosing function ranges = %v", got)
+	}
+
+	// A file already selected whole is not narrowed
+	again := domain.SelectMatches(results, whole, fs, true)
+	if again.LineRanges("/p/rate.go") != nil {
+		t.Error("whole-file selection should be kept")
+	}
+
+	// Deselecting drops the ranges
+	if funcs.SetSelected("/p/rate.go", false).LineRanges("/p/rate.go") != nil {
+		t.Error("deselecting should clear line ranges")
+	}
+}
+
+func TestSearchContentLoadsOnlyDirectoriesWithMatches(t *testing.T) {
+	fs := pickyfs.NewMemFileSystem()
+	fs.WriteFile("/p/a/deep/hit.go", []byte("package deep // needle\n"), 0644)
+	fs.WriteFile("/p/b/miss.go", []byte("package b\n"), 0644)
+	tree, err := domain.BuildLazyTree(fs, "/p")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	search := domain.NewContentSearch(tree.Root, domain.NewViewState("/p"), fs, regexp.MustCompile(`needle`), 0)
+	results := search.Run()
+	if domain.FindNodeByPath(tree.Root, "/p/a/deep") != nil {
+		t.Fatal("Run changed the tree")
+	}
+
+	results = search.Attach(tree.Root, results)
+	if len(results) != 1 || results[0].Node != domain.FindNodeByPath(tree.Root, "/p/a/deep/hit.go") {
+		t.Fatalf("results = %v, want hit.go from the tree", results)
+	}
+	if b := domain.FindNodeByPath(tree.Root, "/p/b"); b == nil || b.IsLoaded() {
+		t.Error("directories without matches should stay unloaded")
+	}
+}
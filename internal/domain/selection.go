@@ -22,38 +22,95 @@ func ToggleSelection(root *Node, state ViewState) ViewState {
 	return newState
 }
 
+// TogglePinned toggles the pinned flag on the current node
+// For directories, every descendant is pinned or unpinned along with it
+func TogglePinned(root *Node, state ViewState) ViewState {
+	cursor := FindNodeByPath(root, state.CursorPath)
+	if cursor == nil {
+		return state
+	}
+	
+	pinned := !state.IsPinned(cursor.Path)
+	newState := state.SetPinned(cursor.Path, pinned)
+	
+	LoadAll(cursor)
+	var stack []*Node
+	stack = append(stack, cursor.Children...)
+	for len(stack) > 0 {
+		cur := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		newState = newState.SetPinned(cur.Path, pinned)
+		stack = append(stack, cur.Children...)
+	}
+	
+	return newState
+}
+
 // GetSelectedPaths returns all selected file paths in depth-first order
 func GetSelectedPaths(root *Node, state ViewState) []string {
 	var paths []string
-	collectSelectedPaths(root, state, &paths)
+	for _, node := range GetSelectedFiles(root, state) {
+		paths = append(paths, node.Path)
+	}
 	return paths
 }
 
-func collectSelectedPaths(node *Node, state ViewState, paths *[]string) {
+// GetSelectedFiles returns all selected file nodes in depth-first order
+// A file reachable through several symlinked paths is listed once, at the
+// first of them, and links that are not followed are left out
+func GetSelectedFiles(root *Node, state ViewState) []*Node {
+	var files []*Node
+	collectSelectedFiles(root, state, &files)
+	
+	seen := make(map[FileID]bool)
+	unique := files[:0]
+	for _, file := range files {
+		if file.Kind == KindSymlink {
+			continue
+		}
+		if file.id != (FileID{}) {
+			if seen[file.id] {
+				continue
+			}
+			seen[file.id] = true
+		}
+		unique = append(unique, file)
+	}
+	return unique
+}
+
+func collectSelectedFiles(node *Node, state ViewState, files *[]*Node) {
 	if state.IsSelected(node.Path) && !node.IsDir {
-		*paths = append(*paths, node.Path)
+		*files = append(*files, node)
 	}
 	
-	for _, child := range node.Children {
-		collectSelectedPaths(child, state, paths)
+	for _, child := range VisibleChildren(node, state) {
+		collectSelectedFiles(child, state, files)
 	}
 }
 
-// setSelectionRecursive recursively sets selection state for all descendants
+// setSelectionRecursive recursively sets selection state for all descendants,
+// loading any directories a lazy tree has not read yet
 func setSelectionRecursive(node *Node, state ViewState, selected bool) ViewState {
-	newState := state
-	
-	for _, child := range node.Children {
-		// Select/deselect both files and directories
-		newState = newState.SetSelected(child.Path, selected)
-		
-		// If it's a directory, recurse into it
-		if child.IsDir {
-			newState = setSelectionRecursive(child, newState, selected)
+	return state.setSelectedPaths(subtreePaths(node, state), selected)
+}
+
+// subtreePaths returns the paths of the visible files and directories below
+// node, loading directories as it reaches them
+func subtreePaths(node *Node, state ViewState) []string {
+	var paths []string
+	var walk func(dir *Node)
+	walk = func(dir *Node) {
+		dir.Load()
+		for _, child := range VisibleChildren(dir, state) {
+			paths = append(paths, child.Path)
+			if child.IsDir {
+				walk(child)
+			}
 		}
 	}
-	
-	return newState
+	walk(node)
+	return paths
 }
 
 // HasPartialSelection returns true if a directory has some but not all files selected
@@ -62,7 +119,7 @@ func HasPartialSelection(node *Node, state ViewState) bool {
 		return false
 	}
 	
-	selected, total := countSelectedFiles(node, state)
+	selected, total := selectionCounts(node, state)
 	return selected > 0 && selected < total
 }
 
@@ -72,20 +129,30 @@ func HasFullSelection(node *Node, state ViewState) bool {
 		return false
 	}
 	
-	selected, total := countSelectedFiles(node, state)
+	selected, total := selectionCounts(node, state)
 	return selected > 0 && selected == total
 }
 
+// selectionCounts reads a directory's counts from the tree's aggregates when
+// they are current, and counts its files otherwise
+func selectionCounts(node *Node, state ViewState) (selected, total int) {
+	if selected, total, ok := cachedSelectionCounts(node, state); ok {
+		return selected, total
+	}
+	return countSelectedFiles(node, state)
+}
+
 // countSelectedFiles returns the number of selected files and total files in a directory tree
+// An unloaded directory counts as one file carrying the directory's own flag
 func countSelectedFiles(node *Node, state ViewState) (selected, total int) {
-	if !node.IsDir {
+	if !node.IsDir || !node.IsLoaded() {
 		if state.IsSelected(node.Path) {
 			return 1, 1
 		}
 		return 0, 1
 	}
 	
-	for _, child := range node.Children {
+	for _, child := range VisibleChildren(node, state) {
 		s, t := countSelectedFiles(child, state)
 		selected += s
 		total += t
@@ -269,4 +269,27 @@ func TestToggleSubdirectoryAfterParentSelection(t *testing.T) {
 	if domain.HasFullSelection(childDir, state) {
 		t.Error("Child directory should NOT have full selection after being toggled")
 	}
-}
\ No newline at end of file
+}
+func TestTogglePinnedDirectory(t *testing.T) {
+	root := &domain.Node{
+		Path:  "/root",
+		Name:  "root",
+		IsDir: true,
+		Children: []*domain.Node{
+			{Path: "/root/a.txt", Name: "a.txt"},
+			{Path: "/root/b.txt", Name: "b.txt"},
+		},
+	}
+	
+	state := domain.NewViewState(root.Path)
+	state = domain.TogglePinned(root, state)
+	
+	if !state.IsPinned("/root/a.txt") || !state.IsPinned("/root/b.txt") {
+		t.Error("Pinning a directory should pin all descendants")
+	}
+	
+	state = domain.TogglePinned(root, state)
+	if state.IsPinned("/root/a.txt") || state.IsPinned("/root") {
+		t.Error("Unpinning a directory should unpin all descendants")
+	}
+}
@@ -0,0 +1,135 @@
+package domain
+
+import (
+	"fmt"
+	"sort"
+)
+
+// SortOrder names how siblings are ordered in the tree
+type SortOrder string
+
+const (
+	// SortByName orders alphabetically
+	SortByName SortOrder = "name"
+	// SortByTokens orders by token count; directories by their total
+	SortByTokens SortOrder = "tokens"
+	// SortBySize orders by size in bytes; directories by their total
+	SortBySize SortOrder = "size"
+	// SortByModified orders by modification time; directories by their newest file
+	SortByModified SortOrder = "mtime"
+)
+
+// SortOrders lists the supported sort orders
+var SortOrders = []SortOrder{SortByName, SortByTokens, SortBySize, SortByModified}
+
+// ParseSortOrder validates a sort order name; the empty string means name
+func ParseSortOrder(name string) (SortOrder, error) {
+	switch o := SortOrder(name); o {
+	case "":
+		return SortByName, nil
+	case SortByName, SortByTokens, SortBySize, SortByModified:
+		return o, nil
+	}
+	return "", fmt.Errorf("unknown sort order %q (want name, tokens, size or mtime)", name)
+}
+
+// SortDirection says whether an order runs from smallest to largest or back
+type SortDirection string
+
+const (
+	SortAscending  SortDirection = "asc"
+	SortDescending SortDirection = "desc"
+)
+
+// SortDirections lists the supported sort directions
+var SortDirections = []SortDirection{SortAscending, SortDescending}
+
+// DefaultDirection is the direction an order uses unless one is chosen:
+// A to Z for names, largest or newest first for the others
+func (o SortOrder) DefaultDirection() SortDirection {
+	if o == SortByName || o == "" {
+		return SortAscending
+	}
+	return SortDescending
+}
+
+// ParseSortDirection validates a sort direction name; the empty string
+// means the order's default direction
+func ParseSortDirection(name string, order SortOrder) (SortDirection, error) {
+	switch d := SortDirection(name); d {
+	case "":
+		return order.DefaultDirection(), nil
+	case SortAscending, SortDescending:
+		return d, nil
+	}
+	return "", fmt.Errorf("unknown sort direction %q (want asc or desc)", name)
+}
+
+// Reverse returns the opposite direction
+func (d SortDirection) Reverse() SortDirection {
+	if d == SortDescending {
+		return SortAscending
+	}
+	return SortDescending
+}
+
+// SortTree reorders every directory's children in place, keeping
+// directories before files. tokens holds per-file counts and is only used
+// by SortByTokens. Ties are broken by name, A to Z.
+func SortTree(root *Node, order SortOrder, dir SortDirection, tokens map[string]int) {
+	var totals map[*Node]int64
+	if order != SortByName {
+		totals = make(map[*Node]int64)
+		sumSortKeys(root, order, tokens, totals)
+	}
+	sortChildren(root, order, dir == SortDescending, totals)
+	invalidate(root)
+}
+
+func sortChildren(node *Node, order SortOrder, descending bool, totals map[*Node]int64) {
+	sort.SliceStable(node.Children, func(i, j int) bool {
+		a, b := node.Children[i], node.Children[j]
+		if a.IsDir != b.IsDir {
+			return a.IsDir
+		}
+		if order != SortByName && totals[a] != totals[b] {
+			return (totals[a] < totals[b]) != descending
+		}
+		if order == SortByName && descending {
+			return a.Name > b.Name
+		}
+		return a.Name < b.Name
+	})
+	for _, child := range node.Children {
+		if child.IsDir {
+			sortChildren(child, order, descending, totals)
+		}
+	}
+}
+
+// sumSortKeys records the sort key of every node under node: the sum of its
+// files' tokens or sizes, or the newest modification time among them
+func sumSortKeys(node *Node, order SortOrder, tokens map[string]int, totals map[*Node]int64) int64 {
+	if !node.IsDir {
+		switch order {
+		case SortByTokens:
+			totals[node] = int64(tokens[node.Path])
+		case SortBySize:
+			totals[node] = node.Size
+		case SortByModified:
+			totals[node] = node.ModTime.UnixNano()
+		}
+		return totals[node]
+	}
+	var total int64
+	for _, child := range node.Children {
+		key := sumSortKeys(child, order, tokens, totals)
+		if order == SortByModified {
+			total = max(total, key)
+		} else {
+			total += key
+		}
+	}
+	totals[node] = total
+	return total
+}
@@ -0,0 +1,110 @@
+package domain_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/eliooooooot/picky/internal/domain"
+)
+
+func names(nodes []*domain.Node) []string {
+	out := make([]string, len(nodes))
+	for i, n := range nodes {
+		out[i] = n.Name
+	}
+	return out
+}
+
+func TestSortTree(t *testing.T) {
+	root := &domain.Node{Path: "/r", Name: "r", IsDir: true}
+	small := &domain.Node{Path: "/r/a.go", Name: "a.go", Parent: root}
+	big := &domain.Node{Path: "/r/b.go", Name: "b.go", Parent: root}
+	lib := &domain.Node{Path: "/r/lib", Name: "lib", IsDir: true, Parent: root}
+	pkg := &domain.Node{Path: "/r/pkg", Name: "pkg", IsDir: true, Parent: root}
+	lib.Children = []*domain.Node{{Path: "/r/lib/x.go", Name: "x.go", Parent: lib}}
+	pkg.Children = []*domain.Node{{Path: "/r/pkg/y.go", Name: "y.go", Parent: pkg}}
+	root.Children = []*domain.Node{lib, pkg, small, big}
+	
+	tokens := map[string]int{"/r/a.go": 10, "/r/b.go": 50, "/r/lib/x.go": 5, "/r/pkg/y.go": 90}
+	
+	domain.SortTree(root, domain.SortByTokens, domain.SortDescending, tokens)
+	got := names(root.Children)
+	want := []string{"pkg", "lib", "b.go", "a.go"}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("by tokens = %v, want %v", got, want)
+		}
+	}
+	
+	domain.SortTree(root, domain.SortByName, domain.SortAscending, tokens)
+	got = names(root.Children)
+	want = []string{"lib", "pkg", "a.go", "b.go"}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("by name = %v, want %v", got, want)
+		}
+	}
+	
+	if _, err := domain.ParseSortOrder("weight"); err == nil {
+		t.Error("expected error for unknown sort order")
+	}
+}
+
+func TestSortTreeBySizeAndModTime(t *testing.T) {
+	now := time.Now()
+	root := &domain.Node{Path: "/r", Name: "r", IsDir: true}
+	old := &domain.Node{Path: "/r/old.go", Name: "old.go", Parent: root, Size: 900, ModTime: now.Add(-48 * time.Hour)}
+	fresh := &domain.Node{Path: "/r/fresh.go", Name: "fresh.go", Parent: root, Size: 10, ModTime: now}
+	mid := &domain.Node{Path: "/r/mid.go", Name: "mid.go", Parent: root, Size: 300, ModTime: now.Add(-time.Hour)}
+	lib := &domain.Node{Path: "/r/lib", Name: "lib", IsDir: true, Parent: root}
+	pkg := &domain.Node{Path: "/r/pkg", Name: "pkg", IsDir: true, Parent: root}
+	lib.Children = []*domain.Node{
+		{Path: "/r/lib/x.go", Name: "x.go", Parent: lib, Size: 100, ModTime: now.Add(-72 * time.Hour)},
+		{Path: "/r/lib/y.go", Name: "y.go", Parent: lib, Size: 100, ModTime: now.Add(-time.Minute)},
+	}
+	pkg.Children = []*domain.Node{{Path: "/r/pkg/z.go", Name: "z.go", Parent: pkg, Size: 500, ModTime: now.Add(-24 * time.Hour)}}
+	root.Children = []*domain.Node{lib, pkg, fresh, mid, old}
+
+	tests := []struct {
+		order domain.SortOrder
+		dir   domain.SortDirection
+		want  []string
+	}{
+		{domain.SortBySize, domain.SortDescending, []string{"pkg", "lib", "old.go", "mid.go", "fresh.go"}},
+		{domain.SortBySize, domain.SortAscending, []string{"lib", "pkg", "fresh.go", "mid.go", "old.go"}},
+		// Directories take the time of their newest file
+		{domain.SortByModified, domain.SortDescending, []string{"lib", "pkg", "fresh.go", "mid.go", "old.go"}},
+		{domain.SortByName, domain.SortDescending, []string{"pkg", "lib", "old.go", "mid.go", "fresh.go"}},
+	}
+	for _, tt := range tests {
+		domain.SortTree(root, tt.order, tt.dir, nil)
+		got := names(root.Children)
+		for i := range tt.want {
+			if got[i] != tt.want[i] {
+				t.Errorf("%s %s = %v, want %v", tt.order, tt.dir, got, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestParseSortDirection(t *testing.T) {
+	for _, tt := range []struct {
+		name  string
+		order domain.SortOrder
+		want  domain.SortDirection
+	}{
+		{"", domain.SortByName, domain.SortAscending},
+		{"", domain.SortBySize, domain.SortDescending},
+		{"asc", domain.SortByTokens, domain.SortAscending},
+		{"desc", domain.SortByName, domain.SortDescending},
+	} {
+		got, err := domain.ParseSortDirection(tt.name, tt.order)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseSortDirection(%q, %s) = %v, %v; want %v", tt.name, tt.order, got, err, tt.want)
+		}
+	}
+	if _, err := domain.ParseSortDirection("sideways", domain.SortByName); err == nil {
+		t.Error("expected error for unknown sort direction")
+	}
+}
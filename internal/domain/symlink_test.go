@@ -0,0 +1,61 @@
+package domain_test
+
+import (
+	"testing"
+
+	"github.com/eliooooooot/picky/internal/domain"
+	pickyfs "github.com/eliooooooot/picky/internal/fs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newLinkedFS(t *testing.T) *pickyfs.MemFileSystem {
+	fs := pickyfs.NewMemFileSystem()
+	require.NoError(t, fs.WriteFile("/root/lib/util.go", []byte("package lib"), 0644))
+	fs.AddSymlink("/root/lib/self", "..")
+	fs.AddSymlink("/root/vendor", "lib")
+	fs.AddSymlink("/root/util.go", "/root/lib/util.go")
+	return fs
+}
+
+func TestFollowedLinksStopAtLoops(t *testing.T) {
+	tree, err := domain.BuildTreeWithOptions(newLinkedFS(t), "/root", domain.TreeOptions{FollowSymlinks: true})
+	require.NoError(t, err)
+
+	self := domain.FindNodeByPath(tree.Root, "/root/lib/self")
+	require.NotNil(t, self)
+	assert.True(t, self.IsDir)
+	assert.True(t, self.Loop, "lib/self leads back to the root")
+	assert.Equal(t, "..", self.LinkTarget)
+	assert.Empty(t, self.Children)
+
+	vendor := domain.FindNodeByPath(tree.Root, "/root/vendor")
+	require.NotNil(t, vendor)
+	assert.Equal(t, "lib", vendor.LinkTarget)
+	assert.False(t, vendor.Loop)
+	assert.NotNil(t, domain.FindNodeByPath(tree.Root, "/root/vendor/util.go"))
+	assert.True(t, domain.FindNodeByPath(tree.Root, "/root/vendor/self").Loop)
+}
+
+func TestSelectedFilesAreDeduplicatedAcrossLinks(t *testing.T) {
+	tree, err := domain.BuildTreeWithOptions(newLinkedFS(t), "/root", domain.TreeOptions{FollowSymlinks: true})
+	require.NoError(t, err)
+
+	state := domain.SelectAll(tree.Root, domain.NewViewState("/root"))
+	assert.Equal(t, []string{"/root/lib/util.go"}, domain.GetSelectedPaths(tree.Root, state))
+}
+
+func TestUnfollowedLinksAreListedButNotRead(t *testing.T) {
+	tree, err := domain.BuildTreeWithOptions(newLinkedFS(t), "/root", domain.TreeOptions{})
+	require.NoError(t, err)
+
+	vendor := domain.FindNodeByPath(tree.Root, "/root/vendor")
+	require.NotNil(t, vendor)
+	assert.False(t, vendor.IsDir)
+	assert.Equal(t, domain.KindSymlink, vendor.Kind)
+	assert.Equal(t, "lib", vendor.LinkTarget)
+
+	state := domain.SelectAll(tree.Root, domain.NewViewState("/root"))
+	assert.True(t, domain.IsSkipped(vendor, state))
+	assert.Equal(t, []string{"/root/lib/util.go"}, domain.GetSelectedPaths(tree.Root, state))
+}
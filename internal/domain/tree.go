@@ -1,6 +1,9 @@
 package domain
 
-import "path/filepath"
+import (
+	"path/filepath"
+	"time"
+)
 
 // Node represents a file or directory in the tree
 type Node struct {
@@ -9,22 +12,53 @@ type Node struct {
 	IsDir    bool
 	Parent   *Node
 	Children []*Node
+	
+	// Kind classifies a file's contents; directories are always KindText
+	Kind FileKind
+	
+	// Size and ModTime come from Stat when the tree is built
+	Size    int64
+	ModTime time.Time
+	
+	// LinkTarget is where a symbolic link points, as written in the link
+	LinkTarget string
+	
+	// Loop is set on a linked directory that leads back to one of its
+	// ancestors; its children are not read
+	Loop bool
+	
+	// id identifies the file behind the node when the file system knows it
+	id FileID
+	
+	// loader is set on directories of a lazy tree whose children are unread
+	loader *builder
+	
+	// index is set on the root of a tree made by NewTree
+	index *treeIndex
 }
 
 // Tree represents the file tree
 type Tree struct {
 	Root *Node
+	
+	// loader reads directories on demand when the tree was built lazily
+	loader *builder
 }
 
-// NewTree creates a new tree
+// NewTree creates a new tree, indexing it for fast lookups
 func NewTree(root *Node) *Tree {
+	root.index = &treeIndex{root: root}
 	return &Tree{
 		Root: root,
 	}
 }
 
 // Flatten returns all nodes in the tree in depth-first order
+// The result is cached and must not be modified
 func (t *Tree) Flatten() []*Node {
+	if t.Root.index != nil {
+		return t.Root.index.flattenAll()
+	}
 	var result []*Node
 	flattenNode(t.Root, &result)
 	return result
@@ -65,6 +99,8 @@ func (t *Tree) ExcludeNode(nodePath string) (string, *Node) {
 		}
 	}
 	
+	invalidate(parent)
+	
 	// Clean up parent reference to help GC
 	node.Parent = nil
 	
@@ -1,7 +1,9 @@
 package domain
 
 import (
+	"io/fs"
 	"path/filepath"
+	"slices"
 	"sort"
 )
 
@@ -9,6 +11,22 @@ import (
 // Returns false to skip the path
 type PathFilter func(absolutePath string, isDir bool) bool
 
+// TreeOptions configures how a tree is read from a file system
+type TreeOptions struct {
+	// Keep filters the paths read; nil keeps everything
+	Keep PathFilter
+	
+	// FollowSymlinks reads through symbolic links, expanding linked
+	// directories and reading linked files. A link back to one of its own
+	// ancestors is marked as a loop and not expanded. Without it links are
+	// listed with their target but never read.
+	FollowSymlinks bool
+	
+	// Lazy reads a directory's children only when it is first expanded or
+	// selected, so huge repositories open at once
+	Lazy bool
+}
+
 // BuildTree creates a tree from a filesystem starting at rootPath
 func BuildTree(fs FileSystem, rootPath string) (*Tree, error) {
 	return BuildTreeWithFilter(fs, rootPath, nil)
@@ -16,62 +34,148 @@ func BuildTree(fs FileSystem, rootPath string) (*Tree, error) {
 
 // BuildTreeWithFilter creates a tree from a filesystem with an optional filter predicate
 func BuildTreeWithFilter(fs FileSystem, rootPath string, keep PathFilter) (*Tree, error) {
-	root, err := buildNodeWithFilter(fs, rootPath, nil, keep)
+	return BuildTreeWithOptions(fs, rootPath, TreeOptions{Keep: keep, FollowSymlinks: true})
+}
+
+// BuildTreeWithOptions creates a tree from a filesystem as opts describe
+func BuildTreeWithOptions(fs FileSystem, rootPath string, opts TreeOptions) (*Tree, error) {
+	b := &builder{fs: fs, opts: opts}
+	root, err := b.stat(rootPath, nil, nil)
 	if err != nil {
 		return nil, err
 	}
+	if root.IsDir {
+		root.Children = b.children(root, root.Path, ancestorIDs(root))
+	}
 	
-	return NewTree(root), nil
+	tree := NewTree(root)
+	if opts.Lazy {
+		tree.loader = b
+	}
+	return tree, nil
 }
 
-func buildNode(fs FileSystem, path string, parent *Node) (*Node, error) {
-	return buildNodeWithFilter(fs, path, parent, nil)
+// builder reads nodes from a file system. In a lazy tree it stays attached
+// to the directories it has not read yet and records the ones it loads.
+type builder struct {
+	fs     FileSystem
+	opts   TreeOptions
+	loaded []*Node
 }
 
-func buildNodeWithFilter(fs FileSystem, path string, parent *Node, keep PathFilter) (*Node, error) {
-	info, err := fs.Stat(path)
+// node reads the node at path. Directories are read in full, or in a lazy
+// tree left for Load. ancestors holds the file ids of parent and everything
+// above it.
+func (b *builder) node(path string, parent *Node, ancestors []FileID) (*Node, error) {
+	node, err := b.stat(path, parent, ancestors)
 	if err != nil {
 		return nil, err
 	}
 	
+	if node.IsDir && !node.Loop {
+		if b.opts.Lazy {
+			node.loader = b
+		} else {
+			node.Children = b.children(node, path, append(ancestors[:len(ancestors):len(ancestors)], node.id))
+		}
+	}
+	
+	return node, nil
+}
+
+// stat creates a node for path without reading a directory's children
+func (b *builder) stat(path string, parent *Node, ancestors []FileID) (*Node, error) {
 	node := &Node{
 		Path:   path,
 		Name:   filepath.Base(path),
-		IsDir:  info.IsDir(),
 		Parent: parent,
 	}
 	
-	if node.IsDir {
-		entries, err := fs.ReadDir(path)
+	// The root is always followed, since it was asked for by path
+	links, hasLinks := b.fs.(LinkFileSystem)
+	if hasLinks && parent != nil {
+		linfo, err := links.Lstat(path)
 		if err != nil {
-			// Skip directories we can't read
-			return node, nil
+			return nil, err
 		}
-		
-		for _, entry := range entries {
-			childPath := filepath.Join(path, entry.Name())
-			
-			// Apply filter if provided
-			if keep != nil && !keep(childPath, entry.IsDir()) {
-				continue
-			}
-			
-			child, err := buildNodeWithFilter(fs, childPath, node, keep)
-			if err != nil {
-				continue // Skip files we can't access
+		if linfo.Mode()&fs.ModeSymlink != 0 {
+			node.LinkTarget, _ = links.Readlink(path)
+			if !b.opts.FollowSymlinks {
+				node.Kind = KindSymlink
+				node.ModTime = linfo.ModTime()
+				return node, nil
 			}
-			node.Children = append(node.Children, child)
 		}
-		
-		// Sort children: directories first, then files, both alphabetically
-		sort.Slice(node.Children, func(i, j int) bool {
-			a, b := node.Children[i], node.Children[j]
-			if a.IsDir != b.IsDir {
-				return a.IsDir
-			}
-			return a.Name < b.Name
-		})
+	}
+	
+	info, err := b.fs.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	node.IsDir = info.IsDir()
+	node.ModTime = info.ModTime()
+	if hasLinks {
+		node.id = links.FileID(info)
+	}
+	
+	if node.IsDir {
+		node.Loop = node.LinkTarget != "" && hasAncestor(ancestors, node.id)
+	} else {
+		node.Size = info.Size()
+		node.Kind = SniffKind(b.fs, path, node.Name)
 	}
 	
 	return node, nil
-}
\ No newline at end of file
+}
+
+// hasAncestor reports whether the file id is one of the ancestors' ids
+func hasAncestor(ancestors []FileID, id FileID) bool {
+	return id != (FileID{}) && slices.Contains(ancestors, id)
+}
+
+// ancestorIDs returns the file ids of node and everything above it
+func ancestorIDs(node *Node) []FileID {
+	var ids []FileID
+	for ; node != nil; node = node.Parent {
+		ids = append(ids, node.id)
+	}
+	return ids
+}
+
+// children builds the kept entries of the directory dir at path, whose
+// ancestors, dir included, have the given file ids. It reads no fields of
+// dir, which only becomes the children's parent, so a lazy load can run it
+// off the goroutine that changes the tree.
+func (b *builder) children(dir *Node, path string, ancestors []FileID) []*Node {
+	entries, err := b.fs.ReadDir(path)
+	if err != nil {
+		// Skip directories we can't read
+		return nil
+	}
+	
+	var children []*Node
+	for _, entry := range entries {
+		childPath := filepath.Join(path, entry.Name())
+		
+		// Apply filter if provided
+		if b.opts.Keep != nil && !b.opts.Keep(childPath, entry.IsDir()) {
+			continue
+		}
+		
+		child, err := b.node(childPath, dir, ancestors)
+		if err != nil {
+			continue // Skip files we can't access
+		}
+		children = append(children, child)
+	}
+	
+	// Sort children: directories first, then files, both alphabetically
+	sort.Slice(children, func(i, j int) bool {
+		a, c := children[i], children[j]
+		if a.IsDir != c.IsDir {
+			return a.IsDir
+		}
+		return a.Name < c.Name
+	})
+	return children
+}
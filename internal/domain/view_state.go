@@ -1,8 +1,14 @@
 package domain
 
-import "strings"
+import (
+	"path"
+	"strings"
+	"sync/atomic"
+)
 
-// ViewState represents the UI state separate from the domain model
+// ViewState represents the UI state separate from the domain model. Its
+// maps must only be changed through the setters, which copy them and give
+// the result a new version for caches to key on.
 type ViewState struct {
 	// CursorPath is the path of the currently focused node
 	CursorPath string
@@ -14,6 +20,61 @@ type ViewState struct {
 	// Selected tracks which nodes are selected
 	// Key is the node path, value is whether it's selected
 	Selected map[string]bool
+	
+	// Pinned tracks files the user wants kept when trimming to a budget
+	Pinned map[string]bool
+	
+	// Truncated caps how many tokens of a selected file are emitted
+	// Key is the file path, value is the number of tokens kept
+	Truncated map[string]int
+	
+	// Lines limits a selected file to some of its line ranges
+	// Files without an entry are emitted whole
+	Lines map[string][]LineRange
+	
+	// Forced tracks binary or generated files the user wants in the output anyway
+	Forced map[string]bool
+	
+	// Modes tracks how much of each selected file is written to the output
+	// Files without an entry are written in full
+	Modes map[string]InclusionMode
+	
+	// HideHidden hides dotfiles and dot-directories from the view
+	// Their selections are kept but they are left out of output while hidden
+	HideHidden bool
+	
+	// HiddenAllow lists dotfiles shown even while HideHidden is set, as
+	// names or shell patterns matched against a node's name; a trailing "/"
+	// matches directories only, e.g. ".github/" or ".env.*"
+	HiddenAllow []string
+	
+	// version identifies these contents among every state made by
+	// NewViewState or a setter; zero marks a state built by hand, which
+	// caches never reuse
+	version uint64
+}
+
+// stateVersions hands out ViewState versions
+var stateVersions atomic.Uint64
+
+// Version identifies the state's contents for caches kept outside the
+// domain; states built by hand have version 0 and should not be cached
+func (v ViewState) Version() uint64 {
+	return v.version
+}
+
+// DefaultHiddenAllow returns the dotfiles that are usually worth including
+func DefaultHiddenAllow() []string {
+	return []string{
+		".github/",
+		".gitignore",
+		".gitattributes",
+		".editorconfig",
+		".dockerignore",
+		".golangci.yml",
+		".golangci.yaml",
+		".env.example",
+	}
 }
 
 // NewViewState creates a new ViewState with the given root path as cursor
@@ -22,6 +83,12 @@ func NewViewState(rootPath string) ViewState {
 		CursorPath: rootPath,
 		Open:       make(map[string]bool),
 		Selected:   make(map[string]bool),
+		Pinned:     make(map[string]bool),
+		Truncated:  make(map[string]int),
+		Lines:      make(map[string][]LineRange),
+		Forced:     make(map[string]bool),
+		Modes:      make(map[string]InclusionMode),
+		version:    stateVersions.Add(1),
 	}
 }
 
@@ -35,6 +102,71 @@ func (v ViewState) IsSelected(path string) bool {
 	return v.Selected[path]
 }
 
+// IsPinned returns whether a node at the given path is pinned
+func (v ViewState) IsPinned(path string) bool {
+	return v.Pinned[path]
+}
+
+// TruncatedTokens returns the token cap for a file and whether one is set
+func (v ViewState) TruncatedTokens(path string) (int, bool) {
+	n, ok := v.Truncated[path]
+	return n, ok
+}
+
+// LineRanges returns the line ranges a file is limited to, or nil for the whole file
+func (v ViewState) LineRanges(path string) []LineRange {
+	return v.Lines[path]
+}
+
+// IsForced returns whether a binary or generated file is forced into the output
+func (v ViewState) IsForced(path string) bool {
+	return v.Forced[path]
+}
+
+// Mode returns how much of a file is written to the output
+func (v ViewState) Mode(path string) InclusionMode {
+	if mode, ok := v.Modes[path]; ok {
+		return mode
+	}
+	return IncludeFull
+}
+
+// IsVisible returns whether a node is shown under the current view options
+func (v ViewState) IsVisible(node *Node) bool {
+	if v.HideHidden && node.Parent != nil && strings.HasPrefix(node.Name, ".") {
+		return v.isAllowedHidden(node)
+	}
+	return true
+}
+
+// isAllowedHidden reports whether a dotfile matches HiddenAllow
+func (v ViewState) isAllowedHidden(node *Node) bool {
+	for _, pattern := range v.HiddenAllow {
+		name, dirOnly := strings.CutSuffix(pattern, "/")
+		if dirOnly && !node.IsDir {
+			continue
+		}
+		if ok, _ := path.Match(name, node.Name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// SetHiddenAllow sets the dotfiles shown even while hidden files are hidden
+func (v ViewState) SetHiddenAllow(patterns []string) ViewState {
+	newState := v.copy()
+	newState.HiddenAllow = append([]string(nil), patterns...)
+	return newState
+}
+
+// SetHideHidden sets whether dotfiles are hidden from the view
+func (v ViewState) SetHideHidden(hide bool) ViewState {
+	newState := v.copy()
+	newState.HideHidden = hide
+	return newState
+}
+
 // SetOpen sets the expanded state for a node at the given path
 func (v ViewState) SetOpen(path string, open bool) ViewState {
 	newState := v.copy()
@@ -48,18 +180,88 @@ func (v ViewState) SetOpen(path string, open bool) ViewState {
 
 // SetSelected sets the selected state for a node at the given path
 func (v ViewState) SetSelected(path string, selected bool) ViewState {
+	return v.setSelectedPaths([]string{path}, selected)
+}
+
+// setSelectedPaths sets the selected state of many paths with a single
+// copy of the state, so selecting a large subtree stays linear
+func (v ViewState) setSelectedPaths(paths []string, selected bool) ViewState {
 	newState := v.copy()
-	if selected {
-		newState.Selected[path] = true
-	} else {
+	for _, path := range paths {
+		if selected {
+			newState.Selected[path] = true
+			continue
+		}
 		delete(newState.Selected, path)
+		// A deselected file no longer carries a truncation or line ranges
+		delete(newState.Truncated, path)
+		delete(newState.Lines, path)
+		delete(newState.Forced, path)
+		delete(newState.Modes, path)
+	}
+	return newState
+}
+
+// SetPinned sets the pinned state for a node at the given path
+func (v ViewState) SetPinned(path string, pinned bool) ViewState {
+	newState := v.copy()
+	if pinned {
+		newState.Pinned[path] = true
+	} else {
+		delete(newState.Pinned, path)
+	}
+	return newState
+}
+
+// SetTruncated caps the tokens emitted for a file; a non-positive cap clears it
+func (v ViewState) SetTruncated(path string, tokens int) ViewState {
+	newState := v.copy()
+	if tokens > 0 {
+		newState.Truncated[path] = tokens
+	} else {
+		delete(newState.Truncated, path)
+	}
+	return newState
+}
+
+// SetLineRanges limits a file to the given line ranges; no ranges means the whole file
+func (v ViewState) SetLineRanges(path string, ranges []LineRange) ViewState {
+	newState := v.copy()
+	if len(ranges) > 0 {
+		newState.Lines[path] = append([]LineRange(nil), ranges...)
+	} else {
+		delete(newState.Lines, path)
+	}
+	return newState
+}
+
+// SetForced sets whether a binary or generated file is written to the output
+func (v ViewState) SetForced(path string, forced bool) ViewState {
+	newState := v.copy()
+	if forced {
+		newState.Forced[path] = true
+	} else {
+		delete(newState.Forced, path)
+	}
+	return newState
+}
+
+// SetMode sets how much of a file is written; full clears the entry
+func (v ViewState) SetMode(path string, mode InclusionMode) ViewState {
+	newState := v.copy()
+	if mode != "" && mode != IncludeFull {
+		newState.Modes[path] = mode
+	} else {
+		delete(newState.Modes, path)
 	}
 	return newState
 }
 
 // SetCursor updates the cursor position
+// The maps and version are shared rather than copied since setters never
+// modify them in place, which keeps caches valid while navigating
 func (v ViewState) SetCursor(path string) ViewState {
-	newState := v.copy()
+	newState := v
 	newState.CursorPath = path
 	return newState
 }
@@ -83,6 +285,41 @@ func (v ViewState) Prune(pathPrefix string) ViewState {
 		}
 	}
 	
+	// Remove from Pinned map
+	for path := range newState.Pinned {
+		if strings.HasPrefix(path, pathPrefix) {
+			delete(newState.Pinned, path)
+		}
+	}
+	
+	// Remove from Truncated map
+	for path := range newState.Truncated {
+		if strings.HasPrefix(path, pathPrefix) {
+			delete(newState.Truncated, path)
+		}
+	}
+	
+	// Remove from Lines map
+	for path := range newState.Lines {
+		if strings.HasPrefix(path, pathPrefix) {
+			delete(newState.Lines, path)
+		}
+	}
+	
+	// Remove from Forced map
+	for path := range newState.Forced {
+		if strings.HasPrefix(path, pathPrefix) {
+			delete(newState.Forced, path)
+		}
+	}
+	
+	// Remove from Modes map
+	for path := range newState.Modes {
+		if strings.HasPrefix(path, pathPrefix) {
+			delete(newState.Modes, path)
+		}
+	}
+	
 	return newState
 }
 
@@ -98,9 +335,44 @@ func (v ViewState) copy() ViewState {
 		newSelected[k] = val
 	}
 	
+	newPinned := make(map[string]bool, len(v.Pinned))
+	for k, val := range v.Pinned {
+		newPinned[k] = val
+	}
+	
+	newTruncated := make(map[string]int, len(v.Truncated))
+	for k, val := range v.Truncated {
+		newTruncated[k] = val
+	}
+	
+	// Range slices are never modified in place, so they can be shared
+	newLines := make(map[string][]LineRange, len(v.Lines))
+	for k, val := range v.Lines {
+		newLines[k] = val
+	}
+	
+	newForced := make(map[string]bool, len(v.Forced))
+	for k, val := range v.Forced {
+		newForced[k] = val
+	}
+	
+	newModes := make(map[string]InclusionMode, len(v.Modes))
+	for k, val := range v.Modes {
+		newModes[k] = val
+	}
+	
+	// HiddenAllow is never modified in place, so it can be shared
 	return ViewState{
-		CursorPath: v.CursorPath,
-		Open:       newOpen,
-		Selected:   newSelected,
+		CursorPath:  v.CursorPath,
+		HideHidden:  v.HideHidden,
+		HiddenAllow: v.HiddenAllow,
+		Open:        newOpen,
+		Selected:    newSelected,
+		Pinned:      newPinned,
+		Truncated:   newTruncated,
+		Lines:       newLines,
+		Forced:      newForced,
+		Modes:       newModes,
+		version:     stateVersions.Add(1),
 	}
 }
\ No newline at end of file
@@ -67,4 +67,96 @@ func TestViewStatePrune(t *testing.T) {
 		assert.True(t, state.IsOpen("/root/dir1"))
 		assert.False(t, prunedState.IsOpen("/root/dir1"))
 	})
-}
\ No newline at end of file
+}
+func TestHideHidden(t *testing.T) {
+	root := &domain.Node{Path: "/r", Name: "r", IsDir: true}
+	git := &domain.Node{Path: "/r/.git", Name: ".git", IsDir: true, Parent: root}
+	head := &domain.Node{Path: "/r/.git/HEAD", Name: "HEAD", Parent: git}
+	git.Children = []*domain.Node{head}
+	env := &domain.Node{Path: "/r/.env", Name: ".env", Parent: root}
+	main := &domain.Node{Path: "/r/main.go", Name: "main.go", Parent: root}
+	root.Children = []*domain.Node{git, env, main}
+	
+	state := domain.NewViewState("/r").SetOpen("/r", true)
+	state = state.SetSelected("/r/.env", true)
+	
+	state = state.SetHideHidden(true)
+	flat := domain.Flatten(root, state)
+	if len(flat) != 2 || flat[1] != main {
+		t.Fatalf("expected root and main.go only, got %d nodes", len(flat))
+	}
+	if paths := domain.GetSelectedPaths(root, state); len(paths) != 0 {
+		t.Errorf("hidden selections should be left out of output, got %v", paths)
+	}
+	if got := domain.NearestVisible(root, state, "/r/.git/HEAD"); got != "/r" {
+		t.Errorf("NearestVisible = %q, want /r", got)
+	}
+	
+	// Selecting the root does not pick up hidden files
+	state = state.SetCursor("/r")
+	state = domain.ToggleSelection(root, state)
+	if state.IsSelected("/r/.git/HEAD") {
+		t.Error("hidden file should not be selected through its parent")
+	}
+	
+	// Showing hidden files again restores the earlier selection
+	state = state.SetHideHidden(false)
+	if !state.IsSelected("/r/.env") {
+		t.Error("selection of hidden file should survive hiding")
+	}
+	if len(domain.Flatten(root, state)) != 4 {
+		t.Error("expected hidden nodes to be visible again")
+	}
+}
+
+func TestHiddenAllow(t *testing.T) {
+	root := &domain.Node{Path: "/r", Name: "r", IsDir: true}
+	github := &domain.Node{Path: "/r/.github", Name: ".github", IsDir: true, Parent: root}
+	idea := &domain.Node{Path: "/r/.idea", Name: ".idea", IsDir: true, Parent: root}
+	example := &domain.Node{Path: "/r/.env.example", Name: ".env.example", Parent: root}
+	env := &domain.Node{Path: "/r/.env", Name: ".env", Parent: root}
+	fakeDir := &domain.Node{Path: "/r/.gitignore", Name: ".gitignore", IsDir: true, Parent: root}
+	root.Children = []*domain.Node{github, idea, fakeDir, env, example}
+
+	state := domain.NewViewState("/r").SetOpen("/r", true).SetHideHidden(true)
+	state = state.SetHiddenAllow([]string{".github/", ".env.*", ".gitignore"})
+
+	assert.True(t, state.IsVisible(github))
+	assert.True(t, state.IsVisible(example))
+	assert.True(t, state.IsVisible(fakeDir), "patterns without a slash match directories too")
+	assert.False(t, state.IsVisible(idea))
+	assert.False(t, state.IsVisible(env))
+
+	file := &domain.Node{Path: "/r/.github2", Name: ".github", Parent: root}
+	assert.False(t, state.IsVisible(file), "a trailing slash matches directories only")
+
+	// The allowlist survives other changes and only matters while hiding
+	state = state.SetSelected("/r/.env.example", true).SetHideHidden(false)
+	assert.True(t, state.IsVisible(idea))
+	state = state.SetHideHidden(true)
+	assert.Equal(t, []string{"/r/.env.example"}, domain.GetSelectedPaths(root, state))
+}
+
+func TestInclusionModes(t *testing.T) {
+	state := domain.NewViewState("/root")
+	assert.Equal(t, domain.IncludeFull, state.Mode("/root/a.go"))
+	state = state.SetSelected("/root/a.go", true).SetMode("/root/a.go", domain.IncludeOutline)
+	assert.Equal(t, domain.IncludeOutline, state.Mode("/root/a.go"))
+	assert.Empty(t, state.SetMode("/root/a.go", domain.IncludeFull).Modes, "full is the default and is not stored")
+
+	// Deselecting or pruning a file drops its mode
+	assert.Equal(t, domain.IncludeFull, state.SetSelected("/root/a.go", false).Mode("/root/a.go"))
+	assert.Equal(t, domain.IncludeFull, state.Prune("/root").Mode("/root/a.go"))
+	assert.Equal(t, domain.IncludeOutline, state.Mode("/root/a.go"), "setters do not modify the original")
+
+	// Cycling visits every mode and wraps around
+	mode := domain.IncludeFull
+	for range domain.InclusionModes {
+		mode = mode.Next()
+	}
+	assert.Equal(t, domain.IncludeFull, mode)
+	assert.Equal(t, domain.IncludeOutline, domain.IncludeFull.Next())
+
+	_, err := domain.ParseInclusionMode("summary")
+	assert.Error(t, err)
+}
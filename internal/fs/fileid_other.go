@@ -0,0 +1,14 @@
+//go:build !unix
+
+package fs
+
+import (
+	"io/fs"
+
+	"github.com/eliooooooot/picky/internal/domain"
+)
+
+// FileID is unknown on systems without inodes, so link loops are not detected
+func (f *OSFileSystem) FileID(info fs.FileInfo) domain.FileID {
+	return domain.FileID{}
+}
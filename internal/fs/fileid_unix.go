@@ -0,0 +1,19 @@
+//go:build unix
+
+package fs
+
+import (
+	"io/fs"
+	"syscall"
+
+	"github.com/eliooooooot/picky/internal/domain"
+)
+
+// FileID identifies a file by the device and inode Stat reported
+func (f *OSFileSystem) FileID(info fs.FileInfo) domain.FileID {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return domain.FileID{}
+	}
+	return domain.FileID{Dev: uint64(st.Dev), Ino: uint64(st.Ino)}
+}
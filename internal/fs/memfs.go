@@ -2,6 +2,7 @@ package fs
 
 import (
 	"bytes"
+	"errors"
 	"io"
 	"io/fs"
 	"path/filepath"
@@ -13,6 +14,7 @@ import (
 // MemFileSystem is an in-memory filesystem for testing
 type MemFileSystem struct {
 	files map[string]*memFile
+	ids   map[*memFile]uint64
 }
 
 type memFile struct {
@@ -20,8 +22,12 @@ type memFile struct {
 	content []byte
 	isDir   bool
 	modTime time.Time
+	link    string // target of a symbolic link
 }
 
+// maxLinkHops bounds how many links a path may pass through, like ELOOP
+const maxLinkHops = 40
+
 // NewMemFileSystem creates a new in-memory filesystem
 func NewMemFileSystem() *MemFileSystem {
 	return &MemFileSystem{
@@ -54,6 +60,81 @@ func (m *MemFileSystem) AddDir(path string) {
 	m.ensureParentDirs(path)
 }
 
+// AddSymlink adds a symbolic link to target, which is relative to the
+// link's directory unless absolute
+func (m *MemFileSystem) AddSymlink(path string, target string) {
+	m.files[path] = &memFile{
+		name:    filepath.Base(path),
+		modTime: time.Now(),
+		link:    target,
+	}
+	
+	// Ensure parent directories exist
+	m.ensureParentDirs(path)
+}
+
+// resolve follows every link in path, including the last element
+func (m *MemFileSystem) resolve(path string) (string, error) {
+	for hops := 0; hops < maxLinkHops; hops++ {
+		link, rest, ok := m.firstLink(path)
+		if !ok {
+			return path, nil
+		}
+		target := m.files[link].link
+		if !filepath.IsAbs(target) {
+			target = filepath.Join(filepath.Dir(link), target)
+		}
+		path = filepath.Join(target, rest)
+	}
+	return "", &fs.PathError{Op: "stat", Path: path, Err: errors.New("too many levels of symbolic links")}
+}
+
+// firstLink returns the outermost link among path and its parents, and the
+// part of path below it
+func (m *MemFileSystem) firstLink(path string) (link, rest string, ok bool) {
+	path = filepath.Clean(path)
+	var prefixes []string
+	for p := path; ; p = filepath.Dir(p) {
+		prefixes = append(prefixes, p)
+		if p == filepath.Dir(p) {
+			break
+		}
+	}
+	for i := len(prefixes) - 1; i >= 0; i-- {
+		if f := m.files[prefixes[i]]; f != nil && f.link != "" {
+			rest, _ := filepath.Rel(prefixes[i], path)
+			return prefixes[i], rest, true
+		}
+	}
+	return "", "", false
+}
+
+// lookup finds the file at path, following links
+func (m *MemFileSystem) lookup(path string) (*memFile, error) {
+	resolved, err := m.resolve(path)
+	if err != nil {
+		return nil, err
+	}
+	f, exists := m.files[resolved]
+	if !exists {
+		return nil, fs.ErrNotExist
+	}
+	return f, nil
+}
+
+// lookupLink finds the file at path, following links in its parents only
+func (m *MemFileSystem) lookupLink(path string) (*memFile, error) {
+	dir, err := m.resolve(filepath.Dir(path))
+	if err != nil {
+		return nil, err
+	}
+	f, exists := m.files[filepath.Join(dir, filepath.Base(path))]
+	if !exists {
+		return nil, fs.ErrNotExist
+	}
+	return f, nil
+}
+
 func (m *MemFileSystem) ensureParentDirs(path string) {
 	dir := filepath.Dir(path)
 	if dir == "." || dir == "/" {
@@ -68,6 +149,11 @@ func (m *MemFileSystem) ensureParentDirs(path string) {
 func (m *MemFileSystem) ReadDir(path string) ([]fs.DirEntry, error) {
 	var entries []fs.DirEntry
 	
+	path, err := m.resolve(path)
+	if err != nil {
+		return nil, err
+	}
+	
 	for p, f := range m.files {
 		if filepath.Dir(p) == path && p != path {
 			entries = append(entries, &memDirEntry{f})
@@ -82,23 +168,58 @@ func (m *MemFileSystem) ReadDir(path string) ([]fs.DirEntry, error) {
 }
 
 func (m *MemFileSystem) Open(path string) (io.ReadCloser, error) {
-	f, exists := m.files[path]
-	if !exists {
-		return nil, fs.ErrNotExist
+	f, err := m.lookup(path)
+	if err != nil {
+		return nil, err
 	}
 	
 	return io.NopCloser(bytes.NewReader(f.content)), nil
 }
 
 func (m *MemFileSystem) Stat(path string) (fs.FileInfo, error) {
-	f, exists := m.files[path]
-	if !exists {
-		return nil, fs.ErrNotExist
+	f, err := m.lookup(path)
+	if err != nil {
+		return nil, err
+	}
+	
+	return &memFileInfo{f, path}, nil
+}
+
+func (m *MemFileSystem) Lstat(path string) (fs.FileInfo, error) {
+	f, err := m.lookupLink(path)
+	if err != nil {
+		return nil, err
 	}
 	
 	return &memFileInfo{f, path}, nil
 }
 
+func (m *MemFileSystem) Readlink(path string) (string, error) {
+	f, err := m.lookupLink(path)
+	if err != nil {
+		return "", err
+	}
+	if f.link == "" {
+		return "", &fs.PathError{Op: "readlink", Path: path, Err: fs.ErrInvalid}
+	}
+	return f.link, nil
+}
+
+// FileID numbers each file the first time it is asked about
+func (m *MemFileSystem) FileID(info fs.FileInfo) domain.FileID {
+	mi, ok := info.(*memFileInfo)
+	if !ok {
+		return domain.FileID{}
+	}
+	if m.ids == nil {
+		m.ids = make(map[*memFile]uint64)
+	}
+	if _, ok := m.ids[mi.f]; !ok {
+		m.ids[mi.f] = uint64(len(m.ids) + 1)
+	}
+	return domain.FileID{Dev: 1, Ino: m.ids[mi.f]}
+}
+
 func (m *MemFileSystem) Create(path string) (io.WriteCloser, error) {
 	buf := &writeBuffer{
 		fs:   m,
@@ -128,6 +249,9 @@ type memDirEntry struct {
 func (e *memDirEntry) Name() string               { return e.f.name }
 func (e *memDirEntry) IsDir() bool                { return e.f.isDir }
 func (e *memDirEntry) Type() fs.FileMode          { 
+	if e.f.link != "" {
+		return fs.ModeSymlink
+	}
 	if e.f.isDir {
 		return fs.ModeDir
 	}
@@ -144,6 +268,9 @@ type memFileInfo struct {
 func (i *memFileInfo) Name() string       { return i.f.name }
 func (i *memFileInfo) Size() int64        { return int64(len(i.f.content)) }
 func (i *memFileInfo) Mode() fs.FileMode  { 
+	if i.f.link != "" {
+		return fs.ModeSymlink | 0777
+	}
 	if i.f.isDir {
 		return fs.ModeDir | 0755
 	}
@@ -176,9 +303,9 @@ func (w *writeBuffer) Close() error {
 
 // ReadFile reads the entire contents of a file
 func (m *MemFileSystem) ReadFile(path string) ([]byte, error) {
-	f, exists := m.files[path]
-	if !exists {
-		return nil, fs.ErrNotExist
+	f, err := m.lookup(path)
+	if err != nil {
+		return nil, err
 	}
 	if f.isDir {
 		return nil, &fs.PathError{Op: "read", Path: path, Err: fs.ErrInvalid}
@@ -225,4 +352,4 @@ func (m *MemFileSystem) MkdirAll(path string, perm fs.FileMode) error {
 	return nil
 }
 
-var _ domain.FileSystem = (*MemFileSystem)(nil)
\ No newline at end of file
+var _ domain.LinkFileSystem = (*MemFileSystem)(nil)
\ No newline at end of file
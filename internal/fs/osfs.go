@@ -10,6 +10,8 @@ import (
 // OSFileSystem implements domain.FileSystem using the real OS
 type OSFileSystem struct{}
 
+var _ domain.LinkFileSystem = (*OSFileSystem)(nil)
+
 // NewOSFileSystem creates a new OS-based filesystem
 func NewOSFileSystem() domain.FileSystem {
 	return &OSFileSystem{}
@@ -27,6 +29,14 @@ func (f *OSFileSystem) Stat(path string) (fs.FileInfo, error) {
 	return os.Stat(path)
 }
 
+func (f *OSFileSystem) Lstat(path string) (fs.FileInfo, error) {
+	return os.Lstat(path)
+}
+
+func (f *OSFileSystem) Readlink(path string) (string, error) {
+	return os.Readlink(path)
+}
+
 func (f *OSFileSystem) Create(path string) (io.WriteCloser, error) {
 	return os.Create(path)
 }
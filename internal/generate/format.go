@@ -0,0 +1,489 @@
+package generate
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/eliooooooot/picky/internal/domain"
+	"github.com/eliooooooot/picky/internal/token"
+)
+
+// Format names an output format
+type Format string
+
+const (
+	FormatText Format = "text"
+	FormatXML  Format = "xml"
+)
+
+// Formats lists the supported output formats
+var Formats = []Format{FormatText, FormatXML}
+
+// ParseFormat validates a format name; the empty string means text
+func ParseFormat(name string) (Format, error) {
+	switch f := Format(strings.ToLower(name)); f {
+	case "":
+		return FormatText, nil
+	case FormatText, FormatXML:
+		return f, nil
+	}
+	names := make([]string, len(Formats))
+	for i, f := range Formats {
+		names[i] = string(f)
+	}
+	return "", fmt.Errorf("unknown output format %q (want one of %s)", name, strings.Join(names, ", "))
+}
+
+// LargeFileMode says how much of a file over the size limit is written
+type LargeFileMode string
+
+const (
+	// LargeFileHead keeps the first lines of the file
+	LargeFileHead LargeFileMode = "head"
+	// LargeFileHeadTail keeps lines from both ends, half from each
+	LargeFileHeadTail LargeFileMode = "head-tail"
+	// LargeFileSkip leaves the contents out entirely
+	LargeFileSkip LargeFileMode = "skip"
+)
+
+// ParseLargeFileMode validates a large file mode; the empty string means head
+func ParseLargeFileMode(name string) (LargeFileMode, error) {
+	switch m := LargeFileMode(strings.ToLower(name)); m {
+	case "":
+		return LargeFileHead, nil
+	case LargeFileHead, LargeFileHeadTail, LargeFileSkip:
+		return m, nil
+	}
+	return "", fmt.Errorf("unknown large file mode %q (want head, head-tail or skip)", name)
+}
+
+// formatWriter is implemented by every output format. Besides the public
+// OutputWriter methods it exposes the pieces needed to assemble parts.
+type formatWriter interface {
+	domain.OutputWriter
+	writePrompt(w io.Writer, prompt string) error
+	writePartHeader(w io.Writer, n, total int) error
+	writeContentHeader(w io.Writer) error
+	writeContentFooter(w io.Writer) error
+	writeBlock(w io.Writer, b block) error
+	readContent(path string, fs domain.FileSystem) ([]byte, int, []int, error)
+	lineRanges(path string) []domain.LineRange
+	skippedKind(path string, content []byte) domain.FileKind
+	redact(path string, content []byte) []byte
+	transform(path string, content []byte) []byte
+	mode(path string) domain.InclusionMode
+	diff(path string) ([]byte, error)
+	head(content []byte) ([]byte, int)
+	truncate(path string, content []byte) ([]byte, int)
+	lineNumbers() bool
+	tokenizer() token.Tokenizer
+	setReader(r fileReader)
+}
+
+// block is one file, or a line range of one file, ready to be written
+type block struct {
+	Path    string
+	Content []byte
+	// Omitted is the number of trailing lines dropped by truncation
+	Omitted int
+	// Start and End are 1-based line numbers when the block is a range of the file
+	Start, End int
+	// Mode is how much of the file the block holds; empty means full
+	Mode domain.InclusionMode
+	// Numbers holds the file's line number for each line of Content, or 0
+	// for lines not taken from the file; nil writes the content unnumbered
+	Numbers []int
+}
+
+// newWriter creates the writer for the format in opts, configured from the view state
+func newWriter(state domain.ViewState, opts Options) formatWriter {
+	var fw formatWriter
+	switch opts.Format {
+	case FormatXML:
+		fw = NewXMLWriter()
+	default:
+		fw = NewTextWriter()
+	}
+
+	r := fileReader{
+		Truncate:       state.Truncated,
+		Lines:          state.Lines,
+		Forced:         state.Forced,
+		Modes:          state.Modes,
+		OutlineAll:     opts.Outline,
+		Diff:           opts.Diff,
+		Tokenizer:      opts.Tokenizer,
+		MaxFileSize:    opts.MaxFileSize,
+		LargeFiles:     opts.LargeFiles,
+		LargeFileLines: opts.LargeFileLines,
+		Redact:         opts.Redact,
+		Transforms:     opts.Transforms,
+		LineNumbers:    opts.LineNumbers,
+		Structure:      opts.Structure,
+		StructureDepth: opts.StructureDepth,
+		FileTokens:     opts.FileTokens,
+	}
+	if r.Tokenizer == nil {
+		r.Tokenizer = token.NaiveTokenizer{}
+	}
+	fw.setReader(r)
+	return fw
+}
+
+// fileReader reads file contents for writers, applying truncation, and
+// holds the settings writers share for the directory structure
+type fileReader struct {
+	// Truncate caps the tokens written per file path
+	Truncate map[string]int
+	// Lines limits files to some of their line ranges
+	Lines map[string][]domain.LineRange
+	// Forced lists binary or generated files written in full anyway
+	Forced map[string]bool
+	// Modes says how much of each file is written; OutlineAll outlines
+	// every file without a mode or line ranges
+	Modes      map[string]domain.InclusionMode
+	OutlineAll bool
+	// Diff returns a file's uncommitted changes for the diff mode
+	Diff func(path string) ([]byte, error)
+	// Tokenizer measures content when truncating
+	Tokenizer token.Tokenizer
+	// MaxFileSize, LargeFiles and LargeFileLines limit files over the size
+	// limit that are not forced; see Options
+	MaxFileSize    int
+	LargeFiles     LargeFileMode
+	LargeFileLines int
+	// Redact replaces secrets found in file contents; see Redact
+	Redact bool
+	// Transforms shrink file contents; see Transforms
+	Transforms Transforms
+	// LineNumbers prefixes content lines with their line numbers in the file
+	LineNumbers bool
+	// Structure, StructureDepth and FileTokens shape the directory
+	// structure; see Options
+	Structure      StructureMode
+	StructureDepth int
+	FileTokens     map[string]int
+}
+
+func (r fileReader) tokenizer() token.Tokenizer {
+	return r.Tokenizer
+}
+
+func (r *fileReader) setReader(o fileReader) {
+	*r = o
+}
+
+func (r fileReader) lineRanges(path string) []domain.LineRange {
+	return r.Lines[path]
+}
+
+// skippedKind returns the kind of a binary or generated file that is left
+// out of the output, or KindText if the file is written
+func (r fileReader) skippedKind(path string, content []byte) domain.FileKind {
+	if r.Forced[path] {
+		return domain.KindText
+	}
+	return domain.DetectKind(filepath.Base(path), content)
+}
+
+// redact replaces secrets in content when redaction is on
+func (r fileReader) redact(path string, content []byte) []byte {
+	if !r.Redact {
+		return content
+	}
+	content, _ = Redact(path, content)
+	return content
+}
+
+// transform applies the configured content transforms
+func (r fileReader) transform(path string, content []byte) []byte {
+	return r.Transforms.Apply(path, content)
+}
+
+// mode returns how much of a file is written. A mode or line ranges chosen
+// for the file win over the global outline setting.
+func (r fileReader) mode(path string) domain.InclusionMode {
+	if mode, ok := r.Modes[path]; ok {
+		return mode
+	}
+	if r.OutlineAll && len(r.Lines[path]) == 0 {
+		return domain.IncludeOutline
+	}
+	return domain.IncludeFull
+}
+
+func (r fileReader) lineNumbers() bool {
+	return r.LineNumbers
+}
+
+// diff returns a file's uncommitted changes
+func (r fileReader) diff(path string) ([]byte, error) {
+	if r.Diff == nil {
+		return nil, errors.New("diffs are not available")
+	}
+	return r.Diff(path)
+}
+
+// headLines is how many lines the head mode and large files keep when
+// LargeFileLines is unset
+const headLines = 200
+
+// keepLines is how many lines the head mode and large files keep
+func (r fileReader) keepLines() int {
+	if r.LargeFileLines <= 0 {
+		return headLines
+	}
+	return r.LargeFileLines
+}
+
+// head keeps the first lines of content for the head mode. It returns the
+// kept content and the number of lines omitted.
+func (r fileReader) head(content []byte) ([]byte, int) {
+	n := r.keepLines()
+	lines := splitLines(content)
+	if len(lines) <= n {
+		return content, 0
+	}
+	return []byte(strings.Join(lines[:n], "")), len(lines) - n
+}
+
+// truncate cuts content to the token limit set for the file. It returns the
+// kept content and the number of lines omitted.
+func (r fileReader) truncate(path string, content []byte) ([]byte, int) {
+	if limit, ok := r.Truncate[path]; ok {
+		return truncateToTokens(content, limit, r.Tokenizer)
+	}
+	return content, 0
+}
+
+// readContent reads a file and applies any truncation configured for it
+// when it is written in full; other modes truncate what they make of it.
+// Files over the size limit are checked with Stat and never read whole. The
+// line numbers returned are nil unless lines were dropped from the middle of
+// the file, so that the kept lines are not numbered from 1.
+func (r fileReader) readContent(path string, fs domain.FileSystem) ([]byte, int, []int, error) {
+	if r.MaxFileSize > 0 && !r.Forced[path] {
+		info, err := fs.Stat(path)
+		if err != nil {
+			return nil, 0, nil, err
+		}
+		if info.Size() > int64(r.MaxFileSize) {
+			return r.readLarge(path, fs)
+		}
+	}
+
+	f, err := fs.Open(path)
+	if err != nil {
+		return nil, 0, nil, err
+	}
+	content, err := io.ReadAll(f)
+	f.Close()
+	if err != nil {
+		return nil, 0, nil, err
+	}
+
+	omitted := 0
+	if r.mode(path) == domain.IncludeFull {
+		content, omitted = r.truncate(path, content)
+	}
+	return content, omitted, nil, nil
+}
+
+// readLarge streams a file over the size limit, keeping only the lines its
+// mode allows. It returns the kept content and the number of lines omitted;
+// in head-tail mode the omission marker sits between the two ends instead,
+// and the line numbers of the kept lines are returned with it.
+func (r fileReader) readLarge(path string, fs domain.FileSystem) ([]byte, int, []int, error) {
+	f, err := fs.Open(path)
+	if err != nil {
+		return nil, 0, nil, err
+	}
+	defer f.Close()
+
+	keep := r.keepLines()
+	headLen, tailLen := keep, 0
+	switch r.LargeFiles {
+	case LargeFileSkip:
+		headLen = 0
+	case LargeFileHeadTail:
+		headLen, tailLen = (keep+1)/2, keep/2
+	}
+
+	var head, tail []string
+	total := 0
+	br := bufio.NewReader(f)
+	for {
+		line, err := br.ReadString('\n')
+		if line != "" {
+			total++
+			switch {
+			case len(head) < headLen:
+				head = append(head, line)
+			case tailLen > 0:
+				tail = append(tail, line)
+				if len(tail) > tailLen {
+					tail = tail[1:]
+				}
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, 0, nil, err
+		}
+	}
+
+	omitted := total - len(head) - len(tail)
+	if tailLen == 0 || omitted == 0 {
+		return []byte(strings.Join(append(head, tail...), "")), omitted, nil, nil
+	}
+	marker := fmt.Sprintf("[truncated: %d lines omitted]\n", omitted)
+	numbers := append(sequentialLines(1, len(head)), 0)
+	numbers = append(numbers, sequentialLines(total-len(tail)+1, len(tail))...)
+	return []byte(strings.Join(head, "") + marker + strings.Join(tail, "")), 0, numbers, nil
+}
+
+// writeFileBlock reads a file and writes it as a single block, or one block
+// per selected line range, reporting read errors inside the block rather
+// than aborting the output
+func writeFileBlock(fw formatWriter, w io.Writer, path string, fs domain.FileSystem) error {
+	for _, b := range fileBlocks(fw, path, fs) {
+		if err := fw.writeBlock(w, b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fileBlocks reads a file into the blocks it is written as, following its
+// inclusion mode; path-only files have none. Binary and generated files that
+// are not forced become a one-line placeholder. Line ranges are cut before
+// transforms so they keep the file's numbering, and when line numbers are on
+// each block carries the numbers its lines had in the file.
+func fileBlocks(fw formatWriter, path string, fs domain.FileSystem) []block {
+	mode := fw.mode(path)
+	if mode == domain.IncludePath {
+		return nil
+	}
+	if mode == domain.IncludeDiff {
+		return diffBlocks(fw, path)
+	}
+
+	content, omitted, numbers, err := fw.readContent(path, fs)
+	if err != nil {
+		return []block{{Path: path, Content: []byte(fmt.Sprintf("Error reading file: %v\n", err))}}
+	}
+	if kind := fw.skippedKind(path, content); kind != domain.KindText {
+		return []block{{Path: path, Content: []byte(fmt.Sprintf("[omitted: %s file]\n", kind))}}
+	}
+	content = fw.redact(path, content)
+	lines := splitLines(content)
+	if numbers == nil {
+		numbers = sequentialLines(1, len(lines))
+	}
+	// number follows the line numbers of src through what was made of it
+	number := func(b block, src []byte, srcNumbers []int) block {
+		if fw.lineNumbers() {
+			b.Numbers = mapLines(src, srcNumbers, b.Content)
+		}
+		return b
+	}
+
+	// Token limits apply to what the mode writes, so a budget fitted to
+	// the mode's counts holds
+	switch mode {
+	case domain.IncludeOutline:
+		out, cut := fw.truncate(path, fw.transform(path, Outline(path, content)))
+		b := block{Path: path, Content: out, Omitted: omitted + cut, Mode: mode}
+		return []block{number(b, content, numbers)}
+	case domain.IncludeHead:
+		head, more := fw.head(content)
+		out, cut := fw.truncate(path, fw.transform(path, head))
+		b := block{Path: path, Content: out, Omitted: omitted + more + cut, Mode: mode}
+		return []block{number(b, head, numbers[:len(splitLines(head))])}
+	}
+	ranges := fw.lineRanges(path)
+	if len(ranges) == 0 {
+		return []block{number(block{Path: path, Content: fw.transform(path, content), Omitted: omitted}, content, numbers)}
+	}
+
+	var blocks []block
+	for _, r := range ranges {
+		// Ranges are in file lines, found through the line numbers since
+		// truncation may have dropped some of them, or the middle of the file
+		first, last := -1, -1
+		for i, n := range numbers {
+			if n >= r.Start && n <= r.End {
+				if first < 0 {
+					first = i
+				}
+				last = i
+			}
+		}
+		if first < 0 {
+			continue
+		}
+		src := []byte(strings.Join(lines[first:last+1], ""))
+		b := block{
+			Path:    path,
+			Content: fw.transform(path, src),
+			Start:   numbers[first],
+			End:     numbers[last],
+		}
+		blocks = append(blocks, number(b, src, numbers[first:last+1]))
+	}
+	return blocks
+}
+
+// diffBlocks writes a file's uncommitted changes as one block. Diffs are
+// redacted and truncated but not transformed, since transforms expect
+// source code.
+func diffBlocks(fw formatWriter, path string) []block {
+	diff, err := fw.diff(path)
+	omitted := 0
+	switch {
+	case err != nil:
+		diff = []byte(fmt.Sprintf("Error reading diff: %v\n", err))
+	case len(diff) == 0:
+		diff = []byte("[no changes]\n")
+	default:
+		diff, omitted = fw.truncate(path, fw.redact(path, diff))
+	}
+	return []block{{Path: path, Content: diff, Omitted: omitted, Mode: domain.IncludeDiff}}
+}
+
+// truncateToTokens keeps whole lines from the top of content while they fit
+// within limit tokens. It returns the kept content and the number of lines omitted.
+func truncateToTokens(content []byte, limit int, tz token.Tokenizer) ([]byte, int) {
+	lines := splitLines(content)
+
+	used := 0
+	kept := 0
+	for _, line := range lines {
+		n := tz.CountTokens(line)
+		if used+n > limit {
+			break
+		}
+		used += n
+		kept++
+	}
+
+	if kept == len(lines) {
+		return content, 0
+	}
+	return []byte(strings.Join(lines[:kept], "")), len(lines) - kept
+}
+
+// splitLines splits content into lines, each keeping its trailing newline
+func splitLines(content []byte) []string {
+	lines := strings.SplitAfter(string(content), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
@@ -1,36 +1,95 @@
 package generate
 
 import (
+	"bytes"
 	"fmt"
+	"io"
 	"github.com/eliooooooot/picky/internal/domain"
+	"github.com/eliooooooot/picky/internal/token"
 )
 
+// Options controls how output is rendered and laid out
+type Options struct {
+	// Format selects the output format; empty means text
+	Format Format
+	// MaxTokensPerPart splits the output into numbered part files when positive
+	MaxTokensPerPart int
+	// Tokenizer measures truncation and parts; defaults to token.NaiveTokenizer
+	Tokenizer token.Tokenizer
+	// MaxFileSize is the size in bytes above which LargeFiles applies; zero or less disables it
+	MaxFileSize int
+	// LargeFiles decides how much of a file over MaxFileSize is written
+	LargeFiles LargeFileMode
+	// LargeFileLines is how many lines the head and head-tail modes keep; zero or less means 200
+	LargeFileLines int
+	// Redact replaces credentials found in file contents with [REDACTED:kind]
+	Redact bool
+	// Transforms strip comments, blank lines and license headers from file contents
+	Transforms Transforms
+	// Outline writes every file without line ranges as an outline; see Outline
+	Outline bool
+	// Diff returns a file's uncommitted changes for files in the diff mode
+	Diff func(path string) ([]byte, error)
+	// LineNumbers prefixes each line of file contents with its line number
+	// in the file, so ranges, truncated files and transformed files can still
+	// be referred back to
+	LineNumbers bool
+	// Structure says how much of the tree the directory structure lists;
+	// empty means the full tree
+	Structure StructureMode
+	// StructureDepth is how many levels the collapsed structure lists
+	StructureDepth int
+	// FileTokens are known per-file token counts, shown next to selected
+	// files in the directory structure
+	FileTokens map[string]int
+}
+
 // Generate creates the output file with selected files using TextWriter
 func Generate(outPath, prompt string, tree *domain.Tree, state domain.ViewState, fs domain.FileSystem) error {
+	return generateFile(outPath, prompt, tree, state, fs, Options{})
+}
+
+func generateFile(outPath, prompt string, tree *domain.Tree, state domain.ViewState, fs domain.FileSystem, opts Options) error {
 	w, err := fs.Create(outPath)
 	if err != nil {
 		return fmt.Errorf("create output file: %w", err)
 	}
 	defer w.Close()
 	
+	return RenderWithOptions(w, prompt, tree, state, fs, opts)
+}
+
+// FileContent returns a file's content as RenderWithOptions would write it,
+// after redaction, its inclusion mode and transforms, without block titles
+func FileContent(path string, state domain.ViewState, fs domain.FileSystem, opts Options) []byte {
+	var buf bytes.Buffer
+	for _, b := range fileBlocks(newWriter(state, opts), path, fs) {
+		buf.Write(b.Content)
+	}
+	return buf.Bytes()
+}
+
+// Render writes the prompt, directory structure and selected file contents to w
+func Render(w io.Writer, prompt string, tree *domain.Tree, state domain.ViewState, fs domain.FileSystem) error {
+	return RenderWithOptions(w, prompt, tree, state, fs, Options{})
+}
+
+// RenderWithOptions is like Render but writes in the format chosen by opts
+func RenderWithOptions(w io.Writer, prompt string, tree *domain.Tree, state domain.ViewState, fs domain.FileSystem, opts Options) error {
+	writer := newWriter(state, opts)
+	
 	// Write prompt first if non-empty
-	if prompt != "" {
-		fmt.Fprintln(w, "# Prompt")
-		fmt.Fprintln(w)
-		fmt.Fprintln(w, prompt)
-		fmt.Fprintln(w) // extra blank line
+	if err := writer.writePrompt(w, prompt); err != nil {
+		return err
 	}
 	
 	// Get all selected paths
 	paths := domain.GetSelectedPaths(tree.Root, state)
 	if len(paths) == 0 {
-		_, err = w.Write([]byte("No files selected\n"))
+		_, err := w.Write([]byte("No files selected\n"))
 		return err
 	}
 	
-	// Use TextWriter for output
-	writer := NewTextWriter()
-	
 	// Write directory structure
 	if err := writer.WriteStructure(w, tree.Root, state); err != nil {
 		return err
@@ -42,4 +101,4 @@ func Generate(outPath, prompt string, tree *domain.Tree, state domain.ViewState,
 	}
 	
 	return nil
-}
\ No newline at end of file
+}
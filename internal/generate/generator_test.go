@@ -1,6 +1,7 @@
 package generate_test
 
 import (
+	"fmt"
 	"github.com/eliooooooot/picky/internal/domain"
 	"github.com/eliooooooot/picky/internal/fs"
 	"github.com/eliooooooot/picky/internal/generate"
@@ -227,4 +228,252 @@ func TestTextWriter(t *testing.T) {
 	if !strings.Contains(content, "test content") {
 		t.Error("Content should contain file content")
 	}
-}
\ No newline at end of file
+}
+func TestGenerateHonoursTruncation(t *testing.T) {
+	memfs := fs.NewMemFileSystem()
+	memfs.AddDir("/root")
+	memfs.AddFile("/root/long.txt", "line one\nline two\nline three\nline four\n")
+	
+	tree, err := domain.BuildTree(memfs, "/root")
+	if err != nil {
+		t.Fatalf("BuildTree failed: %v", err)
+	}
+	
+	// "line one\n" and "line two\n" are 3 naive tokens each
+	state := domain.NewViewState(tree.Root.Path)
+	state = state.SetSelected("/root/long.txt", true)
+	state = state.SetTruncated("/root/long.txt", 6)
+	
+	if err := generate.Generate("/output.txt", "", tree, state, memfs); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	
+	content, err := memfs.GetContent("/output.txt")
+	if err != nil {
+		t.Fatalf("Failed to read output: %v", err)
+	}
+	
+	if !strings.Contains(content, "line one\nline two\n[truncated: 2 lines omitted]\n") {
+		t.Errorf("Output should keep the first two lines and mark the rest, got:\n%s", content)
+	}
+	if strings.Contains(content, "line three") {
+		t.Error("Output should not contain truncated lines")
+	}
+}
+
+func TestGenerateHonoursLineRanges(t *testing.T) {
+	memfs := fs.NewMemFileSystem()
+	memfs.AddDir("/root")
+	memfs.AddFile("/root/long.txt", "one\ntwo\nthree\nfour\nfive\n")
+	
+	tree, err := domain.BuildTree(memfs, "/root")
+	if err != nil {
+		t.Fatalf("BuildTree failed: %v", err)
+	}
+	
+	state := domain.NewViewState(tree.Root.Path)
+	state = state.SetSelected("/root/long.txt", true)
+	state = state.SetLineRanges("/root/long.txt", []domain.LineRange{{Start: 2, End: 2}, {Start: 4, End: 9}})
+	
+	if err := generate.Generate("/output.txt", "", tree, state, memfs); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	
+	content, err := memfs.GetContent("/output.txt")
+	if err != nil {
+		t.Fatalf("Failed to read output: %v", err)
+	}
+	
+	if !strings.Contains(content, "## long.txt (lines 2-2)\n\n```\ntwo\n```") {
+		t.Errorf("Output should contain the first range, got:\n%s", content)
+	}
+	if !strings.Contains(content, "## long.txt (lines 4-5)\n\n```\nfour\nfive\n```") {
+		t.Errorf("Output should clamp the second range to the file, got:\n%s", content)
+	}
+	if strings.Contains(content, "three") || strings.Contains(content, "one") {
+		t.Error("Output should leave out lines outside the ranges")
+	}
+}
+
+func TestGenerateOmitsBinaryAndGeneratedFiles(t *testing.T) {
+	memfs := fs.NewMemFileSystem()
+	memfs.AddDir("/root")
+	memfs.AddFile("/root/logo.png", "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	memfs.AddFile("/root/api.pb.go", "// Code generated by protoc-gen-go. DO NOT EDIT.\n\npackage api\n")
+	memfs.AddFile("/root/main.go", "package main\n")
+	
+	tree, err := domain.BuildTree(memfs, "/root")
+	if err != nil {
+		t.Fatalf("BuildTree failed: %v", err)
+	}
+	
+	state := domain.NewViewState(tree.Root.Path)
+	state = state.SetSelected("/root", true)
+	for _, path := range []string{"/root/logo.png", "/root/api.pb.go", "/root/main.go"} {
+		state = state.SetSelected(path, true)
+	}
+	
+	var out strings.Builder
+	if err := generate.Render(&out, "", tree, state, memfs); err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	content := out.String()
+	
+	if !strings.Contains(content, "## logo.png\n\n```\n[omitted: binary file]\n```") {
+		t.Errorf("Output should replace the binary file with a placeholder, got:\n%s", content)
+	}
+	if !strings.Contains(content, "## api.pb.go\n\n```\n[omitted: generated file]\n```") {
+		t.Errorf("Output should replace the generated file with a placeholder, got:\n%s", content)
+	}
+	if strings.Contains(content, "IHDR") || strings.Contains(content, "package api") {
+		t.Error("Output should not contain skipped file contents")
+	}
+	if !strings.Contains(content, "package main") {
+		t.Error("Output should contain ordinary files")
+	}
+	
+	// Forcing a file writes it in full
+	out.Reset()
+	state = state.SetForced("/root/api.pb.go", true)
+	if err := generate.Render(&out, "", tree, state, memfs); err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	if !strings.Contains(out.String(), "package api") {
+		t.Errorf("Output should contain the forced file, got:\n%s", out.String())
+	}
+}
+
+func TestRenderLargeFilePolicies(t *testing.T) {
+	memfs := fs.NewMemFileSystem()
+	memfs.AddDir("/root")
+	memfs.AddFile("/root/app.log", "l1\nl2\nl3\nl4\nl5\nl6\nl7\nl8\nl9\nl10\n")
+	memfs.AddFile("/root/small.txt", "tiny\n")
+	
+	tree, err := domain.BuildTree(memfs, "/root")
+	if err != nil {
+		t.Fatalf("BuildTree failed: %v", err)
+	}
+	state := domain.NewViewState(tree.Root.Path)
+	state = state.SetSelected("/root/app.log", true)
+	state = state.SetSelected("/root/small.txt", true)
+	
+	tests := []struct {
+		mode generate.LargeFileMode
+		want string
+	}{
+		{generate.LargeFileHead, "## app.log\n\n```\nl1\nl2\nl3\nl4\n[truncated: 6 lines omitted]\n```"},
+		{generate.LargeFileHeadTail, "## app.log\n\n```\nl1\nl2\n[truncated: 6 lines omitted]\nl9\nl10\n```"},
+		{generate.LargeFileSkip, "[truncated: 10 lines omitted]\n```"},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.mode), func(t *testing.T) {
+			var out strings.Builder
+			opts := generate.Options{MaxFileSize: 16, LargeFiles: tt.mode, LargeFileLines: 4}
+			if err := generate.RenderWithOptions(&out, "", tree, state, memfs, opts); err != nil {
+				t.Fatalf("Render failed: %v", err)
+			}
+			if !strings.Contains(out.String(), tt.want) {
+				t.Errorf("Output should contain %q, got:\n%s", tt.want, out.String())
+			}
+			if !strings.Contains(out.String(), "tiny") {
+				t.Error("Files under the limit should be written in full")
+			}
+		})
+	}
+	
+	// Forced files are written in full regardless of size
+	var out strings.Builder
+	state = state.SetForced("/root/app.log", true)
+	opts := generate.Options{MaxFileSize: 16, LargeFiles: generate.LargeFileSkip}
+	if err := generate.RenderWithOptions(&out, "", tree, state, memfs, opts); err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	if !strings.Contains(out.String(), "l5\nl6\n") || strings.Contains(out.String(), "truncated") {
+		t.Errorf("Output should contain the whole forced file, got:\n%s", out.String())
+	}
+}
+
+func TestRenderLargeFileDefaultsAndRanges(t *testing.T) {
+	memfs := fs.NewMemFileSystem()
+	memfs.AddDir("/root")
+	var content strings.Builder
+	for i := 1; i <= 500; i++ {
+		fmt.Fprintf(&content, "l%d\n", i)
+	}
+	memfs.AddFile("/root/app.log", content.String())
+
+	tree, err := domain.BuildTree(memfs, "/root")
+	if err != nil {
+		t.Fatalf("BuildTree failed: %v", err)
+	}
+	state := domain.NewViewState(tree.Root.Path).SetSelected("/root/app.log", true)
+
+	// Without LargeFileLines head-tail keeps the default 200 lines, not none
+	var out strings.Builder
+	opts := generate.Options{MaxFileSize: 16, LargeFiles: generate.LargeFileHeadTail}
+	if err := generate.RenderWithOptions(&out, "", tree, state, memfs, opts); err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	if !strings.Contains(out.String(), "\nl100\n[truncated: 300 lines omitted]\nl401\n") {
+		t.Errorf("Output should keep 100 lines from each end, got:\n%s", out.String())
+	}
+
+	// Ranges are file lines, not lines of the head-tail content
+	state = state.SetLineRanges("/root/app.log", []domain.LineRange{{Start: 450, End: 451}, {Start: 99, End: 402}, {Start: 200, End: 300}})
+	out.Reset()
+	if err := generate.RenderWithOptions(&out, "", tree, state, memfs, opts); err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	for _, want := range []string{
+		"## app.log (lines 450-451)\n\n```\nl450\nl451\n```",
+		"## app.log (lines 99-402)\n\n```\nl99\nl100\n[truncated: 300 lines omitted]\nl401\nl402\n```",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("Output should contain %q, got:\n%s", want, out.String())
+		}
+	}
+	if strings.Contains(out.String(), "lines 200-300") {
+		t.Error("A range entirely in the omitted middle should be left out")
+	}
+}
+
+func TestRenderXMLFormat(t *testing.T) {
+	memfs := fs.NewMemFileSystem()
+	memfs.AddDir("/root")
+	memfs.AddFile("/root/a.go", "package a\n")
+	
+	tree, err := domain.BuildTree(memfs, "/root")
+	if err != nil {
+		t.Fatalf("BuildTree failed: %v", err)
+	}
+	state := domain.NewViewState(tree.Root.Path)
+	state = state.SetSelected("/root/a.go", true)
+	
+	var buf strings.Builder
+	opts := generate.Options{Format: generate.FormatXML}
+	if err := generate.RenderWithOptions(&buf, "explain", tree, state, memfs, opts); err != nil {
+		t.Fatalf("RenderWithOptions failed: %v", err)
+	}
+	out := buf.String()
+	
+	for _, want := range []string{"<prompt>\nexplain\n</prompt>", "<directory_structure>", "<files>", "<file name=\"a.go\">\npackage a\n</file>", "</files>"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("XML output missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "```") {
+		t.Error("XML output should not contain markdown fences")
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	if f, err := generate.ParseFormat(""); err != nil || f != generate.FormatText {
+		t.Errorf("empty format = %q, %v; want text", f, err)
+	}
+	if f, err := generate.ParseFormat("XML"); err != nil || f != generate.FormatXML {
+		t.Errorf("XML format = %q, %v; want xml", f, err)
+	}
+	if _, err := generate.ParseFormat("pdf"); err == nil {
+		t.Error("expected error for unknown format")
+	}
+}
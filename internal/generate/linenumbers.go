@@ -0,0 +1,87 @@
+package generate
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// sequentialLines numbers n lines from first onwards
+func sequentialLines(first, n int) []int {
+	numbers := make([]int, n)
+	for i := range numbers {
+		numbers[i] = first + i
+	}
+	return numbers
+}
+
+// mapLines follows line numbers through a change from src to out that only
+// drops lines, cuts their ends off or changes their spacing, as transforms
+// and outlines do. Each line of out takes the number of the first line of
+// src from where the last match left off that it equals or starts, ignoring
+// white space. Matching whole lines from their start keeps a dropped
+// comment that quotes code from taking that code's number. Lines made up by
+// the change, such as "..." in outlines, get 0 and are written unnumbered.
+func mapLines(src []byte, numbers []int, out []byte) []int {
+	if bytes.Equal(src, out) {
+		return numbers
+	}
+	srcLines := splitLines(src)
+	squeezed := make([]string, len(srcLines))
+	for i, line := range srcLines {
+		squeezed[i] = squeeze(line)
+	}
+
+	outLines := splitLines(out)
+	mapped := make([]int, len(outLines))
+	next := 0
+	for i, line := range outLines {
+		want := squeeze(line)
+		for j := next; j < len(srcLines); j++ {
+			if want == "" && squeezed[j] != "" {
+				continue
+			}
+			if strings.HasPrefix(squeezed[j], want) {
+				mapped[i] = numbers[j]
+				next = j + 1
+				break
+			}
+		}
+	}
+	return mapped
+}
+
+// squeeze drops all white space from a line so reformatted lines still match
+func squeeze(line string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return -1
+		}
+		return r
+	}, line)
+}
+
+// numberedContent prefixes each line of a block's content with its line
+// number in the file, right-aligned to the widest number in the block.
+// Blocks without numbers are returned as they are.
+func numberedContent(b block) []byte {
+	if len(b.Numbers) == 0 {
+		return b.Content
+	}
+	width := len(fmt.Sprint(slices.Max(b.Numbers)))
+	var buf bytes.Buffer
+	for i, line := range splitLines(b.Content) {
+		prefix := strings.Repeat(" ", width) + " |"
+		if i < len(b.Numbers) && b.Numbers[i] > 0 {
+			prefix = fmt.Sprintf("%*d |", width, b.Numbers[i])
+		}
+		// Blank lines get no trailing space after the bar
+		if strings.TrimRight(line, "\r\n") != "" {
+			prefix += " "
+		}
+		buf.WriteString(prefix + line)
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,103 @@
+package generate_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/eliooooooot/picky/internal/domain"
+	"github.com/eliooooooot/picky/internal/fs"
+	"github.com/eliooooooot/picky/internal/generate"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func numberedLines(n int) string {
+	var b strings.Builder
+	for i := 1; i <= n; i++ {
+		fmt.Fprintf(&b, "l%d\n", i)
+	}
+	return b.String()
+}
+
+func TestRenderLineNumbers(t *testing.T) {
+	memfs := fs.NewMemFileSystem()
+	memfs.AddDir("/root")
+	memfs.AddFile("/root/short.txt", "a\nb\n")
+	memfs.AddFile("/root/app.log", numberedLines(12))
+	memfs.AddFile("/root/range.txt", numberedLines(12))
+	memfs.AddFile("/root/main.go", "package main\n\n// Run starts the app\nfunc Run() {\n\t// set up\n\tx := 1 // one\n\t_ = x\n}\n")
+	memfs.AddFile("/root/deref.go", "package main\n\nfunc deref(x *int) int {\n\tif x == nil {\n\t\treturn 0\n\t}\n\t// otherwise return *x\n\t// and never the zero value\n\treturn *x\n}\n")
+
+	tree, err := domain.BuildTree(memfs, "/root")
+	require.NoError(t, err)
+	state := domain.NewViewState(tree.Root.Path)
+	for _, path := range []string{"/root/short.txt", "/root/app.log", "/root/range.txt", "/root/main.go", "/root/deref.go"} {
+		state = state.SetSelected(path, true)
+	}
+	state = state.SetLineRanges("/root/range.txt", []domain.LineRange{{Start: 9, End: 10}})
+
+	render := func(opts generate.Options) string {
+		var out strings.Builder
+		require.NoError(t, generate.RenderWithOptions(&out, "", tree, state, memfs, opts))
+		return out.String()
+	}
+
+	text := render(generate.Options{LineNumbers: true})
+	assert.Contains(t, text, "## short.txt\n\n```\n1 | a\n2 | b\n```")
+	assert.Contains(t, text, "```\n 1 | l1\n 2 | l2\n", "numbers are right-aligned to the widest")
+	assert.Contains(t, text, "## range.txt (lines 9-10)\n\n```\n 9 | l9\n10 | l10\n```", "ranges keep the file's numbering")
+
+	t.Run("truncation", func(t *testing.T) {
+		text := render(generate.Options{LineNumbers: true, MaxFileSize: 30, LargeFiles: generate.LargeFileHeadTail, LargeFileLines: 4})
+		assert.Contains(t, text, "## app.log\n\n```\n 1 | l1\n 2 | l2\n   | [truncated: 8 lines omitted]\n11 | l11\n12 | l12\n```")
+	})
+
+	t.Run("transforms", func(t *testing.T) {
+		transforms, err := generate.ParseTransforms([]string{"comments"})
+		require.NoError(t, err)
+		text := render(generate.Options{LineNumbers: true, Transforms: transforms})
+		assert.Contains(t, text, "1 | package main\n2 |\n3 | // Run starts the app\n4 | func Run() {\n6 | \tx := 1\n7 | \t_ = x\n8 | }\n",
+			"lines keep their numbers after comments are stripped")
+	})
+
+	t.Run("removed comments quoting code", func(t *testing.T) {
+		transforms, err := generate.ParseTransforms([]string{"doc-comments"})
+		require.NoError(t, err)
+		text := render(generate.Options{LineNumbers: true, Transforms: transforms})
+		assert.Contains(t, text, " 9 | \treturn *x\n10 | }\n",
+			"a dropped comment holding the next line's code does not take its number")
+	})
+
+	t.Run("outline", func(t *testing.T) {
+		text := render(generate.Options{LineNumbers: true, Outline: true})
+		assert.Contains(t, text, "3 | // Run starts the app\n4 | func Run()\n")
+	})
+
+	t.Run("xml", func(t *testing.T) {
+		text := render(generate.Options{LineNumbers: true, Format: generate.FormatXML})
+		assert.Contains(t, text, "<file name=\"short.txt\">\n1 | a\n2 | b\n</file>")
+	})
+
+	assert.NotContains(t, render(generate.Options{}), "1 | ", "line numbers are off by default")
+}
+
+func TestGenerateWithOptionsNumbersSplitFile(t *testing.T) {
+	memfs := fs.NewMemFileSystem()
+	memfs.AddDir("/root")
+	memfs.AddFile("/root/huge.txt", numberedLines(200))
+
+	tree, err := domain.BuildTree(memfs, "/root")
+	require.NoError(t, err)
+	state := domain.NewViewState(tree.Root.Path).SetSelected("/root/huge.txt", true)
+
+	written, err := generate.GenerateWithOptions("/selected.txt", "", tree, state, memfs,
+		generate.Options{MaxTokensPerPart: 300, LineNumbers: true})
+	require.NoError(t, err)
+	require.Greater(t, len(written), 1)
+
+	last, err := memfs.GetContent(written[len(written)-1])
+	require.NoError(t, err)
+	assert.Contains(t, last, "-200)\n")
+	assert.Contains(t, last, "200 | l200\n")
+}
@@ -0,0 +1,112 @@
+package generate_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/eliooooooot/picky/internal/domain"
+	"github.com/eliooooooot/picky/internal/fs"
+	"github.com/eliooooooot/picky/internal/generate"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func modesFixture(t *testing.T) (*fs.MemFileSystem, *domain.Tree, domain.ViewState) {
+	memfs := fs.NewMemFileSystem()
+	memfs.AddDir("/root")
+	memfs.AddFile("/root/log.txt", "l1\nl2\nl3\nl4\nl5\n")
+	memfs.AddFile("/root/changed.go", "package main\n")
+	memfs.AddFile("/root/clean.go", "package clean\n")
+	memfs.AddFile("/root/listed.txt", "not written\n")
+
+	tree, err := domain.BuildTree(memfs, "/root")
+	require.NoError(t, err)
+
+	state := domain.NewViewState(tree.Root.Path)
+	modes := map[string]domain.InclusionMode{
+		"/root/log.txt":    domain.IncludeHead,
+		"/root/changed.go": domain.IncludeDiff,
+		"/root/clean.go":   domain.IncludeDiff,
+		"/root/listed.txt": domain.IncludePath,
+	}
+	for path, mode := range modes {
+		state = state.SetSelected(path, true).SetMode(path, mode)
+	}
+	return memfs, tree, state
+}
+
+func TestRenderInclusionModes(t *testing.T) {
+	memfs, tree, state := modesFixture(t)
+	opts := generate.Options{
+		LargeFileLines: 2,
+		Diff: func(path string) ([]byte, error) {
+			if path == "/root/changed.go" {
+				return []byte("+package main\n"), nil
+			}
+			return nil, nil
+		},
+	}
+
+	var out strings.Builder
+	require.NoError(t, generate.RenderWithOptions(&out, "", tree, state, memfs, opts))
+	text := out.String()
+	assert.Contains(t, text, "## log.txt (head)\n\n```\nl1\nl2\n[truncated: 3 lines omitted]\n```")
+	assert.Contains(t, text, "## changed.go (diff)\n\n```\n+package main\n```")
+	assert.Contains(t, text, "## clean.go (diff)\n\n```\n[no changes]\n```")
+	assert.Contains(t, text, "listed.txt *", "path-only files are listed in the structure")
+	assert.NotContains(t, text, "## listed.txt")
+	assert.NotContains(t, text, "not written")
+
+	out.Reset()
+	opts.Format = generate.FormatXML
+	opts.Diff = nil
+	require.NoError(t, generate.RenderWithOptions(&out, "", tree, state, memfs, opts))
+	assert.Contains(t, out.String(), `<file name="log.txt" mode="head">`)
+	assert.Contains(t, out.String(), "Error reading diff: diffs are not available")
+	assert.NotContains(t, out.String(), `name="listed.txt"`)
+}
+
+func TestPartsSkipPathOnlyFiles(t *testing.T) {
+	memfs, tree, state := modesFixture(t)
+	opts := generate.Options{
+		MaxTokensPerPart: 1000,
+		Diff:             func(string) ([]byte, error) { return nil, errors.New("not a repository") },
+	}
+
+	written, err := generate.GenerateWithOptions("/selected.txt", "", tree, state, memfs, opts)
+	require.NoError(t, err)
+	content, err := memfs.GetContent(written[0])
+	require.NoError(t, err)
+	assert.NotContains(t, content, "not written")
+	assert.Contains(t, content, "Error reading diff: not a repository")
+}
+
+func TestTruncationAppliesToModeOutput(t *testing.T) {
+	memfs := fs.NewMemFileSystem()
+	memfs.AddDir("/root")
+	body := "\tprintln(\"a body long enough that truncating it first would leave no signatures\")\n"
+	memfs.AddFile("/root/funcs.go", "package main\n\nfunc A() {\n"+body+"}\n\nfunc B() {\n"+body+"}\n\nfunc C() {\n"+body+"}\n")
+	memfs.AddFile("/root/changed.go", "package main\n")
+
+	tree, err := domain.BuildTree(memfs, "/root")
+	require.NoError(t, err)
+	state := domain.NewViewState(tree.Root.Path).
+		SetSelected("/root/funcs.go", true).
+		SetMode("/root/funcs.go", domain.IncludeOutline).
+		SetTruncated("/root/funcs.go", 12).
+		SetSelected("/root/changed.go", true).
+		SetMode("/root/changed.go", domain.IncludeDiff).
+		SetTruncated("/root/changed.go", 2)
+	opts := generate.Options{
+		Diff: func(string) ([]byte, error) { return []byte("+one\n+two\n+three\n+four\n"), nil },
+	}
+
+	outline := string(generate.FileContent("/root/funcs.go", state, memfs, opts))
+	assert.Contains(t, outline, "func B()", "the limit cuts the outline, not the file it is made from")
+	assert.NotContains(t, outline, "func C()")
+
+	diff := string(generate.FileContent("/root/changed.go", state, memfs, opts))
+	assert.Contains(t, diff, "+one\n")
+	assert.NotContains(t, diff, "+four")
+}
@@ -0,0 +1,101 @@
+package generate
+
+import (
+	"bytes"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	gotoken "go/token"
+	"regexp"
+)
+
+// Outline reduces a file to its shape for architecture questions. Go files
+// keep their package clause, imports, declarations and function signatures
+// with doc comments, but no function bodies. Other languages picky knows keep
+// their top-level lines and declaration lines, with each run of dropped lines
+// shown as "...". Files in unknown languages, and Go files that do not parse,
+// fall back to the generic outline or are returned whole.
+func Outline(name string, content []byte) []byte {
+	lang := languageFor(name)
+	if lang == nil {
+		return content
+	}
+	if lang.goSource {
+		if out, err := goOutline(content); err == nil {
+			return out
+		}
+	}
+	return genericOutline(content)
+}
+
+// goOutline drops function bodies and the comments inside them
+func goOutline(content []byte) ([]byte, error) {
+	fset := gotoken.NewFileSet()
+	file, err := parser.ParseFile(fset, "", content, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+	comments := ast.NewCommentMap(fset, file, file.Comments)
+	for _, decl := range file.Decls {
+		if fn, ok := decl.(*ast.FuncDecl); ok {
+			fn.Body = nil
+		}
+	}
+	// Comments attached to nodes inside the bodies go with them
+	file.Comments = comments.Filter(file).Comments()
+
+	var buf bytes.Buffer
+	if err := format.Node(&buf, fset, file); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// declLine matches lines that declare a function, type or module in common
+// languages, after any modifiers
+var declLine = regexp.MustCompile(`^\s*(?:(?:export|pub(?:\([^)]*\))?|public|private|protected|internal|static|abstract|final|async|override|default|extern|unsafe|open|sealed|data)\s+)*(?:def|class|function|fn|func|interface|struct|enum|trait|impl|type|module|object|namespace|record|protocol|extension)\b`)
+
+// commentLine matches lines holding only a comment
+var commentLine = regexp.MustCompile(`^\s*(?://|#|/\*|\*|--)`)
+
+// genericOutline keeps unindented lines, declaration lines and the comment
+// lines directly above kept declarations
+func genericOutline(content []byte) []byte {
+	lines := bytes.SplitAfter(content, []byte("\n"))
+	keep := make([]bool, len(lines))
+	for i, line := range lines {
+		trimmed := bytes.TrimSpace(line)
+		if len(trimmed) == 0 {
+			continue
+		}
+		unindented := line[0] != ' ' && line[0] != '\t'
+		if !declLine.Match(line) && !(unindented && !commentLine.Match(line)) {
+			continue
+		}
+		keep[i] = true
+		// Keep the doc comment above the declaration
+		for j := i - 1; j >= 0 && commentLine.Match(lines[j]); j-- {
+			keep[j] = true
+		}
+	}
+
+	var out []byte
+	elided := false
+	for i, line := range lines {
+		switch {
+		case keep[i]:
+			out = append(out, line...)
+			elided = false
+		case len(bytes.TrimSpace(line)) == 0:
+			// Blank lines next to kept lines stay; those inside an elided run go
+			if (i > 0 && keep[i-1]) || (i+1 < len(lines) && keep[i+1]) {
+				out = append(out, line...)
+			}
+		case !elided:
+			indent := line[:len(line)-len(bytes.TrimLeft(line, " \t"))]
+			out = append(append(out, indent...), "...\n"...)
+			elided = true
+		}
+	}
+	return out
+}
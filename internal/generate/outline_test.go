@@ -0,0 +1,85 @@
+package generate_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/eliooooooot/picky/internal/domain"
+	"github.com/eliooooooot/picky/internal/fs"
+	"github.com/eliooooooot/picky/internal/generate"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const outlineGoSource = `// Package shapes draws shapes.
+package shapes
+
+import "math"
+
+// Circle is round.
+type Circle struct {
+	// R is the radius
+	R float64
+}
+
+// Area returns the area.
+func (c Circle) Area() float64 {
+	// pi r squared
+	return math.Pi * c.R * c.R
+}
+`
+
+func TestOutlineGo(t *testing.T) {
+	want := `// Package shapes draws shapes.
+package shapes
+
+import "math"
+
+// Circle is round.
+type Circle struct {
+	// R is the radius
+	R float64
+}
+
+// Area returns the area.
+func (c Circle) Area() float64
+`
+	assert.Equal(t, want, string(generate.Outline("shapes.go", []byte(outlineGoSource))))
+}
+
+func TestOutlineGeneric(t *testing.T) {
+	src := "import os\n\n# Greeter greets\nclass Greeter:\n    def greet(self, name):\n        x = 1\n        return x\n\n    def other(self):\n        pass\n"
+	want := "import os\n\n# Greeter greets\nclass Greeter:\n    def greet(self, name):\n        ...\n\n    def other(self):\n        ...\n"
+	assert.Equal(t, want, string(generate.Outline("greet.py", []byte(src))))
+
+	// Broken Go falls back to the generic outline
+	assert.Equal(t, "func broken( {\n\t...\n", string(generate.Outline("b.go", []byte("func broken( {\n\tx := 1\n"))))
+
+	// Files in unknown languages are kept whole
+	assert.Equal(t, "  a\n  b\n", string(generate.Outline("notes.txt", []byte("  a\n  b\n"))))
+}
+
+func TestRenderOutlinedFiles(t *testing.T) {
+	memfs := fs.NewMemFileSystem()
+	memfs.AddDir("/root")
+	memfs.AddFile("/root/shapes.go", outlineGoSource)
+	memfs.AddFile("/root/main.go", "package main\n\nfunc main() {\n\tprintln(1)\n}\n")
+
+	tree, err := domain.BuildTree(memfs, "/root")
+	require.NoError(t, err)
+	state := domain.NewViewState(tree.Root.Path)
+	state = state.SetSelected("/root/shapes.go", true)
+	state = state.SetSelected("/root/main.go", true)
+	state = state.SetMode("/root/shapes.go", domain.IncludeOutline)
+
+	var out strings.Builder
+	require.NoError(t, generate.RenderWithOptions(&out, "", tree, state, memfs, generate.Options{}))
+	assert.Contains(t, out.String(), "## shapes.go (outline)\n")
+	assert.NotContains(t, out.String(), "pi r squared")
+	assert.Contains(t, out.String(), "println(1)", "files not outlined are written in full")
+
+	out.Reset()
+	require.NoError(t, generate.RenderWithOptions(&out, "", tree, state, memfs, generate.Options{Format: generate.FormatXML, Outline: true}))
+	assert.Contains(t, out.String(), `<file name="main.go" mode="outline">`)
+	assert.NotContains(t, out.String(), "println(1)")
+}
@@ -0,0 +1,290 @@
+package generate
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/eliooooooot/picky/internal/domain"
+)
+
+// section is a rendered piece of output that is never split across parts
+type section struct {
+	text   string
+	tokens int
+}
+
+// GenerateWithOptions is like Generate but honours opts. It returns the paths written.
+// When the output exceeds opts.MaxTokensPerPart it is written to numbered files
+// (selected.part1.txt, selected.part2.txt, ...), each repeating the prompt and
+// directory structure. A file is only split when it alone exceeds the limit.
+func GenerateWithOptions(outPath, prompt string, tree *domain.Tree, state domain.ViewState, fs domain.FileSystem, opts Options) ([]string, error) {
+	paths := domain.GetSelectedPaths(tree.Root, state)
+	if opts.MaxTokensPerPart <= 0 || len(paths) == 0 {
+		return []string{outPath}, generateFile(outPath, prompt, tree, state, fs, opts)
+	}
+
+	writer := newWriter(state, opts)
+	tz := writer.tokenizer()
+
+	preamble, err := renderPreamble(writer, prompt, tree, state)
+	if err != nil {
+		return nil, err
+	}
+	avail := opts.MaxTokensPerPart - tz.CountTokens(preamble)
+	if avail <= 0 {
+		return nil, fmt.Errorf("max tokens per part (%d) is too small for the prompt and directory structure", opts.MaxTokensPerPart)
+	}
+
+	var sections []section
+	for _, path := range paths {
+		s, err := fileSections(writer, path, fs, avail)
+		if err != nil {
+			return nil, err
+		}
+		sections = append(sections, s...)
+	}
+
+	sizes := make([]int, len(sections))
+	for i, s := range sections {
+		sizes[i] = s.tokens
+	}
+	parts := packParts(sizes, avail)
+	if len(parts) <= 1 {
+		return []string{outPath}, generateFile(outPath, prompt, tree, state, fs, opts)
+	}
+
+	written := make([]string, 0, len(parts))
+	for i, part := range parts {
+		partPath := PartPath(outPath, i+1)
+		if err := writePart(writer, fs, partPath, i+1, len(parts), prompt, tree, state, sections, part); err != nil {
+			return written, err
+		}
+		written = append(written, partPath)
+	}
+	return written, nil
+}
+
+// CountParts estimates how many parts GenerateWithOptions would produce,
+// using known per-file token counts instead of reading file contents.
+func CountParts(prompt string, tree *domain.Tree, state domain.ViewState, tokens map[string]int, opts Options) int {
+	return CountPartsWithPreamble(PreambleTokens(prompt, tree, state, opts), tree, state, tokens, opts)
+}
+
+// PreambleTokens returns the tokens each part starts with: its header, the
+// prompt and the directory structure
+func PreambleTokens(prompt string, tree *domain.Tree, state domain.ViewState, opts Options) int {
+	writer := newWriter(state, opts)
+	preamble, err := renderPreamble(writer, prompt, tree, state)
+	if err != nil {
+		return 0
+	}
+	return writer.tokenizer().CountTokens(preamble)
+}
+
+// CountPartsWithPreamble is CountParts for callers that keep the tokens of
+// the preamble, or of the structure in it, between calls
+func CountPartsWithPreamble(preamble int, tree *domain.Tree, state domain.ViewState, tokens map[string]int, opts Options) int {
+	paths := domain.GetSelectedPaths(tree.Root, state)
+	if opts.MaxTokensPerPart <= 0 || len(paths) == 0 {
+		return 1
+	}
+
+	writer := newWriter(state, opts)
+	avail := opts.MaxTokensPerPart - preamble
+	if avail <= 0 {
+		return 1
+	}
+
+	var sizes []int
+	for _, node := range domain.GetSelectedFiles(tree.Root, state) {
+		n := blockOverhead(writer, node.Path)
+		if !domain.IsSkipped(node, state) {
+			n += domain.EffectiveTokens(node.Path, state, tokens)
+		}
+		for n > avail {
+			sizes = append(sizes, avail)
+			n -= avail
+		}
+		sizes = append(sizes, n)
+	}
+	return len(packParts(sizes, avail))
+}
+
+// PartPath returns the numbered file name for a part, e.g. selected.part2.txt
+func PartPath(outPath string, n int) string {
+	ext := filepath.Ext(outPath)
+	return fmt.Sprintf("%s.part%d%s", strings.TrimSuffix(outPath, ext), n, ext)
+}
+
+// packParts greedily groups consecutive sizes so each group stays within limit.
+// It returns the indices of each group.
+func packParts(sizes []int, limit int) [][]int {
+	var parts [][]int
+	var current []int
+	used := 0
+	for i, size := range sizes {
+		if len(current) > 0 && used+size > limit {
+			parts = append(parts, current)
+			current = nil
+			used = 0
+		}
+		current = append(current, i)
+		used += size
+	}
+	if len(current) > 0 {
+		parts = append(parts, current)
+	}
+	return parts
+}
+
+// renderPreamble renders everything repeated in each part around the file
+// blocks, using the widest part numbers so the estimate is an upper bound
+func renderPreamble(writer formatWriter, prompt string, tree *domain.Tree, state domain.ViewState) (string, error) {
+	var buf bytes.Buffer
+	if err := writePreamble(&buf, writer, 99, 99, prompt, tree, state); err != nil {
+		return "", err
+	}
+	if err := writer.writeContentFooter(&buf); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+func writePreamble(w io.Writer, writer formatWriter, n, total int, prompt string, tree *domain.Tree, state domain.ViewState) error {
+	if err := writer.writePartHeader(w, n, total); err != nil {
+		return err
+	}
+	if err := writer.writePrompt(w, prompt); err != nil {
+		return err
+	}
+	if err := writer.WriteStructure(w, tree.Root, state); err != nil {
+		return err
+	}
+	return writer.writeContentHeader(w)
+}
+
+func writePart(writer formatWriter, fs domain.FileSystem, path string, n, total int, prompt string, tree *domain.Tree, state domain.ViewState, sections []section, indices []int) error {
+	w, err := fs.Create(path)
+	if err != nil {
+		return fmt.Errorf("create output file: %w", err)
+	}
+	defer w.Close()
+
+	if err := writePreamble(w, writer, n, total, prompt, tree, state); err != nil {
+		return err
+	}
+	for _, i := range indices {
+		if _, err := io.WriteString(w, sections[i].text); err != nil {
+			return err
+		}
+	}
+	return writer.writeContentFooter(w)
+}
+
+// blockOverhead is the token cost of a file block's title and fences
+func blockOverhead(writer formatWriter, path string) int {
+	var buf bytes.Buffer
+	writer.writeBlock(&buf, block{Path: path})
+	return writer.tokenizer().CountTokens(buf.String())
+}
+
+// fileSections renders a file as one section, or as several line-range
+// sections if the whole file does not fit within limit tokens or only some
+// of its lines are selected.
+func fileSections(writer formatWriter, path string, fs domain.FileSystem, limit int) ([]section, error) {
+	tz := writer.tokenizer()
+
+	var buf bytes.Buffer
+	if len(writer.lineRanges(path)) > 0 {
+		// Each selected range is its own section and is never split further
+		var sections []section
+		for _, b := range fileBlocks(writer, path, fs) {
+			buf.Reset()
+			if err := writer.writeBlock(&buf, b); err != nil {
+				return nil, err
+			}
+			sections = append(sections, section{text: buf.String(), tokens: tz.CountTokens(buf.String())})
+		}
+		return sections, nil
+	}
+
+	// Without line ranges a file is one block, or none when only its path
+	// is listed. Splitting its content rather than rereading the file keeps
+	// redaction, inclusion modes and transforms.
+	blocks := fileBlocks(writer, path, fs)
+	if len(blocks) == 0 {
+		return nil, nil
+	}
+	file := blocks[0]
+	if err := writer.writeBlock(&buf, file); err != nil {
+		return nil, err
+	}
+	whole := section{text: buf.String(), tokens: tz.CountTokens(buf.String())}
+	if whole.tokens <= limit {
+		return []section{whole}, nil
+	}
+
+	omitted := file.Omitted
+	lines := splitLines(file.Content)
+
+	var sections []section
+	start := 0
+	for start < len(lines) {
+		// Always take at least one line so oversized lines still make progress
+		end := start + 1
+		used := tz.CountTokens(lines[start])
+		overhead := blockOverhead(writer, path) + tz.CountTokens(fmt.Sprintf(" (lines %d-%d)", start+1, len(lines)))
+		for end < len(lines) {
+			n := tz.CountTokens(lines[end])
+			if overhead+used+n > limit {
+				break
+			}
+			used += n
+			end++
+		}
+
+		b := block{
+			Path:    path,
+			Content: []byte(strings.Join(lines[start:end], "")),
+			Start:   start + 1,
+			End:     end,
+			Mode:    file.Mode,
+		}
+		if file.Numbers != nil {
+			// Title the piece with the file's own line numbers
+			b.Numbers = file.Numbers[start:end]
+			b.Start, b.End = numberSpan(b.Numbers, b.Start, b.End)
+		}
+		if end == len(lines) {
+			b.Omitted = omitted
+		}
+		buf.Reset()
+		if err := writer.writeBlock(&buf, b); err != nil {
+			return nil, err
+		}
+		sections = append(sections, section{text: buf.String(), tokens: tz.CountTokens(buf.String())})
+		start = end
+	}
+	return sections, nil
+}
+
+// numberSpan returns the first and last line numbers taken from the file, or
+// start and end when none of the lines were
+func numberSpan(numbers []int, start, end int) (int, int) {
+	first, last := 0, 0
+	for _, n := range numbers {
+		if n > 0 {
+			if first == 0 {
+				first = n
+			}
+			last = n
+		}
+	}
+	if first == 0 {
+		return start, end
+	}
+	return first, last
+}
@@ -0,0 +1,200 @@
+package generate
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// SecretKind names a kind of credential found by ScanSecrets
+type SecretKind string
+
+const (
+	// SecretPrivateKey is a PEM private key block
+	SecretPrivateKey SecretKind = "private-key"
+	// SecretAWSKey is an AWS access key ID or secret access key
+	SecretAWSKey SecretKind = "aws-key"
+	// SecretJWT is a JSON web token
+	SecretJWT SecretKind = "jwt"
+	// SecretEnv is a value assigned in a .env file, or to a secret-looking
+	// variable such as API_TOKEN elsewhere
+	SecretEnv SecretKind = "env"
+	// SecretHighEntropy is a long random-looking string
+	SecretHighEntropy SecretKind = "high-entropy"
+)
+
+// allowSecretMarkers suppress findings on their line and the line below
+var allowSecretMarkers = []string{"picky:allow-secret", "pragma: allowlist secret"}
+
+// SecretFinding is one credential found in a file's content
+type SecretFinding struct {
+	Kind SecretKind
+	// Line is the 1-based line the secret starts on
+	Line int
+	// Start and End are byte offsets of the secret in the content
+	Start, End int
+}
+
+// secretPattern finds one kind of secret. group picks the submatch that is
+// the secret itself; 0 means the whole match.
+type secretPattern struct {
+	kind  SecretKind
+	re    *regexp.Regexp
+	group int
+}
+
+// secretPatterns are tried in order, so of two findings starting at the same
+// offset the earlier pattern wins. A key block cut off by truncation runs to
+// the end of the content.
+var secretPatterns = []secretPattern{
+	{SecretPrivateKey, regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY( BLOCK)?-----[\s\S]*?(?:-----END [A-Z ]*PRIVATE KEY( BLOCK)?-----|\z)`), 0},
+	{SecretAWSKey, regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`), 0},
+	{SecretAWSKey, regexp.MustCompile(`(?i)aws_?secret_?(?:access_?)?key["']?\s*[:=]\s*["']?([A-Za-z0-9/+]{40})\b`), 1},
+	{SecretJWT, regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{8,}\.eyJ[A-Za-z0-9_-]{8,}\.[A-Za-z0-9_-]{8,}`), 0},
+	{SecretEnv, regexp.MustCompile(`\b[A-Z0-9_]*(?:SECRET|PASSWORD|PASSWD|TOKEN|API_?KEY|PRIVATE_?KEY)[A-Z0-9_]*["']?\s*[:=]\s*["']?([^\s"'#,;]{8,})`), 1},
+}
+
+// envAssignment matches a KEY=value line of a .env file
+var envAssignment = regexp.MustCompile(`(?m)^\s*(?:export\s+)?[A-Za-z_][A-Za-z0-9_]*\s*=\s*["']?([^\s"'#][^"'#\r\n]*?)["']?\s*(?:#.*)?$`)
+
+// entropyCandidate matches long runs of base64 or URL-safe characters
+var entropyCandidate = regexp.MustCompile(`[A-Za-z0-9+/_-]{32,}={0,2}`)
+
+// isDotenv reports whether name is a .env file holding real values.
+// Example and template files are expected to be shared.
+func isDotenv(name string) bool {
+	if name != ".env" && !strings.HasPrefix(name, ".env.") && path.Ext(name) != ".env" {
+		return false
+	}
+	for _, suffix := range []string{".example", ".sample", ".template", ".dist"} {
+		if strings.HasSuffix(name, suffix) {
+			return false
+		}
+	}
+	return true
+}
+
+// ScanSecrets finds credentials in a file's content. Findings on a line
+// carrying an allow marker, or right below one, are left out.
+func ScanSecrets(name string, content []byte) []SecretFinding {
+	var found []SecretFinding
+	add := func(kind SecretKind, start, end int) {
+		found = append(found, SecretFinding{Kind: kind, Start: start, End: end})
+	}
+
+	for _, p := range secretPatterns {
+		for _, m := range p.re.FindAllSubmatchIndex(content, -1) {
+			add(p.kind, m[2*p.group], m[2*p.group+1])
+		}
+	}
+	if isDotenv(path.Base(name)) {
+		for _, m := range envAssignment.FindAllSubmatchIndex(content, -1) {
+			add(SecretEnv, m[2], m[3])
+		}
+	}
+	for _, m := range entropyCandidate.FindAllIndex(content, -1) {
+		if looksRandom(content[m[0]:m[1]]) {
+			add(SecretHighEntropy, m[0], m[1])
+		}
+	}
+
+	// Where findings overlap keep the one that starts first
+	sort.SliceStable(found, func(i, j int) bool {
+		return found[i].Start < found[j].Start
+	})
+	lineStarts := lineOffsets(content)
+	allowed := allowedLines(content)
+	var kept []SecretFinding
+	for _, f := range found {
+		if n := len(kept); n > 0 && f.Start < kept[n-1].End {
+			continue
+		}
+		f.Line = sort.SearchInts(lineStarts, f.Start+1)
+		if allowed[f.Line] {
+			continue
+		}
+		kept = append(kept, f)
+	}
+	return kept
+}
+
+// looksRandom reports whether s mixes cases and digits with enough entropy
+// to be a key rather than a word, path or hex digest
+func looksRandom(s []byte) bool {
+	var upper, lower, digit bool
+	for _, c := range s {
+		switch {
+		case c >= 'A' && c <= 'Z':
+			upper = true
+		case c >= 'a' && c <= 'z':
+			lower = true
+		case c >= '0' && c <= '9':
+			digit = true
+		}
+	}
+	return upper && lower && digit && shannonEntropy(s) >= 4.3
+}
+
+// shannonEntropy returns the bits of entropy per character of s
+func shannonEntropy(s []byte) float64 {
+	var counts [256]int
+	for _, c := range s {
+		counts[c]++
+	}
+	var h float64
+	for _, n := range counts {
+		if n > 0 {
+			p := float64(n) / float64(len(s))
+			h -= p * math.Log2(p)
+		}
+	}
+	return h
+}
+
+// lineOffsets returns the byte offset each line starts at
+func lineOffsets(content []byte) []int {
+	offsets := []int{0}
+	for i, c := range content {
+		if c == '\n' {
+			offsets = append(offsets, i+1)
+		}
+	}
+	return offsets
+}
+
+// allowedLines returns the 1-based lines where findings are suppressed
+func allowedLines(content []byte) map[int]bool {
+	allowed := make(map[int]bool)
+	for i, line := range bytes.Split(content, []byte("\n")) {
+		for _, marker := range allowSecretMarkers {
+			if bytes.Contains(line, []byte(marker)) {
+				allowed[i+1] = true
+				allowed[i+2] = true
+			}
+		}
+	}
+	return allowed
+}
+
+// Redact replaces every secret ScanSecrets finds with [REDACTED:kind].
+// Line breaks inside a secret are kept so line numbers stay the same.
+func Redact(name string, content []byte) ([]byte, []SecretFinding) {
+	findings := ScanSecrets(name, content)
+	if len(findings) == 0 {
+		return content, nil
+	}
+	var out bytes.Buffer
+	last := 0
+	for _, f := range findings {
+		out.Write(content[last:f.Start])
+		fmt.Fprintf(&out, "[REDACTED:%s]", f.Kind)
+		out.Write(bytes.Repeat([]byte("\n"), bytes.Count(content[f.Start:f.End], []byte("\n"))))
+		last = f.End
+	}
+	out.Write(content[last:])
+	return out.Bytes(), findings
+}
@@ -0,0 +1,184 @@
+package generate
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/eliooooooot/picky/internal/domain"
+)
+
+// StructureMode says how much of the tree the directory structure lists
+type StructureMode string
+
+const (
+	// StructureFull lists every node in the tree
+	StructureFull StructureMode = "full"
+	// StructureSelected lists selected files and the directories above them
+	StructureSelected StructureMode = "selected"
+	// StructureCollapsed lists the tree down to StructureDepth levels, with
+	// deeper directories summarised on one line
+	StructureCollapsed StructureMode = "collapsed"
+	// StructureNone leaves the directory structure out
+	StructureNone StructureMode = "none"
+)
+
+// ParseStructureMode validates a structure mode; the empty string means full
+func ParseStructureMode(name string) (StructureMode, error) {
+	switch m := StructureMode(strings.ToLower(name)); m {
+	case "":
+		return StructureFull, nil
+	case StructureFull, StructureSelected, StructureCollapsed, StructureNone:
+		return m, nil
+	}
+	return "", fmt.Errorf("unknown structure mode %q (want full, selected, collapsed or none)", name)
+}
+
+// defaultStructureDepth is how many levels the collapsed structure lists
+// when StructureDepth is unset
+const defaultStructureDepth = 2
+
+// StructureTokens returns the tokens RenderWithOptions spends on the
+// directory structure, or 0 when nothing is selected and none is written
+func StructureTokens(tree *domain.Tree, state domain.ViewState, opts Options) int {
+	if len(domain.GetSelectedPaths(tree.Root, state)) == 0 {
+		return 0
+	}
+	writer := newWriter(state, opts)
+	var buf bytes.Buffer
+	if err := writer.WriteStructure(&buf, tree.Root, state); err != nil {
+		return 0
+	}
+	return writer.tokenizer().CountTokens(buf.String())
+}
+
+// structureTree lists the nodes under root the structure mode keeps
+type structureTree struct {
+	r     fileReader
+	state domain.ViewState
+	// keep holds the paths listed in the selected mode; nil lists all
+	keep map[string]bool
+}
+
+// writeStructureTree writes one line per listed node under root
+func (r fileReader) writeStructureTree(w io.Writer, root *domain.Node, state domain.ViewState) error {
+	t := structureTree{r: r, state: state}
+	levels := -1
+	switch r.Structure {
+	case StructureSelected:
+		t.keep = make(map[string]bool)
+		for _, path := range domain.GetSelectedPaths(root, state) {
+			for p := path; p != root.Path && !t.keep[p]; p = filepath.Dir(p) {
+				t.keep[p] = true
+				if p == filepath.Dir(p) {
+					break
+				}
+			}
+		}
+	case StructureCollapsed:
+		levels = r.StructureDepth
+		if levels <= 0 {
+			levels = defaultStructureDepth
+		}
+	}
+	return t.write(w, root, "", true, levels)
+}
+
+// write writes node and its listed children using box-drawing prefixes.
+// levels is how many levels below node are listed; negative means all.
+func (t structureTree) write(w io.Writer, node *domain.Node, prefix string, isLast bool, levels int) error {
+	collapsed := levels == 0 && node.IsDir
+	if node.Parent != nil { // Skip root node name in structure
+		marker := "├── "
+		if isLast {
+			marker = "└── "
+		}
+		line := prefix + marker + node.Name + t.suffix(node, collapsed)
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+
+		if isLast {
+			prefix += "    "
+		} else {
+			prefix += "│   "
+		}
+	}
+	if collapsed {
+		return nil
+	}
+
+	var children []*domain.Node
+	for _, child := range domain.VisibleChildren(node, t.state) {
+		if t.keep == nil || t.keep[child.Path] {
+			children = append(children, child)
+		}
+	}
+	if levels > 0 {
+		levels--
+	}
+	for i, child := range children {
+		if err := t.write(w, child, prefix, i == len(children)-1, levels); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// suffix marks selected files with their token count when it is known and
+// describes what a collapsed directory holds
+func (t structureTree) suffix(node *domain.Node, collapsed bool) string {
+	if collapsed {
+		files, selected, complete := countFiles(node, t.state)
+		count := fmt.Sprint(files)
+		switch {
+		case !node.IsLoaded():
+			return " (not read)"
+		case !complete:
+			// Directories a lazy tree has not read add an unknown number
+			count += "+"
+		case files == 0:
+			return ""
+		}
+		noun := "files"
+		if files == 1 && complete {
+			noun = "file"
+		}
+		if selected > 0 {
+			return fmt.Sprintf(" (%s %s, %d selected)", count, noun, selected)
+		}
+		return fmt.Sprintf(" (%s %s)", count, noun)
+	}
+	if node.IsDir || !t.state.IsSelected(node.Path) {
+		return ""
+	}
+	// Counts are for whole files, so files cut down another way go without
+	_, known := t.r.FileTokens[node.Path]
+	if !known || domain.IsSkipped(node, t.state) || t.r.mode(node.Path) != domain.IncludeFull || len(t.r.lineRanges(node.Path)) > 0 {
+		return " *"
+	}
+	return fmt.Sprintf(" * (%d tokens)", domain.EffectiveTokens(node.Path, t.state, t.r.FileTokens))
+}
+
+// countFiles counts the files under node the state shows and how many of
+// them are selected. complete is false when directories not read yet are
+// below it.
+func countFiles(node *domain.Node, state domain.ViewState) (files, selected int, complete bool) {
+	complete = node.IsLoaded()
+	for _, child := range domain.VisibleChildren(node, state) {
+		if child.IsDir {
+			f, s, c := countFiles(child, state)
+			files += f
+			selected += s
+			complete = complete && c
+			continue
+		}
+		files++
+		if state.IsSelected(child.Path) {
+			selected++
+		}
+	}
+	return files, selected, complete
+}
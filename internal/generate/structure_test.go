@@ -0,0 +1,126 @@
+package generate_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/eliooooooot/picky/internal/domain"
+	"github.com/eliooooooot/picky/internal/fs"
+	"github.com/eliooooooot/picky/internal/generate"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderStructureModes(t *testing.T) {
+	memfs := fs.NewMemFileSystem()
+	memfs.AddDir("/root")
+	memfs.AddFile("/root/main.go", "package main\n")
+	memfs.AddFile("/root/README.md", "readme\n")
+	memfs.AddFile("/root/internal/app/app.go", "package app\n")
+	memfs.AddFile("/root/internal/app/run.go", "package app\n")
+	memfs.AddFile("/root/internal/db/db.go", "package db\n")
+
+	tree, err := domain.BuildTree(memfs, "/root")
+	require.NoError(t, err)
+	state := domain.NewViewState(tree.Root.Path).
+		SetSelected("/root/main.go", true).
+		SetSelected("/root/internal/app/app.go", true)
+
+	render := func(opts generate.Options) string {
+		var out strings.Builder
+		require.NoError(t, generate.RenderWithOptions(&out, "", tree, state, memfs, opts))
+		return out.String()
+	}
+
+	t.Run("full", func(t *testing.T) {
+		text := render(generate.Options{})
+		assert.Contains(t, text, "├── README.md\n")
+		assert.Contains(t, text, "│   └── db\n│       └── db.go\n")
+	})
+
+	t.Run("selected", func(t *testing.T) {
+		text := render(generate.Options{Structure: generate.StructureSelected})
+		assert.Contains(t, text, "# Directory Structure\n\n├── internal\n│   └── app\n│       └── app.go *\n└── main.go *\n\n")
+	})
+
+	t.Run("collapsed", func(t *testing.T) {
+		text := render(generate.Options{Structure: generate.StructureCollapsed, StructureDepth: 2})
+		assert.Contains(t, text, "├── internal\n│   ├── app (2 files, 1 selected)\n│   └── db (1 file)\n")
+		assert.NotContains(t, text, "run.go")
+		assert.Contains(t, render(generate.Options{Structure: generate.StructureCollapsed, StructureDepth: 1}),
+			"├── internal (3 files, 1 selected)\n")
+	})
+
+	t.Run("collapsed lazy tree", func(t *testing.T) {
+		lazy, err := domain.BuildLazyTree(memfs, "/root")
+		require.NoError(t, err)
+		domain.FindNodeByPath(lazy.Root, "/root/internal").Load()
+		domain.FindNodeByPath(lazy.Root, "/root/internal/app").Load()
+		var out strings.Builder
+		require.NoError(t, generate.RenderWithOptions(&out, "", lazy, state, memfs, generate.Options{Structure: generate.StructureCollapsed, StructureDepth: 1}))
+		assert.Contains(t, out.String(), "├── internal (2+ files, 1 selected)\n", "unread directories leave the count open")
+
+		out.Reset()
+		require.NoError(t, generate.RenderWithOptions(&out, "", lazy, state, memfs, generate.Options{Structure: generate.StructureCollapsed, StructureDepth: 2}))
+		assert.Contains(t, out.String(), "│   ├── app (2 files, 1 selected)\n│   └── db (not read)\n")
+	})
+
+	t.Run("none", func(t *testing.T) {
+		text := render(generate.Options{Structure: generate.StructureNone})
+		assert.True(t, strings.HasPrefix(text, "# Selected Files\n"), "got:\n%s", text)
+		assert.Zero(t, generate.StructureTokens(tree, state, generate.Options{Structure: generate.StructureNone}))
+
+		var out strings.Builder
+		require.NoError(t, generate.RenderWithOptions(&out, "", tree, state, memfs, generate.Options{Structure: generate.StructureNone, Format: generate.FormatXML}))
+		assert.NotContains(t, out.String(), "<directory_structure>")
+	})
+
+	t.Run("token counts", func(t *testing.T) {
+		tokens := map[string]int{"/root/main.go": 120, "/root/internal/app/app.go": 40, "/root/README.md": 9}
+		counted := state.SetTruncated("/root/main.go", 100)
+		var out strings.Builder
+		require.NoError(t, generate.RenderWithOptions(&out, "", tree, counted, memfs, generate.Options{FileTokens: tokens}))
+		assert.Contains(t, out.String(), "main.go * (100 tokens)\n", "counts follow truncation")
+		assert.Contains(t, out.String(), "app.go * (40 tokens)\n")
+		assert.Contains(t, out.String(), "├── README.md\n", "unselected files have no count")
+	})
+
+	t.Run("structure tokens", func(t *testing.T) {
+		full := generate.StructureTokens(tree, state, generate.Options{})
+		selected := generate.StructureTokens(tree, state, generate.Options{Structure: generate.StructureSelected})
+		assert.Greater(t, full, selected)
+		assert.Greater(t, selected, 0)
+		assert.Zero(t, generate.StructureTokens(tree, domain.NewViewState(tree.Root.Path), generate.Options{}), "nothing selected writes no structure")
+	})
+
+	_, err = generate.ParseStructureMode("sparse")
+	assert.Error(t, err)
+}
+
+func TestStructureLeavesOutHiddenFiles(t *testing.T) {
+	memfs := fs.NewMemFileSystem()
+	memfs.AddDir("/root")
+	memfs.AddFile("/root/main.go", "package main\n")
+	memfs.AddFile("/root/.env", "SECRET=1\n")
+	memfs.AddFile("/root/lib/lib.go", "package lib\n")
+	memfs.AddFile("/root/lib/.cache", "x\n")
+
+	tree, err := domain.BuildTree(memfs, "/root")
+	require.NoError(t, err)
+	state := domain.NewViewState(tree.Root.Path).
+		SetSelected("/root/main.go", true).
+		SetHideHidden(true)
+
+	var out strings.Builder
+	require.NoError(t, generate.RenderWithOptions(&out, "", tree, state, memfs, generate.Options{}))
+	assert.NotContains(t, out.String(), ".env")
+	assert.NotContains(t, out.String(), ".cache")
+
+	out.Reset()
+	require.NoError(t, generate.RenderWithOptions(&out, "", tree, state, memfs, generate.Options{Structure: generate.StructureCollapsed, StructureDepth: 1}))
+	assert.Contains(t, out.String(), "├── lib (1 file)\n", "hidden files are not counted")
+
+	out.Reset()
+	require.NoError(t, generate.RenderWithOptions(&out, "", tree, state.SetHideHidden(false), memfs, generate.Options{}))
+	assert.Contains(t, out.String(), ".env")
+}
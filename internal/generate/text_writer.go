@@ -5,19 +5,27 @@ import (
 	"io"
 	"path/filepath"
 	"github.com/eliooooooot/picky/internal/domain"
+	"github.com/eliooooooot/picky/internal/token"
 	"strings"
 )
 
 // TextWriter implements domain.OutputWriter for text output
-type TextWriter struct{}
+type TextWriter struct {
+	fileReader
+}
 
 // NewTextWriter creates a new text writer
 func NewTextWriter() *TextWriter {
-	return &TextWriter{}
+	return &TextWriter{
+		fileReader: fileReader{Tokenizer: token.NaiveTokenizer{}},
+	}
 }
 
 // WriteStructure writes the directory structure in text format
 func (tw *TextWriter) WriteStructure(w io.Writer, root *domain.Node, state domain.ViewState) error {
+	if tw.Structure == StructureNone {
+		return nil
+	}
 	if _, err := fmt.Fprintln(w, "# Directory Structure"); err != nil {
 		return err
 	}
@@ -26,7 +34,7 @@ func (tw *TextWriter) WriteStructure(w io.Writer, root *domain.Node, state domai
 	}
 	
 	// Build a simple tree representation
-	if err := tw.writeNodeStructure(w, root, state, "", true); err != nil {
+	if err := tw.writeStructureTree(w, root, state); err != nil {
 		return err
 	}
 	
@@ -36,34 +44,14 @@ func (tw *TextWriter) WriteStructure(w io.Writer, root *domain.Node, state domai
 	return nil
 }
 
-func (tw *TextWriter) writeNodeStructure(w io.Writer, node *domain.Node, state domain.ViewState, prefix string, isLast bool) error {
-	if node.Parent != nil { // Skip root node name in structure
-		// Determine the prefix for this line
-		marker := "├── "
-		if isLast {
-			marker = "└── "
-		}
-		
-		line := prefix + marker + node.Name
-		if state.IsSelected(node.Path) && !node.IsDir {
-			line += " *"
-		}
-		if _, err := fmt.Fprintln(w, line); err != nil {
-			return err
-		}
-		
-		// Update prefix for children
-		if isLast {
-			prefix += "    "
-		} else {
-			prefix += "│   "
-		}
+// WriteContent writes the content of selected files
+func (tw *TextWriter) WriteContent(w io.Writer, paths []string, fs domain.FileSystem) error {
+	if err := tw.writeContentHeader(w); err != nil {
+		return err
 	}
 	
-	// Write children
-	for i, child := range node.Children {
-		isLastChild := i == len(node.Children)-1
-		if err := tw.writeNodeStructure(w, child, state, prefix, isLastChild); err != nil {
+	for _, path := range paths {
+		if err := writeFileBlock(tw, w, path, fs); err != nil {
 			return err
 		}
 	}
@@ -71,64 +59,72 @@ func (tw *TextWriter) writeNodeStructure(w io.Writer, node *domain.Node, state d
 	return nil
 }
 
-// WriteContent writes the content of selected files
-func (tw *TextWriter) WriteContent(w io.Writer, paths []string, fs domain.FileSystem) error {
+// writePrompt writes the prompt section if the prompt is non-empty
+func (tw *TextWriter) writePrompt(w io.Writer, prompt string) error {
+	if prompt == "" {
+		return nil
+	}
+	_, err := fmt.Fprintf(w, "# Prompt\n\n%s\n\n", prompt)
+	return err
+}
+
+// writePartHeader writes the continuation header at the top of a part
+func (tw *TextWriter) writePartHeader(w io.Writer, n, total int) error {
+	_, err := fmt.Fprintf(w, "# Part %d of %d\n\n", n, total)
+	return err
+}
+
+// writeContentHeader writes the heading that precedes file blocks
+func (tw *TextWriter) writeContentHeader(w io.Writer) error {
 	if _, err := fmt.Fprintln(w, "# Selected Files"); err != nil {
 		return err
 	}
 	if _, err := fmt.Fprintln(w); err != nil {
 		return err
 	}
+	return nil
+}
+
+// writeContentFooter is a no-op; text output has no closing section
+func (tw *TextWriter) writeContentFooter(w io.Writer) error {
+	return nil
+}
+
+// writeBlock writes a titled, fenced block of content
+func (tw *TextWriter) writeBlock(w io.Writer, b block) error {
+	title := filepath.Base(b.Path)
+	if b.Start > 0 {
+		title = fmt.Sprintf("%s (lines %d-%d)", title, b.Start, b.End)
+	}
+	if b.Mode != "" && b.Mode != domain.IncludeFull {
+		title += fmt.Sprintf(" (%s)", b.Mode)
+	}
+	if _, err := fmt.Fprintf(w, "## %s\n\n", title); err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintln(w, "```"); err != nil {
+		return err
+	}
 	
-	for _, path := range paths {
-		// Write file header
-		if _, err := fmt.Fprintf(w, "## %s\n\n", filepath.Base(path)); err != nil {
-			return err
-		}
-		if _, err := fmt.Fprintln(w, "```"); err != nil {
-			return err
-		}
-		
-		// Read and write file content
-		f, err := fs.Open(path)
-		if err != nil {
-			if _, err := fmt.Fprintf(w, "Error reading file: %v\n", err); err != nil {
-				return err
-			}
-			if _, err := fmt.Fprintln(w, "```"); err != nil {
-				return err
-			}
-			if _, err := fmt.Fprintln(w); err != nil {
-				return err
-			}
-			continue
-		}
-		
-		content, err := io.ReadAll(f)
-		f.Close()
-		
-		if err != nil {
-			if _, err := fmt.Fprintf(w, "Error reading file: %v\n", err); err != nil {
-				return err
-			}
-		} else {
-			if _, err := w.Write(content); err != nil {
-				return err
-			}
-			if !strings.HasSuffix(string(content), "\n") {
-				if _, err := fmt.Fprintln(w); err != nil {
-					return err
-				}
-			}
-		}
-		
-		if _, err := fmt.Fprintln(w, "```"); err != nil {
+	if _, err := w.Write(numberedContent(b)); err != nil {
+		return err
+	}
+	if !strings.HasSuffix(string(b.Content), "\n") {
+		if _, err := fmt.Fprintln(w); err != nil {
 			return err
 		}
-		if _, err := fmt.Fprintln(w); err != nil {
+	}
+	if b.Omitted > 0 {
+		if _, err := fmt.Fprintf(w, "[truncated: %d lines omitted]\n", b.Omitted); err != nil {
 			return err
 		}
 	}
 	
+	if _, err := fmt.Fprintln(w, "```"); err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintln(w); err != nil {
+		return err
+	}
 	return nil
-}
\ No newline at end of file
+}
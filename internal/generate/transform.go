@@ -0,0 +1,412 @@
+package generate
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"path/filepath"
+	"strings"
+)
+
+// Transforms are lossy changes to file contents that make more code fit in
+// the context. The zero value leaves contents alone.
+type Transforms struct {
+	// StripComments removes comments in languages picky knows the syntax of
+	StripComments bool
+	// KeepDocComments keeps doc comments when stripping: /** */, /// and //!
+	// comments, and in Go the comments directly above a declaration
+	KeepDocComments bool
+	// CollapseBlankLines squeezes runs of blank lines into one
+	CollapseBlankLines bool
+	// DropLicense removes a license or copyright comment at the top of a file
+	DropLicense bool
+}
+
+// ParseTransforms builds Transforms from names: "comments" strips comments
+// but keeps doc comments, "doc-comments" strips those too, "blank-lines"
+// collapses blank lines and "license" drops license headers
+func ParseTransforms(names []string) (Transforms, error) {
+	var t Transforms
+	docs := false
+	for _, name := range names {
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "comments":
+			t.StripComments = true
+		case "doc-comments":
+			t.StripComments = true
+			docs = true
+		case "blank-lines":
+			t.CollapseBlankLines = true
+		case "license":
+			t.DropLicense = true
+		default:
+			return Transforms{}, fmt.Errorf("unknown transform %q (want comments, doc-comments, blank-lines or license)", name)
+		}
+	}
+	t.KeepDocComments = t.StripComments && !docs
+	return t, nil
+}
+
+// Enabled reports whether any transform is on
+func (t Transforms) Enabled() bool {
+	return t.StripComments || t.CollapseBlankLines || t.DropLicense
+}
+
+// Apply transforms the content of the file called name. Go files are
+// reformatted after comments are stripped; if that fails, for example on a
+// fragment of a file, the stripped content is kept as it is.
+func (t Transforms) Apply(name string, content []byte) []byte {
+	if !t.Enabled() {
+		return content
+	}
+	lang := languageFor(name)
+	if lang != nil && t.DropLicense {
+		content = dropLicense(content, lang)
+	}
+	if lang != nil && t.StripComments {
+		content = stripComments(content, lang, t.KeepDocComments)
+		if lang.goSource {
+			if formatted, err := format.Source(content); err == nil {
+				content = formatted
+			}
+		}
+	}
+	if t.CollapseBlankLines {
+		content = collapseBlankLines(content)
+	}
+	return content
+}
+
+// language describes the comment and string syntax of a family of languages
+type language struct {
+	lineComments []string
+	// blockComments pairs each opening delimiter with its closing one
+	blockComments [][2]string
+	// quotes start strings that end at the same delimiter; only those listed
+	// in multiline may span lines
+	quotes    []string
+	multiline []string
+	// docPrefixes mark comments kept as documentation
+	docPrefixes []string
+	// goSource enables Go's rule that comments directly above code are docs
+	goSource bool
+}
+
+var (
+	cLike = &language{
+		lineComments:  []string{"//"},
+		blockComments: [][2]string{{"/*", "*/"}},
+		quotes:        []string{`"`, `'`, "`"},
+		multiline:     []string{"`"},
+		docPrefixes:   []string{"/**", "/*!", "///", "//!"},
+	}
+	goLang = &language{
+		lineComments:  []string{"//"},
+		blockComments: [][2]string{{"/*", "*/"}},
+		quotes:        []string{`"`, `'`, "`"},
+		multiline:     []string{"`"},
+		goSource:      true,
+	}
+	// Rust's lifetimes look like unterminated character literals
+	rustLang = &language{
+		lineComments:  []string{"//"},
+		blockComments: [][2]string{{"/*", "*/"}},
+		quotes:        []string{`"`},
+		docPrefixes:   []string{"/**", "/*!", "///", "//!"},
+	}
+	cssLang = &language{
+		blockComments: [][2]string{{"/*", "*/"}},
+		quotes:        []string{`"`, `'`},
+		docPrefixes:   []string{"/**"},
+	}
+	pythonLang = &language{
+		lineComments: []string{"#"},
+		quotes:       []string{`"""`, `'''`, `"`, `'`},
+		multiline:    []string{`"""`, `'''`},
+	}
+	hashLang = &language{
+		lineComments: []string{"#"},
+		quotes:       []string{`"`, `'`},
+	}
+	dashLang = &language{
+		lineComments:  []string{"--"},
+		blockComments: [][2]string{{"/*", "*/"}},
+		quotes:        []string{`'`, `"`},
+	}
+	markupLang = &language{
+		blockComments: [][2]string{{"<!--", "-->"}},
+	}
+)
+
+// languages maps file extensions to their syntax
+var languages = map[string]*language{
+	".go": goLang, ".rs": rustLang,
+	".c": cLike, ".h": cLike, ".cc": cLike, ".cpp": cLike, ".hpp": cLike,
+	".java": cLike, ".kt": cLike, ".kts": cLike, ".scala": cLike, ".swift": cLike,
+	".cs": cLike, ".dart": cLike, ".php": cLike, ".proto": cLike,
+	".js": cLike, ".jsx": cLike, ".mjs": cLike, ".cjs": cLike, ".ts": cLike, ".tsx": cLike,
+	".css": cssLang, ".scss": cLike, ".less": cLike,
+	".py": pythonLang,
+	".rb": hashLang, ".sh": hashLang, ".bash": hashLang, ".zsh": hashLang,
+	".yaml": hashLang, ".yml": hashLang, ".toml": hashLang, ".tf": hashLang,
+	".pl": hashLang, ".r": hashLang, ".ex": hashLang, ".exs": hashLang,
+	".sql": dashLang, ".lua": dashLang, ".hs": dashLang,
+	".html": markupLang, ".htm": markupLang, ".xml": markupLang, ".svg": markupLang, ".vue": markupLang,
+}
+
+// languagesByName maps extensionless file names to their syntax
+var languagesByName = map[string]*language{
+	"Makefile": hashLang, "Dockerfile": hashLang, "Containerfile": hashLang,
+}
+
+// languageFor returns the syntax of a file, or nil when it is not known
+func languageFor(name string) *language {
+	base := filepath.Base(name)
+	if lang, ok := languagesByName[base]; ok {
+		return lang
+	}
+	return languages[strings.ToLower(filepath.Ext(base))]
+}
+
+// quoteAt returns the string delimiter starting at src[i], if any
+func (l *language) quoteAt(src []byte, i int) string {
+	for _, q := range l.quotes {
+		if bytes.HasPrefix(src[i:], []byte(q)) {
+			return q
+		}
+	}
+	return ""
+}
+
+// skipString returns the offset just past the string opened by q at src[i].
+// Strings that may not span lines end at the newline if left unterminated.
+func (l *language) skipString(src []byte, i int, q string) int {
+	multiline := false
+	for _, m := range l.multiline {
+		multiline = multiline || m == q
+	}
+	for j := i + len(q); j < len(src); j++ {
+		switch {
+		case src[j] == '\\' && q != "`":
+			j++
+		case src[j] == '\n' && !multiline:
+			return j
+		case bytes.HasPrefix(src[j:], []byte(q)):
+			return j + len(q)
+		}
+	}
+	return len(src)
+}
+
+// commentAt returns the end of the comment starting at src[i], or -1 if
+// there is none. Line comments end before their newline.
+func (l *language) commentAt(src []byte, i int) int {
+	for _, c := range l.lineComments {
+		if bytes.HasPrefix(src[i:], []byte(c)) {
+			if end := bytes.IndexByte(src[i:], '\n'); end >= 0 {
+				return i + end
+			}
+			return len(src)
+		}
+	}
+	for _, c := range l.blockComments {
+		if bytes.HasPrefix(src[i:], []byte(c[0])) {
+			if end := bytes.Index(src[i+len(c[0]):], []byte(c[1])); end >= 0 {
+				return i + len(c[0]) + end + len(c[1])
+			}
+			return len(src)
+		}
+	}
+	return -1
+}
+
+// isDoc reports whether the comment at src[start:end] documents code
+func (l *language) isDoc(src []byte, start, end int, wholeLine bool) bool {
+	comment := src[start:end]
+	for _, p := range l.docPrefixes {
+		// "/**/" is an empty block comment, not a doc comment
+		if bytes.HasPrefix(comment, []byte(p)) && !bytes.Equal(comment, []byte("/**/")) {
+			return true
+		}
+	}
+	if !l.goSource || !wholeLine {
+		return false
+	}
+	// In Go a comment group directly above a declaration documents it
+	rest := src[end:]
+	for {
+		nl := bytes.IndexByte(rest, '\n')
+		if nl < 0 {
+			return false
+		}
+		rest = rest[nl+1:]
+		line := bytes.TrimSpace(firstLine(rest))
+		switch {
+		case len(line) == 0:
+			return false
+		case !bytes.HasPrefix(line, []byte("//")) && !bytes.HasPrefix(line, []byte("/*")):
+			return isGoDecl(line)
+		}
+	}
+}
+
+// isGoDecl reports whether a line of Go starts a declaration, or an
+// exported field or constant inside a declaration block
+func isGoDecl(line []byte) bool {
+	for _, kw := range []string{"package ", "import ", "func ", "type ", "var ", "const "} {
+		if bytes.HasPrefix(line, []byte(kw)) {
+			return true
+		}
+	}
+	return line[0] >= 'A' && line[0] <= 'Z'
+}
+
+// firstLine returns src up to its first newline
+func firstLine(src []byte) []byte {
+	if nl := bytes.IndexByte(src, '\n'); nl >= 0 {
+		return src[:nl]
+	}
+	return src
+}
+
+// stripComments removes comments outside strings. A comment alone on its
+// line takes the line with it; a trailing comment takes the space before it.
+// A shebang line is kept.
+func stripComments(src []byte, lang *language, keepDoc bool) []byte {
+	out := make([]byte, 0, len(src))
+	i := 0
+	if bytes.HasPrefix(src, []byte("#!")) {
+		i = len(firstLine(src))
+		out = append(out, src[:i]...)
+	}
+	for i < len(src) {
+		if q := lang.quoteAt(src, i); q != "" {
+			end := lang.skipString(src, i, q)
+			out = append(out, src[i:end]...)
+			i = end
+			continue
+		}
+		end := lang.commentAt(src, i)
+		if end < 0 {
+			out = append(out, src[i])
+			i++
+			continue
+		}
+
+		lineStart := bytes.LastIndexByte(out, '\n') + 1
+		wholeLine := len(bytes.TrimLeft(out[lineStart:], " \t")) == 0
+		if keepDoc && lang.isDoc(src, i, end, wholeLine) {
+			out = append(out, src[i:end]...)
+			i = end
+			continue
+		}
+		i = end
+		rest := len(bytes.TrimRight(firstLine(src[i:]), " \t\r"))
+		switch {
+		case wholeLine && rest == 0:
+			// Drop the whole line, newline included
+			out = out[:lineStart]
+			i += len(firstLine(src[i:]))
+			if i < len(src) {
+				i++
+			}
+		case wholeLine:
+			// Code follows the comment; keep the indentation before it
+			for i < len(src) && (src[i] == ' ' || src[i] == '\t') {
+				i++
+			}
+		default:
+			out = bytes.TrimRight(out, " \t")
+		}
+	}
+	return out
+}
+
+// licenseWords mark a header comment as a license or copyright notice
+var licenseWords = []string{"copyright", "license", "spdx-license-identifier"}
+
+// dropLicense removes the first comment of a file, after any shebang line,
+// when it is a license or copyright notice, along with the blank lines
+// after it
+func dropLicense(src []byte, lang *language) []byte {
+	start := 0
+	if bytes.HasPrefix(src, []byte("#!")) {
+		start = len(firstLine(src)) + 1
+		if start > len(src) {
+			return src
+		}
+	}
+	i := start
+	for i < len(src) && isSpace(src[i]) {
+		i++
+	}
+
+	// Take one block comment or a run of line comments
+	end := i
+	for end < len(src) {
+		j := end
+		for j < len(src) && isSpace(src[j]) {
+			j++
+		}
+		if j >= len(src) {
+			break
+		}
+		// A blank line ends a run of line comments
+		if end > i && bytes.Count(src[end:j], []byte("\n")) > 1 {
+			break
+		}
+		next := lang.commentAt(src, j)
+		if next < 0 || (end > i && isBlockComment(src[j:], lang)) {
+			break
+		}
+		end = next
+		if isBlockComment(src[j:], lang) {
+			break
+		}
+	}
+	if end == i {
+		return src
+	}
+
+	header := strings.ToLower(string(src[i:end]))
+	for _, word := range licenseWords {
+		if strings.Contains(header, word) {
+			for end < len(src) && isSpace(src[end]) {
+				end++
+			}
+			return append(append([]byte(nil), src[:start]...), src[end:]...)
+		}
+	}
+	return src
+}
+
+// isBlockComment reports whether src starts with a block comment opener
+func isBlockComment(src []byte, lang *language) bool {
+	for _, c := range lang.blockComments {
+		if bytes.HasPrefix(src, []byte(c[0])) {
+			return true
+		}
+	}
+	return false
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+// collapseBlankLines squeezes runs of blank lines into one and drops blank
+// lines at the start of the content
+func collapseBlankLines(src []byte) []byte {
+	lines := bytes.SplitAfter(src, []byte("\n"))
+	out := make([]byte, 0, len(src))
+	blank := true
+	for _, line := range lines {
+		isBlank := len(bytes.TrimSpace(line)) == 0
+		if isBlank && blank {
+			continue
+		}
+		blank = isBlank
+		out = append(out, line...)
+	}
+	return out
+}
@@ -0,0 +1,100 @@
+package generate_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/eliooooooot/picky/internal/domain"
+	"github.com/eliooooooot/picky/internal/fs"
+	"github.com/eliooooooot/picky/internal/generate"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const goSource = `// Copyright 2024 Example Authors.
+// Licensed under the MIT License.
+
+package main
+
+import "fmt"
+
+// Greeting is what main prints.
+const Greeting = "hello // not a comment"
+
+func main() {
+	/* setup */ x := 1 // trailing
+	// inside
+	x++
+
+
+	fmt.Println(Greeting, x, ` + "`raw /* kept */`" + `)
+}
+`
+
+func TestTransformsGo(t *testing.T) {
+	tr, err := generate.ParseTransforms([]string{"comments", "blank-lines", "license"})
+	require.NoError(t, err)
+	got := string(tr.Apply("main.go", []byte(goSource)))
+
+	want := `package main
+
+import "fmt"
+
+// Greeting is what main prints.
+const Greeting = "hello // not a comment"
+
+func main() {
+	x := 1
+	x++
+
+	fmt.Println(Greeting, x, ` + "`raw /* kept */`" + `)
+}
+`
+	assert.Equal(t, want, got)
+
+	tr, err = generate.ParseTransforms([]string{"doc-comments"})
+	require.NoError(t, err)
+	got = string(tr.Apply("main.go", []byte(goSource)))
+	assert.NotContains(t, got, "Greeting is")
+	assert.NotContains(t, got, "Copyright")
+}
+
+func TestTransformsOtherLanguages(t *testing.T) {
+	tr, err := generate.ParseTransforms([]string{"comments", "license"})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name, file, in, want string
+	}{
+		{"python", "app.py", "#!/usr/bin/env python\n# SPDX-License-Identifier: MIT\n\nurl = 'http://x#y'  # home\n# note\nprint(url)\n", "#!/usr/bin/env python\nurl = 'http://x#y'\nprint(url)\n"},
+		{"javascript doc", "app.js", "/** Adds. */\nfunction add(a, b) { return a + b } // sum\n", "/** Adds. */\nfunction add(a, b) { return a + b }\n"},
+		{"rust lifetimes", "lib.rs", "/// Docs.\nfn f<'a>(s: &'a str) {} // gone\n", "/// Docs.\nfn f<'a>(s: &'a str) {}\n"},
+		{"sql", "q.sql", "-- list users\nSELECT '--' FROM users;\n", "SELECT '--' FROM users;\n"},
+		{"unknown", "notes.txt", "# heading\n", "# heading\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, string(tr.Apply(tt.file, []byte(tt.in))))
+		})
+	}
+
+	_, err = generate.ParseTransforms([]string{"minify"})
+	assert.Error(t, err)
+}
+
+func TestRenderAppliesTransforms(t *testing.T) {
+	memfs := fs.NewMemFileSystem()
+	memfs.AddDir("/root")
+	memfs.AddFile("/root/a.js", "// one\nlet a = 1\n\n\n\nlet b = 2 // two\n")
+
+	tree, err := domain.BuildTree(memfs, "/root")
+	require.NoError(t, err)
+	state := domain.NewViewState(tree.Root.Path)
+	state = state.SetSelected("/root/a.js", true)
+
+	tr, err := generate.ParseTransforms([]string{"comments", "blank-lines"})
+	require.NoError(t, err)
+	var out strings.Builder
+	require.NoError(t, generate.RenderWithOptions(&out, "", tree, state, memfs, generate.Options{Transforms: tr}))
+	assert.Contains(t, out.String(), "```\nlet a = 1\n\nlet b = 2\n```")
+}
@@ -0,0 +1,105 @@
+package generate
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/eliooooooot/picky/internal/domain"
+	"github.com/eliooooooot/picky/internal/token"
+)
+
+// XMLWriter implements domain.OutputWriter with XML-style tags, which many
+// models parse more reliably than markdown headings
+type XMLWriter struct {
+	fileReader
+}
+
+// NewXMLWriter creates a new XML writer
+func NewXMLWriter() *XMLWriter {
+	return &XMLWriter{
+		fileReader: fileReader{Tokenizer: token.NaiveTokenizer{}},
+	}
+}
+
+// WriteStructure writes the directory structure inside a <directory_structure> tag
+func (xw *XMLWriter) WriteStructure(w io.Writer, root *domain.Node, state domain.ViewState) error {
+	if xw.Structure == StructureNone {
+		return nil
+	}
+	if _, err := fmt.Fprintln(w, "<directory_structure>"); err != nil {
+		return err
+	}
+	if err := xw.writeStructureTree(w, root, state); err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(w, "</directory_structure>\n\n"); err != nil {
+		return err
+	}
+	return nil
+}
+
+// WriteContent writes each selected file inside a <file> tag
+func (xw *XMLWriter) WriteContent(w io.Writer, paths []string, fs domain.FileSystem) error {
+	if err := xw.writeContentHeader(w); err != nil {
+		return err
+	}
+	for _, path := range paths {
+		if err := writeFileBlock(xw, w, path, fs); err != nil {
+			return err
+		}
+	}
+	return xw.writeContentFooter(w)
+}
+
+func (xw *XMLWriter) writePrompt(w io.Writer, prompt string) error {
+	if prompt == "" {
+		return nil
+	}
+	_, err := fmt.Fprintf(w, "<prompt>\n%s\n</prompt>\n\n", prompt)
+	return err
+}
+
+func (xw *XMLWriter) writePartHeader(w io.Writer, n, total int) error {
+	_, err := fmt.Fprintf(w, "<!-- Part %d of %d -->\n\n", n, total)
+	return err
+}
+
+func (xw *XMLWriter) writeContentHeader(w io.Writer) error {
+	_, err := fmt.Fprintln(w, "<files>")
+	return err
+}
+
+func (xw *XMLWriter) writeContentFooter(w io.Writer) error {
+	_, err := fmt.Fprintln(w, "</files>")
+	return err
+}
+
+func (xw *XMLWriter) writeBlock(w io.Writer, b block) error {
+	attrs := fmt.Sprintf(" name=%q", filepath.Base(b.Path))
+	if b.Start > 0 {
+		attrs += fmt.Sprintf(" lines=\"%d-%d\"", b.Start, b.End)
+	}
+	if b.Mode != "" && b.Mode != domain.IncludeFull {
+		attrs += fmt.Sprintf(" mode=%q", b.Mode)
+	}
+	if _, err := fmt.Fprintf(w, "<file%s>\n", attrs); err != nil {
+		return err
+	}
+	if _, err := w.Write(numberedContent(b)); err != nil {
+		return err
+	}
+	if !strings.HasSuffix(string(b.Content), "\n") {
+		if _, err := fmt.Fprintln(w); err != nil {
+			return err
+		}
+	}
+	if b.Omitted > 0 {
+		if _, err := fmt.Fprintf(w, "[truncated: %d lines omitted]\n", b.Omitted); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintln(w, "</file>")
+	return err
+}
@@ -2,6 +2,7 @@ package ignore
 
 import (
 	"bufio"
+	"path"
 	"path/filepath"
 	"sort"
 	"strings"
@@ -64,4 +65,19 @@ func Save(fs domain.FileSystem, root string, set map[string]struct{}) error {
 	}
 
 	return fs.WriteFile(ignoreFilePath, []byte(content), 0644)
-}
\ No newline at end of file
+}
+// MatchAny reports whether a slash-separated relative path matches any of
+// the glob patterns, either as a whole or by its base name
+func MatchAny(patterns []string, rel string) bool {
+	base := path.Base(rel)
+	for _, pattern := range patterns {
+		pattern = strings.TrimSuffix(filepath.ToSlash(pattern), "/")
+		if ok, _ := path.Match(pattern, rel); ok {
+			return true
+		}
+		if ok, _ := path.Match(pattern, base); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -138,4 +138,14 @@ file2.go
 path/to/nested/file.js
 `
 	assert.Equal(t, expected, result)
-}
\ No newline at end of file
+}
+func TestMatchAny(t *testing.T) {
+	patterns := []string{"*.lock", "dist/", "docs/*.md"}
+
+	assert.True(t, ignore.MatchAny(patterns, "go.lock"))
+	assert.True(t, ignore.MatchAny(patterns, "web/package.lock"), "base name match")
+	assert.True(t, ignore.MatchAny(patterns, "web/dist"), "trailing slash is ignored")
+	assert.True(t, ignore.MatchAny(patterns, "docs/intro.md"))
+	assert.False(t, ignore.MatchAny(patterns, "src/intro.md"))
+	assert.False(t, ignore.MatchAny(nil, "anything"))
+}
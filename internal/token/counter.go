@@ -8,6 +8,12 @@ import (
 type Counter struct {
 	FS        domain.FileSystem
 	Tokenizer Tokenizer
+	// MaxFileSize skips files larger than this many bytes, counting them as
+	// zero without reading them; zero or less counts every file
+	MaxFileSize int
+	// Transform, when set, changes file contents before they are counted,
+	// so counts match output that is transformed the same way
+	Transform func(path string, content []byte) []byte
 	cache     map[string]int // avoids re-reading files
 }
 
@@ -21,7 +27,8 @@ func NewCounter(fs domain.FileSystem, tz Tokenizer) *Counter {
 func (c *Counter) BuildTreeTokenMap(t *domain.Tree) (map[string]int, error) {
 	out := make(map[string]int)
 	for _, n := range t.Flatten() {
-		if n.IsDir {
+		// Links that are not followed are never read
+		if n.IsDir || n.Kind == domain.KindSymlink {
 			continue
 		}
 		tokens, err := c.tokensForFile(n.Path)
@@ -33,15 +40,49 @@ func (c *Counter) BuildTreeTokenMap(t *domain.Tree) (map[string]int, error) {
 	return out, nil
 }
 
+// AddChildTokens counts the files directly inside dir into out, for
+// directories a lazy tree has just loaded. Files that cannot be read are
+// left out and the first such error is returned.
+func (c *Counter) AddChildTokens(out map[string]int, dir *domain.Node) error {
+	var firstErr error
+	for _, n := range dir.Children {
+		if n.IsDir || n.Kind == domain.KindSymlink {
+			continue
+		}
+		tokens, err := c.tokensForFile(n.Path)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		out[n.Path] = tokens
+	}
+	return firstErr
+}
+
 // tokensForFile is cached per-path.
 func (c *Counter) tokensForFile(path string) (int, error) {
 	if v, ok := c.cache[path]; ok {
 		return v, nil
 	}
+	if c.MaxFileSize > 0 {
+		info, err := c.FS.Stat(path)
+		if err != nil {
+			return 0, err
+		}
+		if info.Size() > int64(c.MaxFileSize) {
+			c.cache[path] = 0
+			return 0, nil
+		}
+	}
 	bytes, err := c.FS.ReadFile(path)
 	if err != nil {
 		return 0, err
 	}
+	if c.Transform != nil {
+		bytes = c.Transform(path, bytes)
+	}
 	v := c.Tokenizer.CountTokens(string(bytes))
 	c.cache[path] = v
 	return v, nil
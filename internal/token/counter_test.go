@@ -133,4 +133,56 @@ func TestCounter_Caching(t *testing.T) {
 	if readCount["/file.txt"] != 1 {
 		t.Errorf("File was read %d times, expected 1", readCount["/file.txt"])
 	}
-}
\ No newline at end of file
+}
+
+func TestCounter_MaxFileSizeSkipsLargeFiles(t *testing.T) {
+	memfs := fs.NewMemFileSystem()
+	memfs.AddFile("/small.txt", "test")
+	memfs.AddFile("/big.log", "0123456789abcdef")
+
+	readCount := make(map[string]int)
+	trackingFS := &readTrackingFS{
+		FileSystem: memfs,
+		readCount:  readCount,
+	}
+
+	counter := NewCounter(trackingFS, NaiveTokenizer{})
+	counter.MaxFileSize = 8
+
+	tokens, err := counter.tokensForFile("/big.log")
+	if err != nil {
+		t.Fatalf("tokensForFile failed: %v", err)
+	}
+	if tokens != 0 {
+		t.Errorf("Token count for an oversized file = %d, want 0", tokens)
+	}
+	if readCount["/big.log"] != 0 {
+		t.Errorf("Oversized file was read %d times, expected 0", readCount["/big.log"])
+	}
+
+	tokens, err = counter.tokensForFile("/small.txt")
+	if err != nil {
+		t.Fatalf("tokensForFile failed: %v", err)
+	}
+	if tokens != 1 {
+		t.Errorf("Token count for a small file = %d, want 1", tokens)
+	}
+}
+
+func TestCounter_TransformChangesCounts(t *testing.T) {
+	memfs := fs.NewMemFileSystem()
+	memfs.AddFile("/a.txt", "0123456789abcdef")
+
+	counter := NewCounter(memfs, NaiveTokenizer{})
+	counter.Transform = func(path string, content []byte) []byte {
+		return content[:4]
+	}
+
+	tokens, err := counter.tokensForFile("/a.txt")
+	if err != nil {
+		t.Fatalf("tokensForFile failed: %v", err)
+	}
+	if tokens != 1 {
+		t.Errorf("Token count for a transformed file = %d, want 1", tokens)
+	}
+}
@@ -1,5 +1,11 @@
 package token
 
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
 // Tokenizer can be swapped for GPT-2, tiktoken, etc.
 type Tokenizer interface {
 	// CountTokens returns the number of tokens in the given text.
@@ -11,4 +17,43 @@ type NaiveTokenizer struct{}
 func (NaiveTokenizer) CountTokens(text string) int {
 	// 4 UTF-8 characters per token (round up).
 	return (len([]rune(text)) + 3) / 4
-}
\ No newline at end of file
+}
+
+// WordTokenizer estimates tokens from words and punctuation, which tracks
+// BPE tokenizers more closely than character counts for prose and code.
+type WordTokenizer struct{}
+
+func (WordTokenizer) CountTokens(text string) int {
+	words, punct := 0, 0
+	inWord := false
+	for _, r := range text {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_':
+			if !inWord {
+				words++
+				inWord = true
+			}
+		case unicode.IsSpace(r):
+			inWord = false
+		default:
+			punct++
+			inWord = false
+		}
+	}
+	// Roughly 4 tokens per 3 words, one per punctuation rune (round up).
+	return (words*4+2)/3 + punct
+}
+
+// Names lists the built-in tokenizers accepted by ByName
+var Names = []string{"naive", "words"}
+
+// ByName returns a built-in tokenizer
+func ByName(name string) (Tokenizer, error) {
+	switch strings.ToLower(name) {
+	case "", "naive":
+		return NaiveTokenizer{}, nil
+	case "words":
+		return WordTokenizer{}, nil
+	}
+	return nil, fmt.Errorf("unknown tokenizer %q (want one of %s)", name, strings.Join(Names, ", "))
+}
@@ -59,4 +59,37 @@ func TestNaiveTokenizer_CountTokens(t *testing.T) {
 			}
 		})
 	}
-}
\ No newline at end of file
+}
+func TestWordTokenizer_CountTokens(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		expected int
+	}{
+		{name: "empty string", text: "", expected: 0},
+		{name: "single word", text: "hello", expected: 2},
+		{name: "three words", text: "one two three", expected: 4},
+		{name: "punctuation counts individually", text: "a.b()", expected: 6},
+		{name: "identifiers with underscores", text: "snake_case_name", expected: 2},
+	}
+	
+	tokenizer := WordTokenizer{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tokenizer.CountTokens(tt.text); got != tt.expected {
+				t.Errorf("CountTokens(%q) = %d, want %d", tt.text, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestByName(t *testing.T) {
+	for _, name := range append([]string{""}, Names...) {
+		if _, err := ByName(name); err != nil {
+			t.Errorf("ByName(%q) returned error: %v", name, err)
+		}
+	}
+	if _, err := ByName("tiktoken"); err == nil {
+		t.Error("ByName should reject unknown tokenizers")
+	}
+}
@@ -0,0 +1,44 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/eliooooooot/picky/internal/domain"
+)
+
+// renderColumns renders one line per visible row of the tree with the
+// node's size, token count and age, for display to the right of the tree
+func (m *Model) renderColumns(now time.Time) string {
+	// A directory shows the total size and newest change of its visible files
+	agg := m.tree.Aggregates(m.state, m.tokens)
+	var lines []string
+	for _, node := range domain.Flatten(m.tree.Root, m.state) {
+		stats := agg.Of(node)
+		age := "-"
+		if !stats.ModTime.IsZero() {
+			age = formatAge(now.Sub(stats.ModTime))
+		}
+		lines = append(lines, fmt.Sprintf("%9s %7s %4s", formatSize(stats.Size), m.formatTokenTotal(node), age))
+	}
+	return m.settings.ColorScheme.helpStyle().Render(strings.Join(lines, "\n"))
+}
+
+// formatAge formats how long ago something happened in its largest whole unit
+func formatAge(d time.Duration) string {
+	const day = 24 * time.Hour
+	switch {
+	case d < time.Minute:
+		return "now"
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", d/time.Minute)
+	case d < day:
+		return fmt.Sprintf("%dh", d/time.Hour)
+	case d < 30*day:
+		return fmt.Sprintf("%dd", d/day)
+	case d < 365*day:
+		return fmt.Sprintf("%dmo", d/(30*day))
+	}
+	return fmt.Sprintf("%dy", d/(365*day))
+}
@@ -0,0 +1,178 @@
+package tui
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/eliooooooot/picky/internal/domain"
+)
+
+// commandHelpEntries lists the commands the palette understands
+var commandHelpEntries = []string{
+	"all",
+	"clear",
+	"invert",
+	"select|deselect GLOB",
+	"select|deselect ext EXT",
+	"select|deselect grep REGEX",
+	"… under DIR",
+}
+
+// newCommandInput creates the single-line input used by the command palette
+func newCommandInput() textinput.Model {
+	ti := textinput.New()
+	ti.Prompt = ":"
+	ti.Placeholder = "select *_test.go under internal/"
+	ti.CharLimit = 512
+	return ti
+}
+
+// openCommand shows the command palette
+func (m *Model) openCommand() tea.Cmd {
+	m.inCommandMode = true
+	m.command.Reset()
+	return m.command.Focus()
+}
+
+// closeCommand hides the command palette
+func (m *Model) closeCommand() {
+	m.inCommandMode = false
+	m.command.Blur()
+}
+
+// updateCommand handles keyboard input while the command palette is open
+func (m *Model) updateCommand(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch {
+	case key.Matches(msg, m.keys.Cancel):
+		m.closeCommand()
+		return m, nil
+	case msg.Type == tea.KeyEnter:
+		line := m.command.Value()
+		m.closeCommand()
+		if strings.TrimSpace(line) == "" {
+			return m, nil
+		}
+		status, err := m.runCommand(line)
+		if err != nil {
+			status = err.Error()
+		}
+		m.statusMessage = status
+		m.statusMessageTimer = 1
+		m.ensureCursorVisible()
+		return m, tea.Tick(2*time.Second, func(time.Time) tea.Msg {
+			return clearStatusMsg{}
+		})
+	}
+	var cmd tea.Cmd
+	m.command, cmd = m.command.Update(msg)
+	return m, cmd
+}
+
+// runCommand parses and applies one palette command, returning a status message.
+//
+//	all | clear | invert                 the whole tree, or the cursor's subtree for invert
+//	select|deselect GLOB                 files whose name (or relative path, if GLOB has a /) matches
+//	select|deselect ext EXT              files with the extension
+//	select|deselect grep REGEX           files whose contents match
+//
+// Any command may end with "under DIR" to limit it to a directory relative to the root.
+func (m *Model) runCommand(line string) (string, error) {
+	fields := strings.Fields(line)
+	root := m.tree.Root
+
+	dir := root
+	where := ""
+	if n := len(fields); n >= 2 && fields[n-2] == "under" {
+		node, err := m.commandDir(fields[n-1])
+		if err != nil {
+			return "", err
+		}
+		dir = node
+		where = " under " + fields[n-1]
+		fields = fields[:n-2]
+	}
+	if len(fields) == 0 {
+		return "", fmt.Errorf("missing command before %q", "under")
+	}
+
+	verb, args := fields[0], fields[1:]
+	switch verb {
+	case "all":
+		m.setSelection(domain.SelectAll(dir, m.state))
+		return "Selected all files" + where, nil
+	case "clear", "none":
+		m.state = domain.ClearAll(dir, m.state)
+		return "Cleared selection" + where, nil
+	case "invert":
+		if dir == root {
+			m.setSelection(domain.InvertSubtree(root, m.state))
+			return "Inverted selection under cursor", nil
+		}
+		m.setSelection(domain.InvertRange([]*domain.Node{dir}, m.state))
+		return "Inverted selection" + where, nil
+	case "select", "deselect":
+	default:
+		return "", fmt.Errorf("unknown command %q (try all, clear, invert, select or deselect)", verb)
+	}
+
+	selected := verb == "select"
+	if len(args) == 1 && args[0] == "all" {
+		if selected {
+			m.setSelection(domain.SelectAll(dir, m.state))
+			return "Selected all files" + where, nil
+		}
+		m.state = domain.ClearAll(dir, m.state)
+		return "Cleared selection" + where, nil
+	}
+
+	var match domain.Matcher
+	var what string
+	switch {
+	case len(args) == 0:
+		return "", fmt.Errorf("usage: %s GLOB | ext EXT | grep REGEX [under DIR]", verb)
+	case args[0] == "ext" && len(args) == 2:
+		match = domain.ExtensionMatcher(args[1])
+		what = "with extension " + args[1]
+	case args[0] == "grep" && len(args) >= 2:
+		// Spaces in the expression are collapsed to one
+		expr := strings.Join(args[1:], " ")
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return "", fmt.Errorf("invalid regex %q: %w", expr, err)
+		}
+		match = domain.ContentMatcher(m.fsys, re, m.maxFileSize)
+		what = "containing " + expr
+	case len(args) == 1:
+		glob, err := domain.GlobMatcher(root, args[0])
+		if err != nil {
+			return "", err
+		}
+		match = glob
+		what = "matching " + args[0]
+	default:
+		return "", fmt.Errorf("usage: %s GLOB | ext EXT | grep REGEX [under DIR]", verb)
+	}
+
+	next, count := domain.SetSelectedWhere(dir, m.state, match, selected)
+	m.setSelection(next)
+	if selected {
+		return fmt.Sprintf("Selected %d files %s%s", count, what, where), nil
+	}
+	return fmt.Sprintf("Deselected %d files %s%s", count, what, where), nil
+}
+
+// commandDir resolves a directory argument relative to the tree root
+func (m *Model) commandDir(arg string) (*domain.Node, error) {
+	path := filepath.Join(m.tree.Root.Path, filepath.FromSlash(arg))
+	node := domain.FindNodeByPath(m.tree.Root, path)
+	if node == nil || !node.IsDir {
+		return nil, fmt.Errorf("no directory %q", arg)
+	}
+	return node, nil
+}
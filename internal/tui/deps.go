@@ -0,0 +1,146 @@
+package tui
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/eliooooooot/picky/internal/domain"
+)
+
+// depsListHeight is how many packages the dependency preview lists
+const depsListHeight = 12
+
+// depsPlan is a dependency selection waiting for confirmation
+type depsPlan struct {
+	title string
+	files []*domain.Node
+	// before and after are the selected token totals around the change
+	before, after int
+}
+
+// planDeps previews selecting the in-module packages imported by the Go
+// files at the cursor, or with dependents the files importing them
+func (m *Model) planDeps(dependents bool) tea.Cmd {
+	cursor := domain.FindNodeByPath(m.tree.Root, m.state.CursorPath)
+	if cursor == nil {
+		return nil
+	}
+	start := domain.GoFilesAt(cursor)
+	if len(start) == 0 {
+		return m.flashStatus("No Go files at the cursor")
+	}
+
+	graph, err := domain.BuildImportGraph(m.tree.Root, m.fsys, m.maxFileSize)
+	if err != nil {
+		return m.flashStatus(fmt.Sprintf("Cannot resolve imports: %v", err))
+	}
+
+	what := "Dependencies"
+	files := graph.Dependencies(start, m.depsDepth)
+	if dependents {
+		what = "Dependents"
+		files = graph.Dependents(start, m.depsDepth)
+	}
+	if len(files) == 0 {
+		return m.flashStatus(fmt.Sprintf("No in-module %s of %s", strings.ToLower(what), cursor.Name))
+	}
+
+	depth := "all levels"
+	if m.depsDepth > 0 {
+		depth = fmt.Sprintf("depth %d", m.depsDepth)
+	}
+	plan := &depsPlan{
+		title:  fmt.Sprintf("%s of %s (%s)", what, cursor.Name, depth),
+		files:  files,
+		before: m.selectedTokens(),
+	}
+	plan.after = plan.before
+	for _, file := range files {
+		if !m.state.IsSelected(file.Path) {
+			plan.after += m.tokens[file.Path]
+		}
+	}
+	m.depsPlan = plan
+	return nil
+}
+
+// updateDepsPreview handles keyboard input while a dependency selection is previewed
+func (m *Model) updateDepsPreview(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch {
+	case key.Matches(msg, m.keys.Confirm):
+		m.setSelection(domain.SetRangeSelected(m.depsPlan.files, m.state, true))
+		n := len(m.depsPlan.files)
+		m.depsPlan = nil
+		return m, m.flashStatus(fmt.Sprintf("Selected %d files", n))
+	case key.Matches(msg, m.keys.Cancel, m.keys.Dependencies, m.keys.Dependents):
+		m.depsPlan = nil
+	}
+	return m, nil
+}
+
+// renderDepsPreview lists the packages a dependency selection adds and its token cost
+func (m *Model) renderDepsPreview() string {
+	plan := m.depsPlan
+	scheme := m.settings.ColorScheme
+
+	modalStyle := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(scheme.promptBorder(false)).
+		Padding(1, 2).
+		Width(60)
+
+	var content strings.Builder
+	content.WriteString(scheme.headerStyle().Render(plan.title))
+	content.WriteString("\n\n")
+	content.WriteString(fmt.Sprintf("~%s → ~%s tokens (+%s)",
+		formatTokenCount(plan.before), formatTokenCount(plan.after), formatTokenCount(plan.after-plan.before)))
+	content.WriteString("\n\n")
+
+	// Group files by package directory, keeping first-seen order
+	type pkg struct {
+		dir          string
+		files, added int
+		tokens       int
+	}
+	var pkgs []*pkg
+	byDir := make(map[string]*pkg)
+	for _, file := range plan.files {
+		dir := filepath.Dir(file.Path)
+		p, ok := byDir[dir]
+		if !ok {
+			p = &pkg{dir: m.relPath(dir)}
+			byDir[dir] = p
+			pkgs = append(pkgs, p)
+		}
+		p.files++
+		if !m.state.IsSelected(file.Path) {
+			p.added++
+			p.tokens += m.tokens[file.Path]
+		}
+	}
+
+	for i, p := range pkgs {
+		if i == depsListHeight {
+			content.WriteString(scheme.helpStyle().Render(fmt.Sprintf("… %d more packages", len(pkgs)-i)))
+			content.WriteString("\n")
+			break
+		}
+		line := fmt.Sprintf("+ %s (%d files, ~%s)", p.dir, p.files, formatTokenCount(p.tokens))
+		if p.added == 0 {
+			line = scheme.helpStyle().Render(fmt.Sprintf("✓ %s (already selected)", p.dir))
+		}
+		content.WriteString(line)
+		content.WriteString("\n")
+	}
+	content.WriteString("\n")
+
+	helpStyle := scheme.helpStyle().Italic(true)
+	content.WriteString(helpStyle.Render(fmt.Sprintf("%s select  %s cancel",
+		m.keys.Confirm.Help().Key, m.keys.Cancel.Help().Key)))
+
+	return modalStyle.Render(content.String())
+}
@@ -0,0 +1,31 @@
+package tui
+
+import (
+	"fmt"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/eliooooooot/picky/internal/domain"
+)
+
+// toggleForced forces the binary or generated file at the cursor into the
+// output, selecting it, or returns it to being written as a placeholder
+func (m *Model) toggleForced() tea.Cmd {
+	cursor := domain.FindNodeByPath(m.tree.Root, m.state.CursorPath)
+	if cursor == nil || cursor.IsDir {
+		return nil
+	}
+	if cursor.Kind == domain.KindSymlink {
+		return m.flashStatus(fmt.Sprintf("%s is a link that is not followed", cursor.Name))
+	}
+	if cursor.Kind == domain.KindText {
+		return m.flashStatus(fmt.Sprintf("%s is already included in full", cursor.Name))
+	}
+
+	if m.state.IsForced(cursor.Path) {
+		m.state = m.state.SetForced(cursor.Path, false)
+		return m.flashStatus(fmt.Sprintf("%s will be omitted as a %s file", cursor.Name, cursor.Kind))
+	}
+	m.setSelection(domain.SetRangeSelected([]*domain.Node{cursor}, m.state, true))
+	m.state = m.state.SetForced(cursor.Path, true)
+	return m.flashStatus(fmt.Sprintf("Forced %s into the output", cursor.Name))
+}
@@ -0,0 +1,317 @@
+package tui
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+)
+
+// KeyMap holds every key binding the TUI responds to
+type KeyMap struct {
+	Up       key.Binding
+	Down     key.Binding
+	Collapse key.Binding
+	Expand   key.Binding
+	Toggle   key.Binding
+	Pin      key.Binding
+	Fit      key.Binding
+	Exclude  key.Binding
+	Prompt   key.Binding
+	Settings key.Binding
+	Generate key.Binding
+	Copy     key.Binding
+	Help     key.Binding
+	Quit     key.Binding
+
+	// Visual starts a range; Toggle, Deselect, Invert and Exclude then apply to it
+	Visual   key.Binding
+	Deselect key.Binding
+	Invert   key.Binding
+
+	// Command opens the palette for bulk selection commands
+	Command key.Binding
+
+	// Search finds files by content; SelectFuncs selects the Go
+	// declarations around the matches from the results list
+	Search      key.Binding
+	SelectFuncs key.Binding
+
+	// Dependencies and Dependents select the Go packages the cursor's
+	// files import, or the files importing them, after a preview
+	Dependencies key.Binding
+	Dependents   key.Binding
+
+	// Pair toggles the tests paired with the cursor's file, or its source
+	Pair key.Binding
+
+	// Sort cycles the tree's sort order; ReverseSort flips its direction
+	Sort        key.Binding
+	ReverseSort key.Binding
+
+	// ToggleHidden shows or hides dotfiles outside the hidden allowlist
+	ToggleHidden key.Binding
+
+	// Force writes a binary or generated file to the output instead of a placeholder
+	Force key.Binding
+
+	// Outline writes the cursor's file as its declarations and signatures only
+	Outline key.Binding
+
+	// CycleMode moves the cursor's files through the inclusion modes: full,
+	// outline, head, diff and path only
+	CycleMode key.Binding
+
+	// Confirm and Cancel are used inside modals and prompt mode
+	Confirm key.Binding
+	Cancel  key.Binding
+
+	// ForceQuit quits from any mode, including prompt mode
+	ForceQuit key.Binding
+}
+
+// DefaultKeyMap returns the built-in key bindings (the vim preset)
+func DefaultKeyMap() KeyMap {
+	return VimKeyMap()
+}
+
+// VimKeyMap returns bindings with arrow keys and hjkl navigation
+func VimKeyMap() KeyMap {
+	return KeyMap{
+		Up:           newBinding("up", "up", "k"),
+		Down:         newBinding("down", "down", "j"),
+		Collapse:     newBinding("collapse", "left", "h"),
+		Expand:       newBinding("expand", "right", "l", "enter"),
+		Toggle:       newBinding("select", " "),
+		Pin:          newBinding("pin", "P"),
+		Fit:          newBinding("fit to budget", "f"),
+		Exclude:      newBinding("exclude", "x"),
+		Prompt:       newBinding("prompt", "p"),
+		Settings:     newBinding("settings", "s"),
+		Generate:     newBinding("generate", "g"),
+		Copy:         newBinding("copy to clipboard", "c"),
+		Help:         newBinding("help", "?"),
+		Quit:         newBinding("quit", "q"),
+		Visual:       newBinding("visual range", "v"),
+		Deselect:     newBinding("deselect range", "u"),
+		Invert:       newBinding("invert range", "i"),
+		Command:      newBinding("command palette", ":"),
+		Search:       newBinding("search contents", "/"),
+		SelectFuncs:  newBinding("select enclosing functions", "F"),
+		Dependencies: newBinding("select dependencies", "d"),
+		Dependents:   newBinding("select dependents", "D"),
+		Pair:         newBinding("toggle paired test/source", "t"),
+		Force:        newBinding("force binary/generated file", "!"),
+		Outline:      newBinding("outline file (signatures only)", "S"),
+		CycleMode:    newBinding("cycle inclusion mode", "m"),
+		Sort:         newBinding("cycle sort order", "o"),
+		ReverseSort:  newBinding("reverse sort", "O"),
+		ToggleHidden: newBinding("show/hide hidden files", "."),
+		Confirm:      newBinding("confirm", "enter", "y"),
+		Cancel:       newBinding("close", "esc"),
+		ForceQuit:    newBinding("quit from any mode", "ctrl+c"),
+	}
+}
+
+// EmacsKeyMap returns bindings with arrow keys and emacs-style control navigation
+func EmacsKeyMap() KeyMap {
+	km := VimKeyMap()
+	km.Up.SetKeys("up", "ctrl+p")
+	km.Down.SetKeys("down", "ctrl+n")
+	km.Collapse.SetKeys("left", "ctrl+b")
+	km.Expand.SetKeys("right", "ctrl+f", "enter")
+	km.Toggle.SetKeys(" ", "ctrl+@")
+	km.Cancel.SetKeys("esc", "ctrl+g")
+	km.ForceQuit.SetKeys("ctrl+c", "ctrl+x")
+	return km
+}
+
+// KeyMapPreset returns a built-in preset by name
+func KeyMapPreset(name string) (KeyMap, error) {
+	switch strings.ToLower(name) {
+	case "", "default", "vim":
+		return VimKeyMap(), nil
+	case "emacs":
+		return EmacsKeyMap(), nil
+	}
+	return KeyMap{}, fmt.Errorf("unknown keymap preset %q (want vim or emacs)", name)
+}
+
+// WithOverrides returns a copy of the keymap with the named actions rebound.
+// Action names are the snake_case field names, e.g. "up" or "force_quit".
+func (k KeyMap) WithOverrides(overrides map[string][]string) (KeyMap, error) {
+	named := k.named()
+
+	// Sort for deterministic error reporting
+	actions := make([]string, 0, len(overrides))
+	for action := range overrides {
+		actions = append(actions, action)
+	}
+	sort.Strings(actions)
+
+	for _, action := range actions {
+		b, ok := named[action]
+		if !ok {
+			return KeyMap{}, fmt.Errorf("unknown key action %q", action)
+		}
+		keys := overrides[action]
+		if len(keys) == 0 {
+			b.SetEnabled(false)
+			continue
+		}
+		b.SetKeys(keys...)
+		b.SetHelp(formatKeys(keys[:1]), b.Help().Desc)
+	}
+	return k, nil
+}
+
+// ShortHelp returns the bindings shown in the one-line help, after navigation
+func (k KeyMap) ShortHelp() []key.Binding {
+	return []key.Binding{
+		k.Toggle, k.Exclude, k.Prompt, k.Settings, k.Generate, k.Copy, k.Help, k.Quit,
+	}
+}
+
+// FullHelp returns the bindings shown in the help overlay, grouped by purpose
+func (k KeyMap) FullHelp() [][]key.Binding {
+	return [][]key.Binding{
+		{k.Up, k.Down, k.Collapse, k.Expand},
+		{k.Toggle, k.Pin, k.Fit, k.Exclude, k.Force, k.Outline, k.CycleMode},
+		{k.Visual, k.Deselect, k.Invert},
+		{k.Command, k.Search, k.SelectFuncs},
+		{k.Dependencies, k.Dependents, k.Pair},
+		{k.Sort, k.ReverseSort, k.ToggleHidden},
+		{k.Prompt, k.Settings, k.Generate, k.Copy},
+		{k.Help, k.Quit, k.ForceQuit},
+	}
+}
+
+// VisualHelpEntries formats the bindings available in visual mode
+func (k KeyMap) VisualHelpEntries() []string {
+	entries := []struct {
+		b    key.Binding
+		desc string
+	}{
+		{k.Toggle, "select range"},
+		{k.Deselect, k.Deselect.Help().Desc},
+		{k.Invert, k.Invert.Help().Desc},
+		{k.Exclude, "exclude range"},
+		{k.Cancel, "leave visual mode"},
+	}
+	var out []string
+	if k.Up.Enabled() && k.Down.Enabled() {
+		out = append(out, k.Up.Help().Key+"/"+k.Down.Help().Key+" extend")
+	}
+	for _, e := range entries {
+		if e.b.Enabled() {
+			out = append(out, e.b.Help().Key+" "+e.desc)
+		}
+	}
+	return out
+}
+
+// ShortHelpEntries formats navigation pairs and ShortHelp as "key description" strings
+func (k KeyMap) ShortHelpEntries() []string {
+	var entries []string
+	if k.Up.Enabled() && k.Down.Enabled() {
+		entries = append(entries, k.Up.Help().Key+"/"+k.Down.Help().Key+" navigate")
+	}
+	if k.Collapse.Enabled() && k.Expand.Enabled() {
+		entries = append(entries, k.Collapse.Help().Key+"/"+k.Expand.Help().Key+" collapse/expand")
+	}
+	for _, b := range k.ShortHelp() {
+		if !b.Enabled() {
+			continue
+		}
+		entries = append(entries, b.Help().Key+" "+b.Help().Desc)
+	}
+	return entries
+}
+
+// FullHelpLines formats FullHelp as aligned "keys  description" lines,
+// with a blank line between groups
+func (k KeyMap) FullHelpLines() []string {
+	var lines []string
+	for i, group := range k.FullHelp() {
+		if i > 0 {
+			lines = append(lines, "")
+		}
+		for _, b := range group {
+			if !b.Enabled() {
+				continue
+			}
+			lines = append(lines, fmt.Sprintf("%-14s %s", formatKeys(b.Keys()), b.Help().Desc))
+		}
+	}
+	return lines
+}
+
+// named maps action names to pointers into the keymap
+func (k *KeyMap) named() map[string]*key.Binding {
+	return map[string]*key.Binding{
+		"up":               &k.Up,
+		"down":             &k.Down,
+		"collapse":         &k.Collapse,
+		"expand":           &k.Expand,
+		"toggle":           &k.Toggle,
+		"pin":              &k.Pin,
+		"fit":              &k.Fit,
+		"exclude":          &k.Exclude,
+		"prompt":           &k.Prompt,
+		"settings":         &k.Settings,
+		"generate":         &k.Generate,
+		"copy":             &k.Copy,
+		"help":             &k.Help,
+		"quit":             &k.Quit,
+		"visual":           &k.Visual,
+		"deselect":         &k.Deselect,
+		"invert":           &k.Invert,
+		"command":          &k.Command,
+		"search":           &k.Search,
+		"select_functions": &k.SelectFuncs,
+		"dependencies":     &k.Dependencies,
+		"dependents":       &k.Dependents,
+		"pair":             &k.Pair,
+		"force":            &k.Force,
+		"outline":          &k.Outline,
+		"cycle_mode":       &k.CycleMode,
+		"sort":             &k.Sort,
+		"reverse_sort":     &k.ReverseSort,
+		"toggle_hidden":    &k.ToggleHidden,
+		"confirm":          &k.Confirm,
+		"cancel":           &k.Cancel,
+		"force_quit":       &k.ForceQuit,
+	}
+}
+
+func newBinding(desc string, keys ...string) key.Binding {
+	return key.NewBinding(
+		key.WithKeys(keys...),
+		key.WithHelp(formatKeys(keys[:1]), desc),
+	)
+}
+
+// formatKeys renders key names the way they appear in help text
+func formatKeys(keys []string) string {
+	pretty := make([]string, len(keys))
+	for i, k := range keys {
+		switch k {
+		case "up":
+			pretty[i] = "↑"
+		case "down":
+			pretty[i] = "↓"
+		case "left":
+			pretty[i] = "←"
+		case "right":
+			pretty[i] = "→"
+		case " ":
+			pretty[i] = "space"
+		case "ctrl+@":
+			pretty[i] = "ctrl+space"
+		default:
+			pretty[i] = k
+		}
+	}
+	return strings.Join(pretty, "/")
+}
@@ -0,0 +1,103 @@
+package tui_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/eliooooooot/picky/internal/domain"
+	"github.com/eliooooooot/picky/internal/tui"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func keymapTestModel() *tui.Model {
+	root := &domain.Node{Path: "/root", Name: "root", IsDir: true}
+	file := &domain.Node{Path: "/root/a.txt", Name: "a.txt", Parent: root}
+	root.Children = []*domain.Node{file}
+
+	ignores := make(map[string]struct{})
+	model := tui.NewModel(&domain.Tree{Root: root}, &ignores)
+	model.Init()
+	return model
+}
+
+func TestKeyMapPresets(t *testing.T) {
+	vim, err := tui.KeyMapPreset("vim")
+	require.NoError(t, err)
+	assert.True(t, key.Matches(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'j'}}, vim.Down))
+
+	emacs, err := tui.KeyMapPreset("emacs")
+	require.NoError(t, err)
+	assert.True(t, key.Matches(tea.KeyMsg{Type: tea.KeyCtrlN}, emacs.Down))
+	assert.False(t, key.Matches(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'j'}}, emacs.Down))
+
+	_, err = tui.KeyMapPreset("nano")
+	assert.Error(t, err)
+}
+
+func TestKeyMapOverrides(t *testing.T) {
+	t.Run("rebinds and updates help", func(t *testing.T) {
+		keys, err := tui.DefaultKeyMap().WithOverrides(map[string][]string{"generate": {"G"}})
+		require.NoError(t, err)
+
+		assert.Equal(t, []string{"G"}, keys.Generate.Keys())
+		assert.Contains(t, keys.ShortHelpEntries(), "G generate")
+
+		// The original keymap is unchanged
+		assert.Equal(t, []string{"g"}, tui.DefaultKeyMap().Generate.Keys())
+	})
+
+	t.Run("empty list disables an action", func(t *testing.T) {
+		keys, err := tui.DefaultKeyMap().WithOverrides(map[string][]string{"exclude": {}})
+		require.NoError(t, err)
+
+		assert.False(t, keys.Exclude.Enabled())
+		for _, entry := range keys.ShortHelpEntries() {
+			assert.NotContains(t, entry, "exclude")
+		}
+	})
+
+	t.Run("unknown action is an error", func(t *testing.T) {
+		_, err := tui.DefaultKeyMap().WithOverrides(map[string][]string{"teleport": {"t"}})
+		assert.Error(t, err)
+	})
+}
+
+func TestReboundKeysDriveModel(t *testing.T) {
+	model := keymapTestModel()
+	keys, err := tui.DefaultKeyMap().WithOverrides(map[string][]string{"down": {"n"}})
+	require.NoError(t, err)
+	model.SetKeyMap(keys)
+
+	model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'j'}})
+	assert.Equal(t, "/root", model.State().CursorPath, "old binding no longer moves the cursor")
+
+	model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'n'}})
+	assert.Equal(t, "/root/a.txt", model.State().CursorPath)
+}
+
+func TestHelpLineAndOverlayComeFromKeyMap(t *testing.T) {
+	model := keymapTestModel()
+	keys, err := tui.DefaultKeyMap().WithOverrides(map[string][]string{"copy": {"y"}})
+	require.NoError(t, err)
+	model.SetKeyMap(keys)
+
+	view := model.View()
+	assert.Contains(t, view, "y copy to clipboard")
+	assert.Contains(t, view, "↑/↓ navigate")
+	assert.NotContains(t, view, "Keys")
+
+	model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'?'}})
+	view = model.View()
+	assert.Contains(t, view, "Keys")
+	assert.True(t, strings.Contains(view, "↑/k") && strings.Contains(view, "→/l/enter"))
+
+	// Keys other than help/cancel are swallowed while the overlay is open
+	model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'j'}})
+	assert.Equal(t, "/root", model.State().CursorPath)
+
+	model.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	assert.NotContains(t, model.View(), "Keys")
+}
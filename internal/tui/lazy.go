@@ -0,0 +1,105 @@
+package tui
+
+import (
+	"github.com/eliooooooot/picky/internal/domain"
+	"github.com/eliooooooot/picky/internal/token"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// dirLoadedMsg carries the children of a directory read in the background
+type dirLoadedMsg struct {
+	dir      *domain.Node
+	children []*domain.Node
+}
+
+// loadDir starts reading an unloaded directory in the background. The tree
+// shows a loading placeholder on it until the children arrive.
+func (m *Model) loadDir(dir *domain.Node) tea.Cmd {
+	if m.loading[dir.Path] {
+		return nil
+	}
+	if m.loading == nil {
+		m.loading = make(map[string]bool)
+	}
+	// Capture the read here, since a selection may load the directory on
+	// this goroutine while the command runs
+	read := dir.ChildReader()
+	if read == nil {
+		return nil
+	}
+	m.loading[dir.Path] = true
+	m.vp.SetContent(m.renderWholeTree())
+	return func() tea.Msg {
+		return dirLoadedMsg{dir: dir, children: read()}
+	}
+}
+
+// expand opens the directory under the cursor, loading it first if needed,
+// or moves into its first child
+func (m *Model) expand() tea.Cmd {
+	cursor := domain.FindNodeByPath(m.tree.Root, m.state.CursorPath)
+	if cursor != nil && cursor.IsDir && !cursor.IsLoaded() {
+		return m.loadDir(cursor)
+	}
+	m.state = domain.NavigateIn(m.tree.Root, m.state)
+	// Re-render tree when opening directories
+	m.vp.SetContent(m.renderWholeTree())
+	m.ensureCursorVisible()
+	return nil
+}
+
+// attachLoaded adds background-read children to the tree and opens their directory
+func (m *Model) attachLoaded(msg dirLoadedMsg) {
+	delete(m.loading, msg.dir.Path)
+	msg.dir.Attach(msg.children)
+	m.state = m.state.SetOpen(msg.dir.Path, true)
+	m.syncLoaded()
+	m.vp.SetContent(m.renderWholeTree())
+	m.ensureCursorVisible()
+}
+
+// syncLoaded counts and sorts the children of directories loaded since the
+// last call, whether by expanding them or by a selection reaching into them
+func (m *Model) syncLoaded() {
+	loaded := m.tree.TakeLoaded()
+	if len(loaded) == 0 {
+		return
+	}
+	var tc *token.Counter
+	if m.tokens != nil {
+		tz := m.output.Tokenizer
+		if tz == nil {
+			tz = token.NaiveTokenizer{}
+		}
+		tc = token.NewCounter(m.fsys, tz)
+		tc.MaxFileSize = m.maxFileSize
+		if m.countTransformed {
+			tc.Transform = m.output.Transforms.Apply
+		}
+	}
+	if tc != nil {
+		for _, dir := range loaded {
+			// Unreadable files stay uncounted rather than failing the load
+			_ = tc.AddChildTokens(m.tokens, dir)
+		}
+		// The counts were added in place, so cached totals are stale
+		m.tree.Reindex()
+	}
+	// New totals can reorder the ancestors too, so sort from the root
+	domain.SortTree(m.tree.Root, m.settings.SortOrder, m.settings.SortDirection, m.tokens)
+}
+
+// formatTokenTotal formats a node's token count, marking totals that leave
+// out directories not loaded yet: "?" when nothing is known, "12+" otherwise
+func (m *Model) formatTokenTotal(node *domain.Node) string {
+	tok, complete := m.tokenTotal(node)
+	switch {
+	case complete:
+		return m.formatTokens(tok)
+	case !node.IsLoaded():
+		return "?"
+	default:
+		return m.formatTokens(tok) + "+"
+	}
+}
@@ -6,12 +6,17 @@ import (
 	"github.com/eliooooooot/picky/internal/domain"
 	"github.com/eliooooooot/picky/internal/fs"
 	"github.com/eliooooooot/picky/internal/generate"
+	"github.com/eliooooooot/picky/internal/token"
+	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 	
 	"github.com/atotto/clipboard"
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/textarea"
+	"github.com/charmbracelet/bubbles/textinput"
 	"github.com/charmbracelet/bubbles/viewport"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/charmbracelet/lipgloss/tree"
@@ -32,14 +37,47 @@ type Model struct {
 	settings           Settings
 	isSettingsOpen     bool
 	settingsCursorIdx  int
+	settingsOnOpen     Settings
+	treeTop            int
+	visualAnchor       string
+	visualPaths        map[string]bool
+	saveSettings       func(prev, s Settings) error
+	recountTokens      func(token.Tokenizer) (map[string]int, error)
 	prompt             textarea.Model
 	inPromptMode       bool
+	command            textinput.Model
+	inCommandMode      bool
+	searchInput        textinput.Model
+	inSearchInput      bool
+	searchQuery        string
+	searchResults      []domain.SearchResult
+	searchCursor       int
+	searchGen          int
+	fsys               domain.FileSystem
+	budget             int
+	budgetPlan         *domain.BudgetPlan
+	depsDepth          int
+	depsPlan           *depsPlan
+	pairRules          []domain.PairRule
+	maxFileSize        int
+	countTransformed   bool
+	loading            map[string]bool
+	secrets            map[string]int
+	scanQueue          map[string]bool
+	scanning           map[string]bool
+	modeTokens         map[string]int
+	countQueue         map[string]modeJob
+	counting           map[string]bool
+	modeGen            int
+	structure          structureCache
+	output             generate.Options
+	keys               KeyMap
+	isHelpOpen         bool
 }
 
 // NewModel creates a new TUI model
 func NewModel(tree *domain.Tree, existingIgnores *map[string]struct{}) *Model {
 	ta := textarea.New()
-	ta.Placeholder = "press p to add a prompt"
 	ta.Prompt = "» "
 	ta.CharLimit = 4096
 	ta.ShowLineNumbers = false
@@ -47,16 +85,24 @@ func NewModel(tree *domain.Tree, existingIgnores *map[string]struct{}) *Model {
 	
 	vp := viewport.New(0, 0) // Initialize with zero size, will be set on WindowSizeMsg
 	
-	return &Model{
+	m := &Model{
 		tree:           tree,
 		state:          domain.NewViewState(tree.Root.Path),
 		vp:             vp,
 		paperHeight:    20,
 		newIgnores:     make(map[string]struct{}),
 		existingIgnores: existingIgnores,
-		settings:       defaultSettings(),
+		settings:       DefaultSettings(),
 		prompt:         ta,
+		command:        newCommandInput(),
+		searchInput:    newSearchInput(),
+		fsys:           fs.NewOSFileSystem(),
+		keys:           DefaultKeyMap(),
+		depsDepth:      1,
+		pairRules:      domain.DefaultPairRules(),
 	}
+	m.prompt.Placeholder = m.promptPlaceholder()
+	return m
 }
 
 // Tree returns the internal tree (for app layer to access after TUI exits)
@@ -80,46 +126,148 @@ func (m *Model) NewIgnores() map[string]struct{} {
 }
 
 // SetTokens injects the file-level token map
-func (m *Model) SetTokens(t map[string]int) { m.tokens = t }
+func (m *Model) SetTokens(t map[string]int) {
+	m.tokens = t
+	m.structure = structureCache{}
+}
+
+// SetKeyMap replaces the key bindings
+func (m *Model) SetKeyMap(keys KeyMap) {
+	m.keys = keys
+	if !m.inPromptMode {
+		m.prompt.Placeholder = m.promptPlaceholder()
+	}
+}
+
+// promptPlaceholder tells the user which key opens the prompt
+func (m *Model) promptPlaceholder() string {
+	return fmt.Sprintf("press %s to add a prompt", m.keys.Prompt.Help().Key)
+}
+
+// SetFileSystem sets the filesystem used to read file contents for copying and content search
+func (m *Model) SetFileSystem(fsys domain.FileSystem) { m.fsys = fsys }
+
+// SetBudget sets the token budget used by the fit-to-budget action
+func (m *Model) SetBudget(budget int) { m.budget = budget }
+
+// SetDepsDepth sets how many import levels the dependency actions follow; 0 means no limit
+func (m *Model) SetDepsDepth(depth int) { m.depsDepth = depth }
+
+// SetPairRules sets the rules that pair source files with their tests
+func (m *Model) SetPairRules(rules []domain.PairRule) { m.pairRules = rules }
+
+// SetHiddenAllow sets the dotfiles that stay visible while hidden files are hidden
+func (m *Model) SetHiddenAllow(patterns []string) {
+	m.state = m.state.SetHiddenAllow(patterns)
+}
+
+// SetMaxFileSize sets the size in bytes above which files show their size
+// instead of a token count; zero or less disables it
+func (m *Model) SetMaxFileSize(n int) { m.maxFileSize = n }
+
+// SetCountTransformed makes token counts for newly loaded files reflect the
+// output's content transforms
+func (m *Model) SetCountTransformed(on bool) { m.countTransformed = on }
+
+// SetMaxTokensPerPart sets the per-part limit used to report how many parts output will need
+func (m *Model) SetMaxTokensPerPart(n int) { m.output.MaxTokensPerPart = n }
+
+// SetOutputOptions sets the format and tokenizer used for copying and part estimates
+func (m *Model) SetOutputOptions(opts generate.Options) {
+	m.output = opts
+	m.structure = structureCache{}
+}
+
+// SetSettings replaces the TUI preferences and applies them to the tree
+func (m *Model) SetSettings(s Settings) {
+	prev := m.settings
+	m.settings = s
+	m.applySettings(prev)
+}
+
+// Settings returns the current TUI preferences
+func (m *Model) Settings() Settings { return m.settings }
+
+// SetSettingsSaver sets the function that persists settings when the settings
+// modal closes; it is given the settings from when the modal opened and now
+func (m *Model) SetSettingsSaver(save func(prev, s Settings) error) { m.saveSettings = save }
+
+// SetTokenRecounter sets the function used to rebuild token counts when the tokenizer changes
+func (m *Model) SetTokenRecounter(recount func(token.Tokenizer) (map[string]int, error)) {
+	m.recountTokens = recount
+}
+
+// OutputOptions returns the generator options chosen for this session, with
+// the current token counts for the directory structure
+func (m *Model) OutputOptions() generate.Options {
+	opts := m.output
+	opts.FileTokens = m.tokens
+	return opts
+}
 
 // Prompt returns the current prompt text
 func (m *Model) Prompt() string {
 	return m.prompt.Value()
 }
 
-// returns file tokens, or aggregated directory tokens
+// returns file tokens, or aggregated directory tokens; binary and
+// generated files count only when forced
 func (m *Model) tokenCount(node *domain.Node) int {
-	if m.tokens == nil {
-		return 0
-	}
-	if !node.IsDir {
-		return m.tokens[node.Path]
-	}
-	// directory: sum tokens of descendant files still in tree
-	var sum int
-	var stack []*domain.Node
-	stack = append(stack, node)
-	for len(stack) > 0 {
-		cur := stack[len(stack)-1]
-		stack = stack[:len(stack)-1]
-		if cur.IsDir {
-			stack = append(stack, cur.Children...)
-		} else {
-			sum += m.tokens[cur.Path]
-		}
-	}
+	sum, _ := m.tokenTotal(node)
 	return sum
 }
 
-func (m *Model) selectedTokens() int {
+// tokenTotal is tokenCount that also reports whether the total is complete,
+// which it is not while the subtree holds directories not loaded yet
+func (m *Model) tokenTotal(node *domain.Node) (int, bool) {
 	if m.tokens == nil {
-		return 0
+		return 0, true
 	}
-	total := 0
-	for _, p := range domain.GetSelectedPaths(m.tree.Root, m.state) {
-		total += m.tokens[p]
+	// The tree's aggregates are computed once per state, not once per row
+	stats := m.tree.Aggregates(m.state, m.tokens).Of(node)
+	return stats.Tokens, stats.Complete
+}
+
+func (m *Model) selectedTokens() int {
+	return domain.SelectedTokens(m.tree.Root, m.state, m.selectionTokens())
+}
+
+// outputTokens is selectedTokens plus the directory structure written with them
+func (m *Model) outputTokens() int {
+	return m.selectedTokens() + m.structureTokens()
+}
+
+// partCount estimates how many parts the output needs. The structure in
+// each part's preamble comes from structureTokens, so it is not rendered
+// again on every frame.
+func (m *Model) partCount() int {
+	opts := m.OutputOptions()
+	rest := opts
+	rest.Structure = generate.StructureNone
+	preamble := generate.PreambleTokens(m.prompt.Value(), m.tree, m.state, rest) + m.structureTokens()
+	return generate.CountPartsWithPreamble(preamble, m.tree, m.state, m.selectionTokens(), opts)
+}
+
+// structureCache keeps the tokens of the directory structure for one
+// generation of the tree and version of the view state
+type structureCache struct {
+	gen     int
+	version uint64
+	tokens  int
+	ok      bool
+}
+
+// structureTokens returns the tokens of the directory structure written
+// with the selection. Rendering it is counted once per tree and state
+// rather than on every frame.
+func (m *Model) structureTokens() int {
+	gen, version := m.tree.Generation(), m.state.Version()
+	if c := m.structure; c.ok && version != 0 && c.gen == gen && c.version == version {
+		return c.tokens
 	}
-	return total
+	n := generate.StructureTokens(m.tree, m.state, m.OutputOptions())
+	m.structure = structureCache{gen: gen, version: version, tokens: n, ok: true}
+	return n
 }
 
 // Init implements tea.Model
@@ -136,7 +284,8 @@ func (m *Model) Init() tea.Cmd {
 	// Initialize viewport content
 	m.vp.SetContent(m.renderWholeTree())
 	m.ensureCursorVisible()
-	return nil
+	m.queueDrawn()
+	return tea.Batch(m.scanSecrets(), m.countModes())
 }
 
 // clearStatusMsg is a custom message type for clearing the status message
@@ -144,24 +293,59 @@ type clearStatusMsg struct{}
 
 // Update implements tea.Model
 func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	model, cmd := m.update(msg)
+	// Selections can load directories of a lazy tree; count what they read
+	m.syncLoaded()
+	m.queueDrawn()
+	return model, tea.Batch(cmd, m.scanSecrets(), m.countModes())
+}
+
+// queueDrawn queues the background work the next frame needs, since View
+// cannot start commands: a secret scan of each row drawn for the first
+// time, and a count of each file shown or totalled in another inclusion mode
+func (m *Model) queueDrawn() {
+	for _, node := range domain.Flatten(m.tree.Root, m.state) {
+		m.secretCount(node)
+		if !node.IsDir && m.effectiveMode(node) != domain.IncludeFull &&
+			!domain.IsSkipped(node, m.state) && !domain.IsOversized(node, m.maxFileSize) {
+			m.modeTokenCount(node)
+		}
+	}
+	m.selectionTokens()
+}
+
+func (m *Model) update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
+	case dirLoadedMsg:
+		m.attachLoaded(msg)
+		return m, nil
+	case secretsScannedMsg:
+		m.addSecrets(msg)
+		return m, nil
+	case modesCountedMsg:
+		m.addModeCounts(msg)
+		return m, nil
+	case searchDoneMsg:
+		return m, m.showSearchResults(msg)
 	case clearStatusMsg:
 		m.statusMessage = ""
 		m.statusMessageTimer = 0
 		return m, nil
+	case tea.MouseMsg:
+		return m.updateMouse(msg)
 	case tea.KeyMsg:
 		// Global quit works regardless of mode
-		if key := msg.String(); key == "ctrl+c" || (key == "q" && !m.inPromptMode) {
+		if key.Matches(msg, m.keys.ForceQuit) || (key.Matches(msg, m.keys.Quit) && !m.inPromptMode && !m.inCommandMode && !m.inSearchInput) {
 			return m, tea.Quit
 		}
 		
 		// Handle prompt mode
 		if m.inPromptMode {
-			switch msg.String() {
-			case "esc":
+			switch {
+			case key.Matches(msg, m.keys.Cancel):
 				m.inPromptMode = false
 				m.prompt.Blur()
-				m.prompt.Placeholder = "press p to add a prompt"
+				m.prompt.Placeholder = m.promptPlaceholder()
 				return m, nil
 			}
 			var cmd tea.Cmd
@@ -169,46 +353,159 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, cmd
 		}
 		
+		// Handle command palette
+		if m.inCommandMode {
+			return m.updateCommand(msg)
+		}
+		
+		// Handle search input
+		if m.inSearchInput {
+			return m.updateSearchInput(msg)
+		}
+		
 		// Handle settings modal if open
 		if m.isSettingsOpen {
 			return m.updateSettings(msg)
 		}
 		
-		switch msg.String() {
-		case "p":
+		// Handle help overlay if open
+		if m.isHelpOpen {
+			if key.Matches(msg, m.keys.Help, m.keys.Cancel) {
+				m.isHelpOpen = false
+			}
+			return m, nil
+		}
+		
+		// Handle budget preview if open
+		if m.budgetPlan != nil {
+			return m.updateBudgetPreview(msg)
+		}
+		
+		// Handle dependency preview if open
+		if m.depsPlan != nil {
+			return m.updateDepsPreview(msg)
+		}
+		
+		// Handle search results if open
+		if m.searchResults != nil {
+			return m.updateSearchResults(msg)
+		}
+		
+		// Handle visual range mode
+		if m.visualAnchor != "" {
+			return m.updateVisual(msg)
+		}
+		
+		switch {
+		case key.Matches(msg, m.keys.Prompt):
 			m.inPromptMode = true
 			m.prompt.Focus()
 			m.prompt.Placeholder = ""
 			return m, nil
 			
-		case "up", "k":
+		case key.Matches(msg, m.keys.Up):
 			m.state = domain.NavigateUp(m.tree.Root, m.state)
 			m.ensureCursorVisible()
 			
-		case "down", "j":
+		case key.Matches(msg, m.keys.Down):
 			m.state = domain.NavigateDown(m.tree.Root, m.state)
 			m.ensureCursorVisible()
 			
-		case "left", "h":
+		case key.Matches(msg, m.keys.Collapse):
 			m.state = domain.NavigateOut(m.tree.Root, m.state)
 			// Re-render tree when closing directories
 			m.vp.SetContent(m.renderWholeTree())
 			m.ensureCursorVisible()
 			
-		case "right", "l", "enter":
-			m.state = domain.NavigateIn(m.tree.Root, m.state)
-			// Re-render tree when opening directories
-			m.vp.SetContent(m.renderWholeTree())
-			m.ensureCursorVisible()
+		case key.Matches(msg, m.keys.Expand):
+			return m, m.expand()
+			
+		case key.Matches(msg, m.keys.Toggle):
+			m.toggleSelection()
+			
+		case key.Matches(msg, m.keys.Pair):
+			return m, m.togglePaired()
+			
+		case key.Matches(msg, m.keys.Force):
+			return m, m.toggleForced()
+			
+		case key.Matches(msg, m.keys.Outline):
+			return m, m.toggleOutline()
+			
+		case key.Matches(msg, m.keys.CycleMode):
+			return m, m.cycleMode()
+			
+		case key.Matches(msg, m.keys.Sort):
+			prev := m.settings
+			m.settings.SortOrder = cycle(domain.SortOrders, m.settings.SortOrder, 1)
+			m.settings.SortDirection = m.settings.SortOrder.DefaultDirection()
+			m.applySettings(prev)
+			return m, m.flashStatus(fmt.Sprintf("Sorted by %s, %s", m.settings.SortOrder, m.settings.SortDirection))
+			
+		case key.Matches(msg, m.keys.ReverseSort):
+			prev := m.settings
+			m.settings.SortDirection = m.settings.SortDirection.Reverse()
+			m.applySettings(prev)
+			return m, m.flashStatus(fmt.Sprintf("Sorted by %s, %s", m.settings.SortOrder, m.settings.SortDirection))
+			
+		case key.Matches(msg, m.keys.ToggleHidden):
+			prev := m.settings
+			m.settings.ShowHidden = !m.settings.ShowHidden
+			m.applySettings(prev)
+			if m.settings.ShowHidden {
+				return m, m.flashStatus("Showing hidden files")
+			}
+			return m, m.flashStatus("Hiding hidden files except the allowlist")
+			
+		case key.Matches(msg, m.keys.Pin):
+			m.state = domain.TogglePinned(m.tree.Root, m.state)
+			
+		case key.Matches(msg, m.keys.Visual):
+			m.visualAnchor = m.state.CursorPath
+			
+		case key.Matches(msg, m.keys.Command):
+			return m, m.openCommand()
+			
+		case key.Matches(msg, m.keys.Search):
+			return m, m.openSearch()
+			
+		case key.Matches(msg, m.keys.Dependencies):
+			return m, m.planDeps(false)
+			
+		case key.Matches(msg, m.keys.Dependents):
+			return m, m.planDeps(true)
 			
-		case " ":
-			m.state = domain.ToggleSelection(m.tree.Root, m.state)
+		case key.Matches(msg, m.keys.Fit):
+			if m.budget <= 0 {
+				m.statusMessage = "No token budget set (use -budget)"
+				m.statusMessageTimer = 1
+				return m, tea.Tick(2*time.Second, func(time.Time) tea.Msg {
+					return clearStatusMsg{}
+				})
+			}
+			// The structure counts toward the budget as it does in the header
+			policy := domain.DefaultBudgetPolicy()
+			policy.Overhead = m.structureTokens()
+			plan := domain.FitToBudget(m.tree.Root, m.state, m.selectionTokens(), m.budget, policy)
+			if plan.IsEmpty() {
+				if plan.Fits() {
+					m.statusMessage = "Selection already fits the budget"
+				} else {
+					m.statusMessage = "Pinned files alone exceed the budget"
+				}
+				m.statusMessageTimer = 1
+				return m, tea.Tick(2*time.Second, func(time.Time) tea.Msg {
+					return clearStatusMsg{}
+				})
+			}
+			m.budgetPlan = &plan
+			return m, nil
 			
-		case "g":
+		case key.Matches(msg, m.keys.Generate):
 			m.requestedGenerate = true
 			return m, tea.Quit
 			
-		case "c":
+		case key.Matches(msg, m.keys.Copy):
 			if err := m.copyToClipboard(); err != nil {
 				m.statusMessage = fmt.Sprintf("Error copying to clipboard: %v", err)
 				m.statusMessageTimer = 1
@@ -221,16 +518,15 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				return clearStatusMsg{}
 			})
 			
-		case "s":
-			if m.isSettingsOpen {
-				m.isSettingsOpen = false
-			} else {
-				m.isSettingsOpen = true
-				m.settingsCursorIdx = 0
-			}
+		case key.Matches(msg, m.keys.Help):
+			m.isHelpOpen = true
 			return m, nil
 			
-		case "x":
+		case key.Matches(msg, m.keys.Settings):
+			m.openSettings()
+			return m, nil
+			
+		case key.Matches(msg, m.keys.Exclude):
 			// Exclude current node
 			// First, find the current node before excluding it
 			currentNode := domain.FindNodeByPath(m.tree.Root, m.state.CursorPath)
@@ -305,33 +601,105 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 // updateSettings handles keyboard input when the settings modal is open
 func (m *Model) updateSettings(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
-	switch msg.String() {
-	case "up", "k":
-		// Wrap around navigation (2 items now)
-		m.settingsCursorIdx = (m.settingsCursorIdx - 1 + 2) % 2
-	case "down", "j":
-		// Wrap around navigation (2 items now)
-		m.settingsCursorIdx = (m.settingsCursorIdx + 1) % 2
-	case " ", "enter":
-		// Toggle the highlighted setting
-		if m.settingsCursorIdx == 0 {
-			m.settings = m.settings.ToggleEmoji()
-		}
-		// Color scheme doesn't use space/enter, it uses left/right
-	case "left", "h":
-		// Change color scheme (previous)
-		if m.settingsCursorIdx == 1 {
-			m.settings = m.settings.PrevColorScheme()
-		}
-	case "right", "l":
-		// Change color scheme (next)
-		if m.settingsCursorIdx == 1 {
-			m.settings = m.settings.NextColorScheme()
-		}
-	case "esc", "s":
-		m.isSettingsOpen = false
-	case "q", "ctrl+c":
-		return m, tea.Quit
+	prev := m.settings
+	switch {
+	case key.Matches(msg, m.keys.Up):
+		// Wrap around navigation
+		m.settingsCursorIdx = (m.settingsCursorIdx - 1 + settingCount) % settingCount
+	case key.Matches(msg, m.keys.Down):
+		m.settingsCursorIdx = (m.settingsCursorIdx + 1) % settingCount
+	case key.Matches(msg, m.keys.Toggle, m.keys.Confirm):
+		// Checkboxes flip, lists advance to the next value
+		if isToggleSetting(m.settingsCursorIdx) {
+			m.settings = m.settings.Toggle(m.settingsCursorIdx)
+		} else {
+			m.settings = m.settings.Cycle(m.settingsCursorIdx, 1)
+		}
+	case key.Matches(msg, m.keys.Collapse):
+		m.settings = m.settings.Cycle(m.settingsCursorIdx, -1)
+	case key.Matches(msg, m.keys.Expand):
+		m.settings = m.settings.Cycle(m.settingsCursorIdx, 1)
+	case key.Matches(msg, m.keys.Cancel, m.keys.Settings):
+		m.closeSettings()
+	}
+	m.applySettings(prev)
+	return m, nil
+}
+
+// openSettings shows the settings modal and remembers the settings to detect changes
+func (m *Model) openSettings() {
+	m.isSettingsOpen = true
+	m.settingsCursorIdx = 0
+	m.settingsOnOpen = m.settings
+}
+
+// closeSettings hides the settings modal and saves the settings if they changed
+func (m *Model) closeSettings() {
+	m.isSettingsOpen = false
+	if m.saveSettings == nil || m.settings == m.settingsOnOpen {
+		return
+	}
+	if err := m.saveSettings(m.settingsOnOpen, m.settings); err != nil {
+		m.statusMessage = fmt.Sprintf("Could not save settings: %v", err)
+		m.statusMessageTimer = 1
+	}
+}
+
+// applySettings brings the tree, token counts and output options in line
+// with any settings that changed since prev
+func (m *Model) applySettings(prev Settings) {
+	s := m.settings
+	
+	if s.ShowHidden != prev.ShowHidden || s.ShowHidden == m.state.HideHidden {
+		m.state = m.state.SetHideHidden(!s.ShowHidden)
+		m.state = m.state.SetCursor(domain.NearestVisible(m.tree.Root, m.state, m.state.CursorPath))
+	}
+	
+	if s.Tokenizer != prev.Tokenizer {
+		tz, err := token.ByName(s.Tokenizer)
+		if err != nil {
+			m.statusMessage = err.Error()
+			m.statusMessageTimer = 1
+		} else {
+			m.output.Tokenizer = tz
+			m.structure = structureCache{}
+			m.resetModeCounts()
+			if m.recountTokens != nil {
+				if tokens, err := m.recountTokens(tz); err == nil {
+					m.tokens = tokens
+				}
+			}
+		}
+	}
+	
+	if s.Format != "" {
+		m.output.Format = s.Format
+		m.structure = structureCache{}
+	}
+	
+	if s.SortOrder != prev.SortOrder || s.SortDirection != prev.SortDirection ||
+		(s.Tokenizer != prev.Tokenizer && s.SortOrder == domain.SortByTokens) {
+		domain.SortTree(m.tree.Root, s.SortOrder, s.SortDirection, m.tokens)
+	}
+	
+	if m.vp.Height > 0 {
+		m.ensureCursorVisible()
+	}
+}
+
+// updateBudgetPreview handles keyboard input while the fit-to-budget preview is shown
+func (m *Model) updateBudgetPreview(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch {
+	case key.Matches(msg, m.keys.Confirm):
+		m.state = m.budgetPlan.Apply(m.state)
+		m.statusMessage = fmt.Sprintf("Fitted selection to ~%s tokens", formatTokenCount(m.budgetPlan.After))
+		m.statusMessageTimer = 1
+		m.budgetPlan = nil
+		return m, tea.Tick(2*time.Second, func(time.Time) tea.Msg {
+			return clearStatusMsg{}
+		})
+	case key.Matches(msg, m.keys.Cancel, m.keys.Fit):
+		m.budgetPlan = nil
 	}
 	return m, nil
 }
@@ -340,7 +708,7 @@ func (m *Model) promptCollapsedView() string {
 	// Faint border
 	border := lipgloss.NewStyle().
 		Border(lipgloss.NormalBorder()).
-		BorderForeground(PromptBorderDim).
+		BorderForeground(m.settings.ColorScheme.promptBorder(false)).
 		Width(m.vp.Width - 2) // Account for padding
 
 	value := strings.TrimSpace(m.prompt.Value())
@@ -386,7 +754,7 @@ func (m *Model) formatInstructions(commands []string) string {
 	}
 	
 	separator := " • "
-	sepLen := len(separator)
+	sepLen := lipgloss.Width(separator)
 	width := m.vp.Width
 	
 	var lines []string
@@ -394,7 +762,7 @@ func (m *Model) formatInstructions(commands []string) string {
 	currentLen := 0
 	
 	for _, cmd := range commands {
-		cmdLen := len(cmd)
+		cmdLen := lipgloss.Width(cmd)
 		
 		// Check if adding this command would exceed width
 		neededLen := cmdLen
@@ -464,33 +832,11 @@ func (m *Model) copyToClipboard() error {
 	// Create a buffer to write content to
 	var buf bytes.Buffer
 	
-	// Write prompt first if non-empty
-	if prompt := m.prompt.Value(); prompt != "" {
-		fmt.Fprintln(&buf, "# Prompt")
-		fmt.Fprintln(&buf)
-		fmt.Fprintln(&buf, prompt)
-		fmt.Fprintln(&buf) // extra blank line
-	}
-	
-	// Get all selected paths
-	paths := domain.GetSelectedPaths(m.tree.Root, m.state)
-	if len(paths) == 0 {
+	if len(domain.GetSelectedPaths(m.tree.Root, m.state)) == 0 {
 		return fmt.Errorf("no files selected")
 	}
 	
-	// Use TextWriter for output
-	writer := generate.NewTextWriter()
-	
-	// Write directory structure
-	if err := writer.WriteStructure(&buf, m.tree.Root, m.state); err != nil {
-		return err
-	}
-	
-	// Use the actual OS filesystem
-	osFS := fs.NewOSFileSystem()
-	
-	// Write file contents
-	if err := writer.WriteContent(&buf, paths, osFS); err != nil {
+	if err := generate.RenderWithOptions(&buf, m.prompt.Value(), m.tree, m.state, m.fsys, m.OutputOptions()); err != nil {
 		return err
 	}
 	
@@ -500,14 +846,26 @@ func (m *Model) copyToClipboard() error {
 
 // renderWholeTree renders the complete tree structure without any viewport cropping
 func (m *Model) renderWholeTree() string {
+	// Note the visual range so rows in it can be highlighted
+	m.visualPaths = nil
+	if m.visualAnchor != "" {
+		m.visualPaths = make(map[string]bool)
+		for _, node := range domain.NodesBetween(m.tree.Root, m.state, m.visualAnchor, m.state.CursorPath) {
+			m.visualPaths[node.Path] = true
+		}
+	}
+	
 	// Build the complete tree starting from root
 	items := m.buildTreeItems(m.tree.Root)
 	
 	// Create tree with items
 	t := tree.New().
-		EnumeratorStyle(lipgloss.NewStyle().Foreground(lipgloss.Color("241"))).
+		EnumeratorStyle(m.settings.ColorScheme.helpStyle()).
 		Child(items...)
 	
+	if m.settings.Columns {
+		return lipgloss.JoinHorizontal(lipgloss.Top, t.String(), "  ", m.renderColumns(time.Now()))
+	}
 	return t.String()
 }
 
@@ -519,13 +877,12 @@ func (m *Model) renderPrompt() string {
 
 	boxStyle := lipgloss.NewStyle().
 		Border(lipgloss.NormalBorder()).
-		BorderForeground(PromptBorderLit).
+		BorderForeground(m.settings.ColorScheme.promptBorder(true)).
 		Width(m.prompt.Width() + 4)
 
-	title := lipgloss.NewStyle().
-		Foreground(PromptBorderLit).
+	title := m.settings.ColorScheme.fg(m.settings.ColorScheme.PromptBorderActive).
 		Bold(true).
-		Render(" Prompt (esc to close)")
+		Render(fmt.Sprintf(" Prompt (%s to close)", m.keys.Cancel.Help().Key))
 
 	return lipgloss.JoinVertical(
 		lipgloss.Top,
@@ -539,10 +896,17 @@ func (m *Model) View() string {
 	var b strings.Builder
 	
 	// Header
-	headerStyle := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("6"))
-	header := headerStyle.Render(
-		fmt.Sprintf("⛏️  Picky   •   Tokens selected: ~%s",
-			formatTokenCount(m.selectedTokens())))
+	headerStyle := m.settings.ColorScheme.headerStyle()
+	tokenSummary := fmt.Sprintf("Tokens selected: ~%s", formatTokenCount(m.outputTokens()))
+	if m.budget > 0 {
+		tokenSummary += fmt.Sprintf(" / %s", formatTokenCount(m.budget))
+	}
+	if m.output.MaxTokensPerPart > 0 {
+		if parts := m.partCount(); parts > 1 {
+			tokenSummary += fmt.Sprintf("   •   %d parts", parts)
+		}
+	}
+	header := headerStyle.Render("⛏️  Picky   •   " + tokenSummary)
 	if m.inPromptMode {
 		header = m.dim(header)
 	}
@@ -550,24 +914,20 @@ func (m *Model) View() string {
 	b.WriteString("\n")
 	
 	// Instructions
-	helpStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("241"))
+	helpStyle := m.settings.ColorScheme.helpStyle()
 	
 	var instructionText string
 	if m.inPromptMode {
 		// No instructions shown in prompt mode
 		instructionText = ""
+	} else if m.inSearchInput {
+		instructionText = m.formatInstructions([]string{"enter search", m.keys.Cancel.Help().Key + " close", "Go regular expression syntax"})
+	} else if m.inCommandMode {
+		instructionText = m.formatInstructions(append([]string{"enter run", m.keys.Cancel.Help().Key + " close"}, commandHelpEntries...))
+	} else if m.visualAnchor != "" {
+		instructionText = m.formatInstructions(append([]string{"-- VISUAL --"}, m.keys.VisualHelpEntries()...))
 	} else {
-		instructionText = m.formatInstructions([]string{
-			"↑/↓ navigate",
-			"←/→ collapse/expand", 
-			"space select",
-			"x exclude",
-			"p prompt",
-			"s settings",
-			"g generate",
-			"c copy to clipboard",
-			"q quit",
-		})
+		instructionText = m.formatInstructions(m.keys.ShortHelpEntries())
 	}
 	
 	if instructionText != "" {
@@ -581,9 +941,13 @@ func (m *Model) View() string {
 	b.WriteString(m.renderPrompt())
 	b.WriteString("\n")
 	
-	// Status message
-	if m.statusMessageTimer > 0 {
-		statusStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("3"))
+	// Status message, or the command line while the palette is open
+	if m.inCommandMode {
+		b.WriteString(m.command.View())
+	} else if m.inSearchInput {
+		b.WriteString(m.searchInput.View())
+	} else if m.statusMessageTimer > 0 {
+		statusStyle := m.settings.ColorScheme.statusStyle()
 		statusLine := statusStyle.Render(m.statusMessage)
 		if m.inPromptMode {
 			statusLine = m.dim(statusLine)
@@ -598,6 +962,9 @@ func (m *Model) View() string {
 	// Get the tree view from viewport
 	treeView := m.vp.View()
 	
+	// Remember where the tree starts on screen so mouse rows can be mapped back
+	m.treeTop = strings.Count(b.String(), "\n")
+	
 	if m.inPromptMode {
 		treeView = m.dim(treeView)
 	}
@@ -614,6 +981,22 @@ func (m *Model) View() string {
 		// We'll overlay it by using ANSI cursor positioning or just append it
 		b.WriteString("\n\n")
 		b.WriteString(settingsView)
+	} else if m.isHelpOpen {
+		b.WriteString(lipgloss.NewStyle().Faint(true).Render(treeView))
+		b.WriteString("\n\n")
+		b.WriteString(m.renderHelpOverlay())
+	} else if m.budgetPlan != nil {
+		b.WriteString(lipgloss.NewStyle().Faint(true).Render(treeView))
+		b.WriteString("\n\n")
+		b.WriteString(m.renderBudgetPreview())
+	} else if m.depsPlan != nil {
+		b.WriteString(lipgloss.NewStyle().Faint(true).Render(treeView))
+		b.WriteString("\n\n")
+		b.WriteString(m.renderDepsPreview())
+	} else if m.searchResults != nil {
+		b.WriteString(lipgloss.NewStyle().Faint(true).Render(treeView))
+		b.WriteString("\n\n")
+		b.WriteString(m.renderSearchResults())
 	} else {
 		// Normal tree view
 		b.WriteString(treeView)
@@ -627,17 +1010,13 @@ func (m *Model) renderSettingsModal() string {
 	// Define styles
 	modalStyle := lipgloss.NewStyle().
 		Border(lipgloss.RoundedBorder()).
-		BorderForeground(lipgloss.Color("240")).
+		BorderForeground(m.settings.ColorScheme.promptBorder(false)).
 		Padding(1, 2).
 		Width(50)
 	
-	titleStyle := lipgloss.NewStyle().
-		Bold(true).
-		Foreground(lipgloss.Color("6"))
+	titleStyle := m.settings.ColorScheme.headerStyle()
 	
-	selectedStyle := lipgloss.NewStyle().
-		Background(lipgloss.Color("238")).
-		Foreground(lipgloss.Color("255"))
+	selectedStyle := m.settings.ColorScheme.modalSelectedStyle()
 	
 	normalStyle := lipgloss.NewStyle()
 	
@@ -648,43 +1027,119 @@ func (m *Model) renderSettingsModal() string {
 	content.WriteString(titleStyle.Render("Settings"))
 	content.WriteString("\n\n")
 	
-	// 1. Emoji setting
-	emojiSetting := "[x] Emoji icons"
-	if !m.settings.Emoji {
-		emojiSetting = "[ ] Emoji icons"
+	for i := 0; i < settingCount; i++ {
+		line := m.settings.settingLine(i)
+		if i == m.settingsCursorIdx {
+			content.WriteString(selectedStyle.Render(line))
+		} else {
+			content.WriteString(normalStyle.Render(line))
+		}
+		content.WriteString("\n\n")
 	}
 	
-	if m.settingsCursorIdx == 0 {
-		content.WriteString(selectedStyle.Render(emojiSetting))
+	// Help text
+	helpStyle := m.settings.ColorScheme.helpStyle().Italic(true)
+	if !isToggleSetting(m.settingsCursorIdx) {
+		content.WriteString(helpStyle.Render(fmt.Sprintf("%s/%s navigate  %s/%s change  %s close",
+			m.keys.Up.Help().Key, m.keys.Down.Help().Key,
+			m.keys.Collapse.Help().Key, m.keys.Expand.Help().Key, m.keys.Cancel.Help().Key)))
 	} else {
-		content.WriteString(normalStyle.Render(emojiSetting))
+		content.WriteString(helpStyle.Render(fmt.Sprintf("%s/%s navigate  %s/%s toggle  %s close",
+			m.keys.Up.Help().Key, m.keys.Down.Help().Key,
+			m.keys.Toggle.Help().Key, m.keys.Confirm.Help().Key, m.keys.Cancel.Help().Key)))
 	}
 	
+	// Apply modal styling
+	return modalStyle.Render(content.String())
+}
+
+// renderHelpOverlay renders every key binding grouped by purpose
+func (m *Model) renderHelpOverlay() string {
+	modalStyle := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(m.settings.ColorScheme.promptBorder(false)).
+		Padding(1, 2).
+		Width(50)
+	
+	titleStyle := m.settings.ColorScheme.headerStyle()
+	
+	var content strings.Builder
+	content.WriteString(titleStyle.Render("Keys"))
+	content.WriteString("\n\n")
+	content.WriteString(strings.Join(m.keys.FullHelpLines(), "\n"))
 	content.WriteString("\n\n")
 	
-	// 2. Color scheme setting
-	colorSchemeSetting := fmt.Sprintf("Color scheme: ← %s →", m.settings.ColorScheme.Name)
+	helpStyle := m.settings.ColorScheme.helpStyle().Italic(true)
+	content.WriteString(helpStyle.Render(fmt.Sprintf("%s/%s close", m.keys.Help.Help().Key, m.keys.Cancel.Help().Key)))
 	
-	if m.settingsCursorIdx == 1 {
-		content.WriteString(selectedStyle.Render(colorSchemeSetting))
-	} else {
-		content.WriteString(normalStyle.Render(colorSchemeSetting))
-	}
+	return modalStyle.Render(content.String())
+}
+
+// renderBudgetPreview renders the proposed fit-to-budget changes
+func (m *Model) renderBudgetPreview() string {
+	plan := m.budgetPlan
+	
+	modalStyle := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(m.settings.ColorScheme.promptBorder(false)).
+		Padding(1, 2).
+		Width(60)
 	
+	titleStyle := m.settings.ColorScheme.headerStyle()
+	
+	dropStyle := m.settings.ColorScheme.fg(DropColor)
+	truncStyle := m.settings.ColorScheme.fg(TruncateColor)
+	
+	tokens := m.selectionTokens()
+	var content strings.Builder
+	
+	content.WriteString(titleStyle.Render("Fit to budget"))
+	content.WriteString("\n\n")
+	content.WriteString(fmt.Sprintf("~%s → ~%s tokens (budget %s)",
+		formatTokenCount(plan.Before), formatTokenCount(plan.After), formatTokenCount(plan.Budget)))
 	content.WriteString("\n\n")
 	
-	// Help text
-	helpStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("241")).Italic(true)
-	if m.settingsCursorIdx == 1 {
-		content.WriteString(helpStyle.Render("↑/↓ navigate  ←/→ change  esc close"))
-	} else {
-		content.WriteString(helpStyle.Render("↑/↓ navigate  space/enter toggle  esc close"))
+	for _, path := range plan.Drop {
+		line := fmt.Sprintf("- %s (%s)", m.relPath(path), formatTokenCount(tokens[path]))
+		content.WriteString(dropStyle.Render(line))
+		content.WriteString("\n")
 	}
 	
-	// Apply modal styling
+	// Map iteration order is random, keep the preview stable
+	truncated := make([]string, 0, len(plan.Truncate))
+	for path := range plan.Truncate {
+		truncated = append(truncated, path)
+	}
+	sort.Strings(truncated)
+	for _, path := range truncated {
+		line := fmt.Sprintf("✂ %s (keep %s of %s)", m.relPath(path),
+			formatTokenCount(plan.Truncate[path]), formatTokenCount(tokens[path]))
+		content.WriteString(truncStyle.Render(line))
+		content.WriteString("\n")
+	}
+	
+	if !plan.Fits() {
+		content.WriteString("\n")
+		content.WriteString(dropStyle.Render("Pinned files still exceed the budget"))
+		content.WriteString("\n")
+	}
+	
+	content.WriteString("\n")
+	helpStyle := m.settings.ColorScheme.helpStyle().Italic(true)
+	content.WriteString(helpStyle.Render(fmt.Sprintf("%s apply  %s cancel",
+		m.keys.Confirm.Help().Key, m.keys.Cancel.Help().Key)))
+	
 	return modalStyle.Render(content.String())
 }
 
+// relPath returns a path relative to the tree root for display
+func (m *Model) relPath(path string) string {
+	rel, err := filepath.Rel(m.tree.Root.Path, path)
+	if err != nil {
+		return path
+	}
+	return filepath.ToSlash(rel)
+}
 
 func findIndex(nodes []*domain.Node, target *domain.Node) int {
 	for i, n := range nodes {
@@ -712,9 +1167,9 @@ func (m *Model) buildTreeItems(node *domain.Node) []any {
 	label := m.formatNodeLabelWithStyle(node)
 	
 	// Handle directories with children
-	if node.IsDir && m.state.IsOpen(node.Path) && len(node.Children) > 0 {
+	if children := domain.VisibleChildren(node, m.state); node.IsDir && m.state.IsOpen(node.Path) && len(children) > 0 {
 		childItems := []any{}
-		for _, child := range node.Children {
+		for _, child := range children {
 			childItems = append(childItems, m.buildTreeItems(child)...)
 		}
 		return []any{tree.Root(label).Child(childItems...)}
@@ -730,41 +1185,52 @@ func (m *Model) formatNodeLabelWithStyle(node *domain.Node) string {
 	label := m.formatNodeLabel(node)
 	
 	// Determine the appropriate color based on selection state
+	scheme := m.settings.ColorScheme
 	var color lipgloss.Color
+	selected := false
 	if node.IsDir {
 		if domain.HasFullSelection(node, m.state) {
-			color = m.settings.ColorScheme.Selected
+			color = scheme.Selected
+			selected = true
 		} else if domain.HasPartialSelection(node, m.state) {
-			color = m.settings.ColorScheme.PartiallySelected
+			color = scheme.PartiallySelected
+			selected = true
 		} else {
-			color = m.settings.ColorScheme.Unselected
+			color = scheme.Unselected
 		}
 	} else {
 		if m.state.IsSelected(node.Path) {
-			color = m.settings.ColorScheme.Selected
+			color = scheme.Selected
+			selected = true
 		} else {
-			color = m.settings.ColorScheme.Unselected
+			color = scheme.Unselected
 		}
 	}
 	
 	// Apply styling based on cursor position
 	if node.Path == m.state.CursorPath && !m.inPromptMode {
 		// Cursor is on this item - use block background
-		// For Classic scheme with dark backgrounds, use white text
-		textColor := lipgloss.Color("0") // Default black text
-		if m.settings.ColorScheme.Name == "Classic" {
-			textColor = lipgloss.Color("255") // White text for Classic
+		return scheme.cursorStyle(color).Render(label)
+	}
+	
+	if m.visualPaths[node.Path] {
+		return scheme.visualStyle(color).Render(label)
+	}
+	
+	// Cursor is elsewhere - use text color only
+	// Unselected names take the theme's directory and file colors when set
+	if !selected {
+		if node.IsDir && scheme.Directory != "" {
+			color = scheme.Directory
+		} else if !node.IsDir && scheme.File != "" {
+			color = scheme.File
 		}
-		
-		cursorStyle := lipgloss.NewStyle().
-			Background(color).
-			Foreground(textColor)
-		return cursorStyle.Render(label)
-	} else {
-		// Cursor is elsewhere - use text color only
-		textStyle := lipgloss.NewStyle().Foreground(color)
-		return textStyle.Render(label)
 	}
+	textStyle := scheme.fg(color)
+	if scheme.NoColor && selected {
+		textStyle = textStyle.Bold(true)
+	}
+	return textStyle.Render(label)
 }
 
 // formatNodeLabel formats a node's label with selection and directory indicators
@@ -807,10 +1273,81 @@ func (m *Model) formatNodeLabel(node *domain.Node) string {
 			name = "📄 " + name
 		}
 	}
+	if node.LinkTarget != "" {
+		name += " → " + node.LinkTarget
+		if node.Loop {
+			name += " (loop)"
+		}
+	}
 	
-	tok := m.tokenCount(node)
 	// final label: "[✓] [▶ dir] (123)"
-	return fmt.Sprintf("%s %s (%s)", selected, name, formatTokenCount(tok))
+	label := fmt.Sprintf("%s %s", selected, name)
+	// Columns show the token count beside the tree instead
+	if m.settings.TokenDisplay != TokenDisplayOff && !m.settings.Columns {
+		tok := m.tokenCount(node)
+		tokText := m.formatTokenTotal(node)
+		if limit, ok := m.state.TruncatedTokens(node.Path); ok && !node.IsDir && limit < tok {
+			tokText = fmt.Sprintf("%s of %s", m.formatTokens(limit), tokText)
+		}
+		if mode := m.effectiveMode(node); mode != domain.IncludeFull && !domain.IsSkipped(node, m.state) && !domain.IsOversized(node, m.maxFileSize) {
+			count := "?"
+			if n, ok := m.modeTokenCount(node); ok {
+				count = m.formatTokens(n)
+			}
+			tokText = fmt.Sprintf("%s %s of %s", count, mode, tokText)
+		}
+		if domain.IsOversized(node, m.maxFileSize) {
+			tokText = formatSize(node.Size) + ", too large to count"
+		}
+		label += fmt.Sprintf(" (%s)", tokText)
+	}
+	if m.loading[node.Path] {
+		label += " (loading…)"
+	}
+	if node.Kind != domain.KindText {
+		if m.state.IsForced(node.Path) {
+			label += fmt.Sprintf(" [%s, forced]", node.Kind)
+		} else {
+			label += fmt.Sprintf(" [%s]", node.Kind)
+		}
+	}
+	if ranges := m.state.LineRanges(node.Path); len(ranges) > 0 {
+		label += fmt.Sprintf(" [%d ranges]", len(ranges))
+	}
+	label += modeBadge(m.state.Mode(node.Path))
+	label += m.secretBadge(node)
+	if m.state.IsPinned(node.Path) {
+		if m.settings.Emoji {
+			label += " 📌"
+		} else {
+			label += " [pinned]"
+		}
+	}
+	return label
+}
+
+// formatTokens formats a token count for tree labels using the chosen display mode
+func (m *Model) formatTokens(count int) string {
+	if m.settings.TokenDisplay == TokenDisplayExact {
+		return fmt.Sprintf("%d", count)
+	}
+	return formatTokenCount(count)
+}
+
+// formatSize formats a file size in bytes with KB/MB/GB suffixes
+func formatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	value, suffix := float64(size)/unit, "KB"
+	for _, next := range []string{"MB", "GB"} {
+		if value < unit {
+			break
+		}
+		value, suffix = value/unit, next
+	}
+	return fmt.Sprintf("%.1f %s", value, suffix)
 }
 
 // formatTokenCount formats a token count with k/M suffixes for large numbers
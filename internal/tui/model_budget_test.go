@@ -0,0 +1,138 @@
+package tui_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/eliooooooot/picky/internal/domain"
+	pickyfs "github.com/eliooooooot/picky/internal/fs"
+	"github.com/eliooooooot/picky/internal/generate"
+	"github.com/eliooooooot/picky/internal/tui"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFitToBudgetPreview(t *testing.T) {
+	setup := func(t *testing.T) *tui.Model {
+		fs := pickyfs.NewMemFileSystem()
+		fs.WriteFile("/root/a.go", []byte("a"), 0644)
+		fs.WriteFile("/root/a_test.go", []byte("a"), 0644)
+		fs.WriteFile("/root/b.go", []byte("b"), 0644)
+		
+		tree, err := domain.BuildTree(fs, "/root")
+		require.NoError(t, err)
+		
+		ignores := make(map[string]struct{})
+		model := tui.NewModel(tree, &ignores)
+		model.SetTokens(map[string]int{
+			"/root/a.go":      100,
+			"/root/a_test.go": 100,
+			"/root/b.go":      300,
+		})
+		model.SetBudget(400)
+		model.Init()
+		
+		// Select everything from the root
+		model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{' '}})
+		return model
+	}
+	
+	t.Run("preview shows proposed drops without applying them", func(t *testing.T) {
+		model := setup(t)
+		
+		model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'f'}})
+		
+		view := model.View()
+		assert.Contains(t, view, "Fit to budget")
+		assert.Contains(t, view, "- a_test.go (100)")
+		assert.True(t, model.State().IsSelected("/root/a_test.go"), "preview must not change the selection")
+	})
+	
+	t.Run("enter applies the plan", func(t *testing.T) {
+		model := setup(t)
+		
+		model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'f'}})
+		model.Update(tea.KeyMsg{Type: tea.KeyEnter})
+		
+		state := model.State()
+		assert.False(t, state.IsSelected("/root/a_test.go"))
+		assert.True(t, state.IsSelected("/root/a.go"))
+		assert.True(t, state.IsSelected("/root/b.go"))
+		assert.NotContains(t, model.View(), "Fit to budget")
+	})
+	
+	t.Run("esc discards the plan", func(t *testing.T) {
+		model := setup(t)
+		
+		model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'f'}})
+		model.Update(tea.KeyMsg{Type: tea.KeyEsc})
+		
+		assert.True(t, model.State().IsSelected("/root/a_test.go"))
+		assert.NotContains(t, model.View(), "Fit to budget")
+	})
+	
+	t.Run("pinned files are kept", func(t *testing.T) {
+		model := setup(t)
+		
+		// Cursor: root -> a.go -> a_test.go
+		model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'j'}})
+		model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'j'}})
+		model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'P'}})
+		require.True(t, model.State().IsPinned("/root/a_test.go"))
+		
+		// The directory structure counts toward the budget as well
+		structure := generate.StructureTokens(model.Tree(), model.State(), model.OutputOptions())
+		model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'f'}})
+		assert.Contains(t, model.View(), fmt.Sprintf("~%d → ~400 tokens", 500+structure), "the preview starts from the header's total")
+		model.Update(tea.KeyMsg{Type: tea.KeyEnter})
+		
+		state := model.State()
+		assert.True(t, state.IsSelected("/root/a_test.go"))
+		limit, ok := state.TruncatedTokens("/root/b.go")
+		assert.True(t, ok)
+		assert.Equal(t, 200-structure, limit)
+	})
+	
+	t.Run("header shows the budget", func(t *testing.T) {
+		model := setup(t)
+		
+		// The total includes the directory structure written with the files
+		structure := generate.StructureTokens(model.Tree(), model.State(), model.OutputOptions())
+		header := strings.Split(model.View(), "\n")[0]
+		assert.Contains(t, header, fmt.Sprintf("~%d / 400", 500+structure))
+	})
+}
+
+func TestHeaderShowsPartCount(t *testing.T) {
+	fs := pickyfs.NewMemFileSystem()
+	fs.WriteFile("/root/a.txt", []byte("a"), 0644)
+	fs.WriteFile("/root/b.txt", []byte("b"), 0644)
+	
+	tree, err := domain.BuildTree(fs, "/root")
+	require.NoError(t, err)
+	
+	ignores := make(map[string]struct{})
+	model := tui.NewModel(tree, &ignores)
+	model.SetFileSystem(fs)
+	model.SetTokens(map[string]int{"/root/a.txt": 500, "/root/b.txt": 500})
+	model.Init()
+	model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{' '}})
+	
+	header := strings.Split(model.View(), "\n")[0]
+	assert.NotContains(t, header, "parts", "no part count without a limit")
+	
+	model.SetMaxTokensPerPart(600)
+	header = strings.Split(model.View(), "\n")[0]
+	assert.Contains(t, header, "2 parts")
+	
+	// Rows: root, a.txt, b.txt; cycle b.txt round to its first line only
+	cursorTo(model, 2)
+	for i := 0; i < 2; i++ {
+		send(model, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'m'}})
+	}
+	require.Equal(t, domain.IncludeHead, model.State().Mode("/root/b.txt"))
+	header = strings.Split(model.View(), "\n")[0]
+	assert.NotContains(t, header, "parts", "files count in their inclusion mode")
+}
@@ -0,0 +1,97 @@
+package tui_test
+
+import (
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/eliooooooot/picky/internal/domain"
+	pickyfs "github.com/eliooooooot/picky/internal/fs"
+	"github.com/eliooooooot/picky/internal/tui"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func commandTestModel(t *testing.T) *tui.Model {
+	fs := pickyfs.NewMemFileSystem()
+	fs.WriteFile("/root/main.go", []byte("package main\n"), 0644)
+	fs.WriteFile("/root/main_test.go", []byte("package main\n"), 0644)
+	fs.WriteFile("/root/internal/a.go", []byte("// TODO\n"), 0644)
+	fs.WriteFile("/root/internal/a_test.go", []byte("package a\n"), 0644)
+	fs.WriteFile("/root/internal/notes.md", []byte("TODO: write\n"), 0644)
+
+	tree, err := domain.BuildTree(fs, "/root")
+	require.NoError(t, err)
+
+	ignores := make(map[string]struct{})
+	model := tui.NewModel(tree, &ignores)
+	model.SetFileSystem(fs)
+	model.Init()
+	model.Update(tea.WindowSizeMsg{Width: 100, Height: 30})
+	return model
+}
+
+// runCommand types a command into the palette and runs it
+func runCommand(m *tui.Model, line string) {
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{':'}})
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(line)})
+	m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+}
+
+func selected(m *tui.Model) []string {
+	return domain.GetSelectedPaths(m.Tree().Root, m.State())
+}
+
+func TestCommandSelectGlobUnderDir(t *testing.T) {
+	m := commandTestModel(t)
+
+	runCommand(m, "select *_test.go under internal/")
+	assert.Equal(t, []string{"/root/internal/a_test.go"}, selected(m))
+	assert.Contains(t, m.View(), "Selected 1 files matching *_test.go under internal/")
+
+	runCommand(m, "select *_test.go")
+	assert.ElementsMatch(t, []string{"/root/main_test.go", "/root/internal/a_test.go"}, selected(m))
+
+	runCommand(m, "deselect internal/*")
+	assert.Equal(t, []string{"/root/main_test.go"}, selected(m))
+}
+
+func TestCommandAllClearInvert(t *testing.T) {
+	m := commandTestModel(t)
+
+	runCommand(m, "all")
+	assert.Len(t, selected(m), 5)
+
+	runCommand(m, "clear under internal")
+	assert.ElementsMatch(t, []string{"/root/main.go", "/root/main_test.go"}, selected(m))
+
+	runCommand(m, "invert")
+	assert.Len(t, selected(m), 3, "the cursor is on the root, so the whole tree flips")
+}
+
+func TestCommandExtensionAndGrep(t *testing.T) {
+	m := commandTestModel(t)
+
+	runCommand(m, "select ext md")
+	assert.Equal(t, []string{"/root/internal/notes.md"}, selected(m))
+
+	runCommand(m, "select grep TODO")
+	assert.ElementsMatch(t, []string{"/root/internal/a.go", "/root/internal/notes.md"}, selected(m))
+}
+
+func TestCommandErrorsAndCancel(t *testing.T) {
+	m := commandTestModel(t)
+
+	runCommand(m, "select *.go under nowhere")
+	assert.Contains(t, m.View(), `no directory "nowhere"`)
+
+	runCommand(m, "frobnicate")
+	assert.Contains(t, m.View(), `unknown command "frobnicate"`)
+
+	// q is typed into the palette rather than quitting, and esc discards the line
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{':'}})
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'q'}})
+	assert.Contains(t, m.View(), ":q")
+	m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	assert.Empty(t, selected(m))
+	assert.NotContains(t, m.View(), ":q")
+}
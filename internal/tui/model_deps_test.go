@@ -0,0 +1,90 @@
+package tui_test
+
+import (
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/eliooooooot/picky/internal/domain"
+	pickyfs "github.com/eliooooooot/picky/internal/fs"
+	"github.com/eliooooooot/picky/internal/tui"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func depsTestModel(t *testing.T) *tui.Model {
+	fs := pickyfs.NewMemFileSystem()
+	fs.WriteFile("/m/go.mod", []byte("module example.com/m\n"), 0644)
+	fs.WriteFile("/m/main.go", []byte("package main\n\nimport \"example.com/m/api\"\n"), 0644)
+	fs.WriteFile("/m/api/api.go", []byte("package api\n\nimport \"example.com/m/store\"\n"), 0644)
+	fs.WriteFile("/m/store/store.go", []byte("package store\n"), 0644)
+
+	tree, err := domain.BuildTree(fs, "/m")
+	require.NoError(t, err)
+
+	ignores := make(map[string]struct{})
+	model := tui.NewModel(tree, &ignores)
+	model.SetFileSystem(fs)
+	model.SetTokens(map[string]int{"/m/main.go": 10, "/m/api/api.go": 200, "/m/store/store.go": 3000})
+	model.Init()
+	model.Update(tea.WindowSizeMsg{Width: 100, Height: 40})
+	return model
+}
+
+// cursorTo moves the cursor down n rows
+func cursorTo(m *tui.Model, n int) {
+	for i := 0; i < n; i++ {
+		m.Update(tea.KeyMsg{Type: tea.KeyDown})
+	}
+}
+
+func TestSelectDependenciesPreviewsThenSelects(t *testing.T) {
+	m := depsTestModel(t)
+
+	// Rows: m, api, store, go.mod, main.go
+	cursorTo(m, 4)
+	require.Equal(t, "/m/main.go", m.State().CursorPath)
+
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'d'}})
+	view := m.View()
+	assert.Contains(t, view, "Dependencies of main.go (depth 1)")
+	assert.Contains(t, view, "~0 → ~200 tokens (+200)")
+	assert.Contains(t, view, "+ api (1 files, ~200)")
+	assert.False(t, m.State().IsSelected("/m/api/api.go"), "nothing is selected before confirming")
+
+	m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	assert.Equal(t, []string{"/m/api/api.go"}, domain.GetSelectedPaths(m.Tree().Root, m.State()))
+}
+
+func TestSelectDependenciesFollowsDepth(t *testing.T) {
+	m := depsTestModel(t)
+	m.SetDepsDepth(0)
+	cursorTo(m, 4)
+
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'d'}})
+	assert.Contains(t, m.View(), "Dependencies of main.go (all levels)")
+	m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	assert.ElementsMatch(t, []string{"/m/api/api.go", "/m/store/store.go"}, domain.GetSelectedPaths(m.Tree().Root, m.State()))
+}
+
+func TestSelectDependentsAndCancel(t *testing.T) {
+	m := depsTestModel(t)
+	cursorTo(m, 1) // api directory
+
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'D'}})
+	assert.Contains(t, m.View(), "Dependents of api (depth 1)")
+	m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	assert.NotContains(t, m.View(), "Dependents of")
+	assert.Empty(t, domain.GetSelectedPaths(m.Tree().Root, m.State()))
+
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'D'}})
+	m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	assert.Equal(t, []string{"/m/main.go"}, domain.GetSelectedPaths(m.Tree().Root, m.State()))
+}
+
+func TestSelectDependenciesOnNonGoFile(t *testing.T) {
+	m := depsTestModel(t)
+	cursorTo(m, 3) // go.mod
+
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'d'}})
+	assert.Contains(t, m.View(), "No Go files at the cursor")
+}
@@ -0,0 +1,70 @@
+package tui_test
+
+import (
+	"bytes"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/eliooooooot/picky/internal/domain"
+	pickyfs "github.com/eliooooooot/picky/internal/fs"
+	"github.com/eliooooooot/picky/internal/tui"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestForceKeyIncludesSkippedFiles(t *testing.T) {
+	fs := pickyfs.NewMemFileSystem()
+	fs.WriteFile("/root/go.sum", []byte("example.com/x v1.0.0 h1:abc=\n"), 0644)
+	fs.WriteFile("/root/main.go", []byte("package main\n"), 0644)
+
+	tree, err := domain.BuildTree(fs, "/root")
+	require.NoError(t, err)
+
+	ignores := make(map[string]struct{})
+	m := tui.NewModel(tree, &ignores)
+	m.SetTokens(map[string]int{"/root/go.sum": 700, "/root/main.go": 20})
+	m.Init()
+	m.Update(tea.WindowSizeMsg{Width: 100, Height: 30})
+
+	view := m.View()
+	assert.Contains(t, view, "go.sum (0) [generated]")
+	assert.Contains(t, view, "root (20)", "skipped files are left out of directory totals")
+
+	// Rows: root, go.sum, main.go
+	cursorTo(m, 1)
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'!'}})
+	assert.True(t, m.State().IsSelected("/root/go.sum"))
+	assert.True(t, m.State().IsForced("/root/go.sum"))
+	view = m.View()
+	assert.Contains(t, view, "go.sum (700) [generated, forced]")
+	assert.Contains(t, view, "Forced go.sum into the output")
+
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'!'}})
+	assert.False(t, m.State().IsForced("/root/go.sum"))
+	assert.True(t, m.State().IsSelected("/root/go.sum"), "unforcing keeps the selection")
+
+	cursorTo(m, 2)
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'!'}})
+	assert.False(t, m.State().IsForced("/root/main.go"))
+	assert.Contains(t, m.View(), "main.go is already included in full")
+}
+
+func TestOversizedFilesShowTheirSize(t *testing.T) {
+	fs := pickyfs.NewMemFileSystem()
+	fs.WriteFile("/root/big.log", bytes.Repeat([]byte("log line\n"), 3<<20/9+1), 0644)
+	fs.WriteFile("/root/main.go", []byte("package main\n"), 0644)
+
+	tree, err := domain.BuildTree(fs, "/root")
+	require.NoError(t, err)
+
+	ignores := make(map[string]struct{})
+	m := tui.NewModel(tree, &ignores)
+	m.SetTokens(map[string]int{"/root/big.log": 0, "/root/main.go": 20})
+	m.SetMaxFileSize(1 << 20)
+	m.Init()
+	m.Update(tea.WindowSizeMsg{Width: 100, Height: 30})
+
+	view := m.View()
+	assert.Contains(t, view, "big.log (3.0 MB, too large to count)")
+	assert.Contains(t, view, "main.go (20)")
+}
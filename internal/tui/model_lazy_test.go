@@ -0,0 +1,62 @@
+package tui_test
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/eliooooooot/picky/internal/domain"
+	pickyfs "github.com/eliooooooot/picky/internal/fs"
+	"github.com/eliooooooot/picky/internal/token"
+	"github.com/eliooooooot/picky/internal/tui"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func lazyTestModel(t *testing.T) *tui.Model {
+	fs := pickyfs.NewMemFileSystem()
+	fs.WriteFile("/root/a/one.txt", []byte(strings.Repeat("x", 400)), 0644)
+	fs.WriteFile("/root/a/deep/two.txt", []byte(strings.Repeat("y", 40)), 0644)
+	fs.WriteFile("/root/b.txt", []byte(strings.Repeat("z", 8)), 0644)
+
+	tree, err := domain.BuildLazyTree(fs, "/root")
+	require.NoError(t, err)
+	tokens, err := token.NewCounter(fs, token.NaiveTokenizer{}).BuildTreeTokenMap(tree)
+	require.NoError(t, err)
+
+	ignores := make(map[string]struct{})
+	model := tui.NewModel(tree, &ignores)
+	model.SetFileSystem(fs)
+	model.SetTokens(tokens)
+	model.Init()
+	return model
+}
+
+func TestExpandLoadsDirectoryInBackground(t *testing.T) {
+	m := lazyTestModel(t)
+	press(m, "down")
+	require.Equal(t, "/root/a", m.State().CursorPath)
+	assert.Contains(t, m.View(), "a (?)", "unloaded totals are unknown")
+	assert.Contains(t, m.View(), "root (2+)")
+
+	_, cmd := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("l")})
+	require.NotNil(t, cmd)
+	assert.Contains(t, m.View(), "(loading…)")
+	assert.False(t, m.State().IsOpen("/root/a"))
+
+	m.Update(cmd())
+	assert.True(t, m.State().IsOpen("/root/a"))
+	view := m.View()
+	assert.NotContains(t, view, "(loading…)")
+	assert.Contains(t, view, "one.txt (100)")
+	assert.Contains(t, view, "a (100+)", "deep is still unloaded")
+}
+
+func TestSelectingUnloadedDirectoryCountsItsTokens(t *testing.T) {
+	m := lazyTestModel(t)
+	press(m, "down", " ")
+
+	assert.True(t, m.State().IsSelected("/root/a/deep/two.txt"))
+	assert.Contains(t, m.View(), "root (112)")
+	assert.Contains(t, m.View(), "✓ ▶ a (110)")
+}
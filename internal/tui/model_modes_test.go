@@ -0,0 +1,157 @@
+package tui_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/eliooooooot/picky/internal/domain"
+	pickyfs "github.com/eliooooooot/picky/internal/fs"
+	"github.com/eliooooooot/picky/internal/generate"
+	"github.com/eliooooooot/picky/internal/tui"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// send updates the model with msg and delivers the results of the
+// commands that follow, as the program loop would. Commands still running
+// after a moment, such as the timers clearing status messages, are left.
+func send(m *tui.Model, msg tea.Msg) {
+	_, cmd := m.Update(msg)
+	run(m, cmd)
+}
+
+func run(m *tui.Model, cmd tea.Cmd) {
+	if cmd == nil {
+		return
+	}
+	done := make(chan tea.Msg, 1)
+	go func() { done <- cmd() }()
+	select {
+	case msg := <-done:
+		if batch, ok := msg.(tea.BatchMsg); ok {
+			for _, cmd := range batch {
+				run(m, cmd)
+			}
+			return
+		}
+		send(m, msg)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestOutlineKeyShowsReducedTokens(t *testing.T) {
+	fs := pickyfs.NewMemFileSystem()
+	fs.WriteFile("/root/main.go", []byte("package main\n\nfunc main() {\n\tprintln(\"a long body that the outline leaves out\")\n}\n"), 0644)
+	fs.WriteFile("/root/go.sum", []byte("example.com/x v1.0.0 h1:abc=\n"), 0644)
+
+	tree, err := domain.BuildTree(fs, "/root")
+	require.NoError(t, err)
+
+	ignores := make(map[string]struct{})
+	m := tui.NewModel(tree, &ignores)
+	m.SetFileSystem(fs)
+	m.SetTokens(map[string]int{"/root/main.go": 100, "/root/go.sum": 7})
+	m.Init()
+	m.Update(tea.WindowSizeMsg{Width: 100, Height: 30})
+
+	// Rows: root, go.sum, main.go
+	cursorTo(m, 2)
+	_, cmd := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'S'}})
+	assert.True(t, m.State().IsSelected("/root/main.go"))
+	assert.Equal(t, domain.IncludeOutline, m.State().Mode("/root/main.go"))
+	view := m.View()
+	assert.Contains(t, view, "main.go (? outline of 100) [outline]", "outlines are counted in the background")
+	assert.Contains(t, view, "Outlined main.go")
+	run(m, cmd)
+	assert.Contains(t, m.View(), "main.go (7 outline of 100) [outline]")
+
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'S'}})
+	assert.Equal(t, domain.IncludeFull, m.State().Mode("/root/main.go"))
+	assert.Contains(t, m.View(), "main.go (100)")
+
+	m.Update(tea.KeyMsg{Type: tea.KeyUp})
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'S'}})
+	assert.Equal(t, domain.IncludeFull, m.State().Mode("/root/go.sum"))
+	assert.Contains(t, m.View(), "go.sum is not written as text")
+
+	// With outlining on for every file the counts drop without the badge
+	m.SetOutputOptions(generate.Options{Outline: true})
+	send(m, tea.KeyMsg{Type: tea.KeyDown})
+	assert.Contains(t, m.View(), "main.go (7 outline of 100)")
+	assert.NotContains(t, m.View(), "[outline]")
+}
+
+func TestCycleModeKeyShowsMarkers(t *testing.T) {
+	fs := pickyfs.NewMemFileSystem()
+	fs.WriteFile("/root/main.go", []byte("package main\n\nfunc main() {\n\tprintln(\"a long body that the outline leaves out\")\n}\n"), 0644)
+
+	tree, err := domain.BuildTree(fs, "/root")
+	require.NoError(t, err)
+
+	ignores := make(map[string]struct{})
+	m := tui.NewModel(tree, &ignores)
+	m.SetFileSystem(fs)
+	m.SetTokens(map[string]int{"/root/main.go": 100})
+	m.SetOutputOptions(generate.Options{
+		LargeFileLines: 1,
+		Diff:           func(string) ([]byte, error) { return []byte("+x\n"), nil },
+	})
+	m.Init()
+	m.Update(tea.WindowSizeMsg{Width: 100, Height: 30})
+	cursorTo(m, 1)
+
+	steps := []struct {
+		mode  domain.InclusionMode
+		label string
+	}{
+		{domain.IncludeOutline, "main.go (7 outline of 100) [outline]"},
+		{domain.IncludeHead, "main.go (4 head of 100) [head]"},
+		{domain.IncludeDiff, "main.go (1 diff of 100) [diff]"},
+		{domain.IncludePath, "main.go (0 path of 100) [path only]"},
+		{domain.IncludeFull, "main.go (100)"},
+	}
+	for _, step := range steps {
+		send(m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'m'}})
+		assert.Equal(t, step.mode, m.State().Mode("/root/main.go"))
+		assert.True(t, m.State().IsSelected("/root/main.go"))
+		assert.Contains(t, m.View(), step.label)
+	}
+	assert.Contains(t, m.View(), "main.go: full content")
+}
+
+func TestInclusionModesCountTowardTotalsAndBudget(t *testing.T) {
+	fs := pickyfs.NewMemFileSystem()
+	fs.WriteFile("/root/main.go", []byte("package main\n\nfunc main() {\n\tprintln(\"a long body that the outline leaves out\")\n}\n"), 0644)
+	fs.WriteFile("/root/other.go", []byte("package main\n"), 0644)
+
+	tree, err := domain.BuildTree(fs, "/root")
+	require.NoError(t, err)
+
+	ignores := make(map[string]struct{})
+	m := tui.NewModel(tree, &ignores)
+	m.SetFileSystem(fs)
+	m.SetTokens(map[string]int{"/root/main.go": 100, "/root/other.go": 300})
+	m.SetBudget(200)
+	m.Init()
+	m.Update(tea.WindowSizeMsg{Width: 100, Height: 30})
+
+	// Rows: root, main.go, other.go
+	cursorTo(m, 1)
+	send(m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'S'}})
+	cursorTo(m, 1)
+	press(m, " ")
+	structure := generate.StructureTokens(m.Tree(), m.State(), m.OutputOptions())
+	press(m, "f")
+	view := m.View()
+	assert.Contains(t, view, fmt.Sprintf("~%d → ~200 tokens", 307+structure), "the outlined file counts its outline")
+	assert.Contains(t, view, fmt.Sprintf("✂ other.go (keep %d of 300)", 193-structure))
+
+	press(m, "esc", "m", "m", "m", "m")
+	require.Equal(t, domain.IncludePath, m.State().Mode("/root/other.go"))
+	structure = generate.StructureTokens(m.Tree(), m.State(), m.OutputOptions())
+	header := strings.Split(m.View(), "\n")[0]
+	assert.Contains(t, header, fmt.Sprintf("~%d / 200", 7+structure), "a path-only file costs nothing")
+}
@@ -0,0 +1,101 @@
+package tui_test
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/eliooooooot/picky/internal/domain"
+	"github.com/eliooooooot/picky/internal/tui"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func mouseTestModel() *tui.Model {
+	root := &domain.Node{Path: "/root", Name: "root", IsDir: true}
+	dir := &domain.Node{Path: "/root/dir", Name: "dir", IsDir: true, Parent: root}
+	inner := &domain.Node{Path: "/root/dir/inner.txt", Name: "inner.txt", Parent: dir}
+	dir.Children = []*domain.Node{inner}
+	file := &domain.Node{Path: "/root/file.txt", Name: "file.txt", Parent: root}
+	root.Children = []*domain.Node{dir, file}
+
+	ignores := make(map[string]struct{})
+	model := tui.NewModel(&domain.Tree{Root: root}, &ignores)
+	model.Init()
+	model.Update(tea.WindowSizeMsg{Width: 100, Height: 30})
+	model.Update(tea.KeyMsg{Type: tea.KeyRight}) // open root
+	return model
+}
+
+// rowOf renders the model and returns the screen row showing name
+func rowOf(t *testing.T, m *tui.Model, name string) int {
+	t.Helper()
+	for i, line := range strings.Split(m.View(), "\n") {
+		if strings.Contains(line, " "+name+" (") {
+			return i
+		}
+	}
+	require.Failf(t, "row not found", "%q is not rendered", name)
+	return -1
+}
+
+func click(m *tui.Model, x, y int) {
+	m.Update(tea.MouseMsg{X: x, Y: y, Button: tea.MouseButtonLeft, Action: tea.MouseActionPress})
+}
+
+func TestMouseClickMovesCursor(t *testing.T) {
+	model := mouseTestModel()
+
+	click(model, 20, rowOf(t, model, "file.txt"))
+	assert.Equal(t, "/root/file.txt", model.State().CursorPath)
+	assert.False(t, model.State().IsSelected("/root/file.txt"), "clicking the name does not select")
+}
+
+func TestMouseClickCheckboxToggles(t *testing.T) {
+	model := mouseTestModel()
+
+	// file.txt is at depth 1: 4-column enumerator plus 3 columns of indent
+	y := rowOf(t, model, "file.txt")
+	click(model, 7, y)
+	assert.True(t, model.State().IsSelected("/root/file.txt"))
+
+	click(model, 7, y)
+	assert.False(t, model.State().IsSelected("/root/file.txt"))
+}
+
+func TestMouseClickArrowExpands(t *testing.T) {
+	model := mouseTestModel()
+
+	y := rowOf(t, model, "dir")
+	click(model, 9, y)
+	assert.True(t, model.State().IsOpen("/root/dir"))
+	assert.Contains(t, model.View(), "inner.txt")
+
+	click(model, 9, y)
+	assert.False(t, model.State().IsOpen("/root/dir"))
+}
+
+func TestMouseIgnoredOutsideTree(t *testing.T) {
+	model := mouseTestModel()
+
+	click(model, 7, 0) // header row
+	assert.Equal(t, "/root", model.State().CursorPath)
+}
+
+func TestMouseWheelScrolls(t *testing.T) {
+	root := &domain.Node{Path: "/root", Name: "root", IsDir: true}
+	for _, name := range strings.Split("a b c d e f g h i j k l m n o p q r s t u v w x y z", " ") {
+		root.Children = append(root.Children, &domain.Node{Path: "/root/" + name, Name: name, Parent: root})
+	}
+	ignores := make(map[string]struct{})
+	model := tui.NewModel(&domain.Tree{Root: root}, &ignores)
+	model.Update(tea.WindowSizeMsg{Width: 80, Height: 20})
+	model.Update(tea.KeyMsg{Type: tea.KeyRight})
+	model.View()
+
+	model.Update(tea.MouseMsg{Button: tea.MouseButtonWheelDown, Action: tea.MouseActionPress})
+	assert.Equal(t, 3, model.VP().YOffset)
+
+	model.Update(tea.MouseMsg{Button: tea.MouseButtonWheelUp, Action: tea.MouseActionPress})
+	assert.Equal(t, 0, model.VP().YOffset)
+}
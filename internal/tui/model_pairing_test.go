@@ -0,0 +1,142 @@
+package tui_test
+
+import (
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/eliooooooot/picky/internal/domain"
+	pickyfs "github.com/eliooooooot/picky/internal/fs"
+	"github.com/eliooooooot/picky/internal/tui"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func pairingTestModel(t *testing.T) *tui.Model {
+	fs := pickyfs.NewMemFileSystem()
+	fs.WriteFile("/root/foo.go", []byte("package foo\n"), 0644)
+	fs.WriteFile("/root/foo_test.go", []byte("package foo\n"), 0644)
+	fs.WriteFile("/root/widget.ts", []byte("export {}\n"), 0644)
+	fs.WriteFile("/root/widget.spec.ts", []byte("test()\n"), 0644)
+
+	tree, err := domain.BuildTree(fs, "/root")
+	require.NoError(t, err)
+
+	ignores := make(map[string]struct{})
+	model := tui.NewModel(tree, &ignores)
+	model.Init()
+	model.Update(tea.WindowSizeMsg{Width: 100, Height: 30})
+	return model
+}
+
+func TestPairKeyTogglesPairedFile(t *testing.T) {
+	m := pairingTestModel(t)
+
+	// Rows: root, foo.go, foo_test.go, widget.spec.ts, widget.ts
+	cursorTo(m, 1)
+	require.Equal(t, "/root/foo.go", m.State().CursorPath)
+
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'t'}})
+	assert.True(t, m.State().IsSelected("/root/foo_test.go"))
+	assert.False(t, m.State().IsSelected("/root/foo.go"), "only the paired file changes")
+	assert.Contains(t, m.View(), "Selected foo_test.go")
+
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'t'}})
+	assert.False(t, m.State().IsSelected("/root/foo_test.go"))
+
+	// The reverse: from a spec file to its source
+	cursorTo(m, 2)
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'t'}})
+	assert.True(t, m.State().IsSelected("/root/widget.ts"))
+}
+
+func TestPairTestsSettingSelectsPairsAutomatically(t *testing.T) {
+	m := pairingTestModel(t)
+	s := m.Settings()
+	s.PairTests = true
+	m.SetSettings(s)
+
+	cursorTo(m, 1)
+	m.Update(tea.KeyMsg{Type: tea.KeySpace})
+	assert.True(t, m.State().IsSelected("/root/foo.go"))
+	assert.True(t, m.State().IsSelected("/root/foo_test.go"))
+
+	// Deselecting leaves the pair alone
+	m.Update(tea.KeyMsg{Type: tea.KeySpace})
+	assert.False(t, m.State().IsSelected("/root/foo.go"))
+	assert.True(t, m.State().IsSelected("/root/foo_test.go"))
+}
+
+func TestPairRulesAreConfigurable(t *testing.T) {
+	m := pairingTestModel(t)
+	m.SetPairRules([]domain.PairRule{{Source: "*.go", Test: "*_test.go"}})
+
+	cursorTo(m, 4)
+	require.Equal(t, "/root/widget.ts", m.State().CursorPath)
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'t'}})
+	assert.False(t, m.State().IsSelected("/root/widget.spec.ts"))
+	assert.Contains(t, m.View(), "No paired file for widget.ts")
+}
+
+func TestPairTestsFollowEverySelectionPath(t *testing.T) {
+	m := pairingTestModel(t)
+	s := m.Settings()
+	s.PairTests = true
+	m.SetSettings(s)
+
+	// The command palette
+	runCommand(m, "select widget.ts")
+	assert.True(t, m.State().IsSelected("/root/widget.spec.ts"))
+
+	// A visual range over foo.go
+	cursorTo(m, 1)
+	require.Equal(t, "/root/foo.go", m.State().CursorPath)
+	press(m, "v", " ")
+	assert.True(t, m.State().IsSelected("/root/foo.go"))
+	assert.True(t, m.State().IsSelected("/root/foo_test.go"))
+}
+
+func TestPairTestsFollowDependencySelection(t *testing.T) {
+	fs := pickyfs.NewMemFileSystem()
+	fs.WriteFile("/m/go.mod", []byte("module example.com/m\n"), 0644)
+	fs.WriteFile("/m/main.go", []byte("package main\n\nimport \"example.com/m/api\"\n"), 0644)
+	fs.WriteFile("/m/api/api.go", []byte("package api\n"), 0644)
+	fs.WriteFile("/m/api/api_test.go", []byte("package api\n"), 0644)
+
+	tree, err := domain.BuildTree(fs, "/m")
+	require.NoError(t, err)
+	ignores := make(map[string]struct{})
+	m := tui.NewModel(tree, &ignores)
+	m.SetFileSystem(fs)
+	m.Init()
+	m.Update(tea.WindowSizeMsg{Width: 100, Height: 30})
+	s := m.Settings()
+	s.PairTests = true
+	m.SetSettings(s)
+
+	// Rows: m, api, go.mod, main.go
+	cursorTo(m, 3)
+	require.Equal(t, "/m/main.go", m.State().CursorPath)
+	press(m, "d")
+	m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	assert.True(t, m.State().IsSelected("/m/api/api.go"))
+	assert.True(t, m.State().IsSelected("/m/api/api_test.go"))
+}
+
+func TestPairTestsFollowModeChanges(t *testing.T) {
+	m := pairingTestModel(t)
+	s := m.Settings()
+	s.PairTests = true
+	m.SetSettings(s)
+
+	cursorTo(m, 1)
+	require.Equal(t, "/root/foo.go", m.State().CursorPath)
+	press(m, "m")
+	assert.Equal(t, domain.IncludeOutline, m.State().Mode("/root/foo.go"))
+	assert.True(t, m.State().IsSelected("/root/foo_test.go"))
+
+	// Forcing and outlining select through the same path
+	cursorTo(m, 3)
+	require.Equal(t, "/root/widget.ts", m.State().CursorPath)
+	press(m, "S")
+	assert.True(t, m.State().IsSelected("/root/widget.spec.ts"))
+}
@@ -0,0 +1,138 @@
+package tui_test
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/eliooooooot/picky/internal/domain"
+	pickyfs "github.com/eliooooooot/picky/internal/fs"
+	"github.com/eliooooooot/picky/internal/tui"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func searchTestModel(t *testing.T) *tui.Model {
+	fs := pickyfs.NewMemFileSystem()
+	fs.WriteFile("/root/limit.go", []byte("package limit\n\ntype RateLimiter struct{}\n\nfunc other() {}\n"), 0644)
+	fs.WriteFile("/root/notes.md", []byte("the RateLimiter is slow\n"), 0644)
+	fs.WriteFile("/root/image.png", []byte("RateLimiter\x00"), 0644)
+	fs.WriteFile("/root/main.go", []byte("package main\n"), 0644)
+
+	tree, err := domain.BuildTree(fs, "/root")
+	require.NoError(t, err)
+
+	ignores := make(map[string]struct{})
+	model := tui.NewModel(tree, &ignores)
+	model.SetFileSystem(fs)
+	model.Init()
+	model.Update(tea.WindowSizeMsg{Width: 100, Height: 40})
+	return model
+}
+
+// search types a query into the search input and runs it
+func search(m *tui.Model, query string) {
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'/'}})
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(query)})
+	send(m, tea.KeyMsg{Type: tea.KeyEnter})
+}
+
+func TestSearchShowsResultsWithCountsAndPreview(t *testing.T) {
+	m := searchTestModel(t)
+
+	search(m, "RateLimiter")
+	view := m.View()
+	modal := view[strings.Index(view, "Search \""):]
+	assert.Contains(t, view, "2 matches in 2 files")
+	assert.Contains(t, view, "limit.go (1)")
+	assert.Contains(t, view, "3: type RateLimiter struct{}")
+	assert.NotContains(t, modal, "image.png", "binary files are skipped")
+}
+
+func TestSearchSelectAllMatches(t *testing.T) {
+	m := searchTestModel(t)
+
+	search(m, "RateLimiter")
+	m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+
+	assert.ElementsMatch(t, []string{"/root/limit.go", "/root/notes.md"}, domain.GetSelectedPaths(m.Tree().Root, m.State()))
+	assert.Nil(t, m.State().LineRanges("/root/limit.go"))
+	assert.Contains(t, m.View(), `Selected 2 files matching "RateLimiter"`)
+}
+
+func TestSearchSelectEnclosingFunctions(t *testing.T) {
+	m := searchTestModel(t)
+
+	search(m, "func other")
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'F'}})
+
+	assert.True(t, m.State().IsSelected("/root/limit.go"))
+	assert.Equal(t, []domain.LineRange{{Start: 5, End: 5}}, m.State().LineRanges("/root/limit.go"))
+	assert.Contains(t, m.View(), "limit.go (0) [1 ranges]")
+}
+
+func TestSearchSelectOneResultAndClose(t *testing.T) {
+	m := searchTestModel(t)
+
+	search(m, "RateLimiter")
+	m.Update(tea.KeyMsg{Type: tea.KeyDown})
+	m.Update(tea.KeyMsg{Type: tea.KeySpace})
+	m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+
+	assert.Equal(t, []string{"/root/notes.md"}, domain.GetSelectedPaths(m.Tree().Root, m.State()))
+	assert.NotContains(t, m.View(), "matches in")
+}
+
+func TestSearchNoMatchesAndBadRegex(t *testing.T) {
+	m := searchTestModel(t)
+
+	search(m, "Nowhere")
+	assert.Contains(t, m.View(), `No files match "Nowhere"`)
+
+	search(m, "(")
+	assert.Contains(t, m.View(), "Invalid regex")
+}
+
+func TestSearchRunsInTheBackground(t *testing.T) {
+	m := searchTestModel(t)
+
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'/'}})
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("RateLimiter")})
+	_, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	require.NotNil(t, cmd)
+	assert.Contains(t, m.View(), `Searching for "RateLimiter"`)
+	assert.NotContains(t, m.View(), "matches in", "results wait for the search to finish")
+
+	run(m, cmd)
+	assert.Contains(t, m.View(), "2 matches in 2 files")
+}
+
+func TestSearchToggleDeselectsResult(t *testing.T) {
+	m := searchTestModel(t)
+
+	search(m, "RateLimiter")
+	m.Update(tea.KeyMsg{Type: tea.KeySpace})
+	assert.True(t, m.State().IsSelected("/root/limit.go"))
+	m.Update(tea.KeyMsg{Type: tea.KeySpace})
+	assert.False(t, m.State().IsSelected("/root/limit.go"))
+	assert.Contains(t, m.View(), "[ ] limit.go (1)")
+}
+
+func TestSearchReachesUnloadedDirectories(t *testing.T) {
+	fs := pickyfs.NewMemFileSystem()
+	fs.WriteFile("/root/pkg/deep/limit.go", []byte("type RateLimiter struct{}\n"), 0644)
+	fs.WriteFile("/root/other/main.go", []byte("package main\n"), 0644)
+	tree, err := domain.BuildLazyTree(fs, "/root")
+	require.NoError(t, err)
+	ignores := make(map[string]struct{})
+	m := tui.NewModel(tree, &ignores)
+	m.SetFileSystem(fs)
+	m.Init()
+	m.Update(tea.WindowSizeMsg{Width: 100, Height: 40})
+
+	search(m, "RateLimiter")
+	assert.Contains(t, m.View(), "1 matches in 1 files")
+	send(m, tea.KeyMsg{Type: tea.KeyEnter})
+	assert.Equal(t, []string{"/root/pkg/deep/limit.go"}, domain.GetSelectedPaths(m.Tree().Root, m.State()))
+	assert.False(t, domain.FindNodeByPath(m.Tree().Root, "/root/other").IsLoaded(), "directories without matches stay unloaded")
+}
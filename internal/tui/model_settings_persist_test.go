@@ -0,0 +1,125 @@
+package tui_test
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/eliooooooot/picky/internal/domain"
+	"github.com/eliooooooot/picky/internal/generate"
+	"github.com/eliooooooot/picky/internal/tui"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func settingsTestModel() *tui.Model {
+	root := &domain.Node{Path: "/root", Name: "root", IsDir: true}
+	env := &domain.Node{Path: "/root/.env", Name: ".env", Parent: root}
+	file := &domain.Node{Path: "/root/main.go", Name: "main.go", Parent: root}
+	root.Children = []*domain.Node{env, file}
+
+	ignores := make(map[string]struct{})
+	model := tui.NewModel(&domain.Tree{Root: root}, &ignores)
+	model.Init()
+	model.SetTokens(map[string]int{"/root/.env": 5, "/root/main.go": 1500})
+	model.Update(tea.WindowSizeMsg{Width: 80, Height: 30})
+	return model
+}
+
+func press(m *tui.Model, keys ...string) {
+	for _, k := range keys {
+		switch k {
+		case "up":
+			m.Update(tea.KeyMsg{Type: tea.KeyUp})
+		case "down":
+			m.Update(tea.KeyMsg{Type: tea.KeyDown})
+		case "right":
+			m.Update(tea.KeyMsg{Type: tea.KeyRight})
+		case "esc":
+			m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+		default:
+			m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(k)})
+		}
+	}
+}
+
+func TestSettingsSavedWhenModalCloses(t *testing.T) {
+	model := settingsTestModel()
+	var saved []tui.Settings
+	var before []tui.Settings
+	model.SetSettingsSaver(func(prev, s tui.Settings) error {
+		before = append(before, prev)
+		saved = append(saved, s)
+		return nil
+	})
+
+	// Open and close without changes: nothing to save
+	press(model, "s", "esc")
+	assert.Empty(t, saved)
+
+	// Row 2 is the output format
+	press(model, "s", "down", "down", "right", "esc")
+	require.Len(t, saved, 1)
+	assert.Equal(t, generate.FormatXML, saved[0].Format)
+	assert.Equal(t, generate.FormatText, before[0].Format, "the saver is given the settings from when the modal opened")
+	assert.Equal(t, generate.FormatXML, model.OutputOptions().Format)
+}
+
+func TestSettingsPaneShowsNewOptions(t *testing.T) {
+	model := settingsTestModel()
+	press(model, "s")
+
+	view := model.View()
+	for _, want := range []string{"Output format: ← text →", "Tokenizer: ← naive →", "[x] Show hidden files", "Sort order: ← name →", "Token counts: ← compact →"} {
+		assert.Contains(t, view, want)
+	}
+}
+
+func TestShowHiddenSetting(t *testing.T) {
+	model := settingsTestModel()
+	press(model, "l")
+	assert.Contains(t, model.View(), ".env")
+
+	s := model.Settings()
+	s.ShowHidden = false
+	model.SetSettings(s)
+	assert.NotContains(t, model.View(), ".env")
+
+	s.ShowHidden = true
+	model.SetSettings(s)
+	assert.Contains(t, model.View(), ".env")
+}
+
+func TestTokenDisplaySetting(t *testing.T) {
+	model := settingsTestModel()
+	press(model, "l")
+	assert.Contains(t, model.View(), "main.go (1.5k)")
+
+	s := model.Settings()
+	s.TokenDisplay = tui.TokenDisplayExact
+	model.SetSettings(s)
+	assert.Contains(t, model.View(), "main.go (1500)")
+
+	s.TokenDisplay = tui.TokenDisplayOff
+	model.SetSettings(s)
+	view := model.View()
+	assert.True(t, strings.Contains(view, "main.go") && !strings.Contains(view, "main.go ("))
+}
+
+func TestToggleHiddenKeyKeepsAllowlist(t *testing.T) {
+	model := settingsTestModel()
+	model.SetHiddenAllow([]string{".env"})
+	press(model, "l")
+
+	press(model, ".")
+	assert.False(t, model.Settings().ShowHidden)
+	assert.Contains(t, model.View(), "Hiding hidden files")
+	assert.Contains(t, model.View(), ".env", "allowlisted dotfiles stay visible")
+
+	model.SetHiddenAllow(nil)
+	assert.NotContains(t, model.View(), ".env (")
+
+	press(model, ".")
+	assert.True(t, model.Settings().ShowHidden)
+	assert.Contains(t, model.View(), ".env (5)")
+}
@@ -99,12 +99,12 @@ func TestSettingsModalToggle(t *testing.T) {
 		model.isSettingsOpen = true
 		model.settingsCursorIdx = 0
 		
-		// Test up wraps to bottom (which is still 0 since we only have 1 item)
+		// Test up wraps to bottom
 		updatedModel, _ := model.Update(tea.KeyMsg{Type: tea.KeyUp})
 		m := updatedModel.(*Model)
-		assert.Equal(t, 0, m.settingsCursorIdx)
+		assert.Equal(t, settingCount-1, m.settingsCursorIdx)
 		
-		// Test down wraps to top (which is still 0 since we only have 1 item)
+		// Test down wraps to top
 		updatedModel, _ = m.Update(tea.KeyMsg{Type: tea.KeyDown})
 		m = updatedModel.(*Model)
 		assert.Equal(t, 0, m.settingsCursorIdx)
@@ -0,0 +1,87 @@
+package tui_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/eliooooooot/picky/internal/domain"
+	pickyfs "github.com/eliooooooot/picky/internal/fs"
+	"github.com/eliooooooot/picky/internal/tui"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func sortTestModel(t *testing.T) *tui.Model {
+	fs := pickyfs.NewMemFileSystem()
+	fs.WriteFile("/root/a.txt", []byte(strings.Repeat("a", 2048)), 0644)
+	fs.WriteFile("/root/b.txt", []byte("b"), 0644)
+	fs.WriteFile("/root/c.txt", []byte(strings.Repeat("c", 100)), 0644)
+
+	tree, err := domain.BuildTree(fs, "/root")
+	require.NoError(t, err)
+
+	ignores := make(map[string]struct{})
+	model := tui.NewModel(tree, &ignores)
+	model.Init()
+	model.SetTokens(map[string]int{"/root/a.txt": 512, "/root/b.txt": 1, "/root/c.txt": 25})
+	press(model, "l")
+	return model
+}
+
+func rowOrder(m *tui.Model) []string {
+	var names []string
+	for _, node := range domain.Flatten(m.Tree().Root, m.State())[1:] {
+		names = append(names, node.Name)
+	}
+	return names
+}
+
+func TestSortKeysCycleOrderAndDirection(t *testing.T) {
+	m := sortTestModel(t)
+	assert.Equal(t, []string{"a.txt", "b.txt", "c.txt"}, rowOrder(m))
+
+	// Tokens, largest first
+	press(m, "o")
+	assert.Equal(t, domain.SortByTokens, m.Settings().SortOrder)
+	assert.Equal(t, []string{"a.txt", "c.txt", "b.txt"}, rowOrder(m))
+	assert.Contains(t, m.View(), "Sorted by tokens, desc")
+
+	press(m, "O")
+	assert.Equal(t, domain.SortAscending, m.Settings().SortDirection)
+	assert.Equal(t, []string{"b.txt", "c.txt", "a.txt"}, rowOrder(m))
+
+	// Size, then modification time, then back to name
+	press(m, "o")
+	assert.Equal(t, domain.SortBySize, m.Settings().SortOrder)
+	assert.Equal(t, []string{"a.txt", "c.txt", "b.txt"}, rowOrder(m))
+	press(m, "o", "o")
+	assert.Equal(t, domain.SortByName, m.Settings().SortOrder)
+	assert.Equal(t, []string{"a.txt", "b.txt", "c.txt"}, rowOrder(m))
+}
+
+func TestSettingsPaneSortDirectionAndColumns(t *testing.T) {
+	m := sortTestModel(t)
+	press(m, "s")
+	view := m.View()
+	assert.Contains(t, view, "Sort direction: ← asc →")
+	assert.Contains(t, view, "[ ] Size, token and age columns")
+
+	// Rows 5 and 6 are the sort order and direction
+	press(m, "down", "down", "down", "down", "down", "right", "down", "right", "esc")
+	assert.Equal(t, domain.SortByTokens, m.Settings().SortOrder)
+	assert.Equal(t, domain.SortDescending, m.Settings().SortDirection)
+	assert.Equal(t, []string{"a.txt", "c.txt", "b.txt"}, rowOrder(m))
+}
+
+func TestColumnsShowSizeTokensAndAge(t *testing.T) {
+	m := sortTestModel(t)
+	s := m.Settings()
+	s.Columns = true
+	m.SetSettings(s)
+
+	view := m.View()
+	assert.Contains(t, view, "2.0 KB     512  now")
+	assert.Contains(t, view, "1 B       1  now")
+	assert.Contains(t, view, "2.1 KB     538  now", "the root totals its files")
+	assert.NotContains(t, view, "a.txt (512)", "token counts move out of the labels")
+}
@@ -0,0 +1,36 @@
+package tui_test
+
+import (
+	"testing"
+
+	"github.com/eliooooooot/picky/internal/domain"
+	pickyfs "github.com/eliooooooot/picky/internal/fs"
+	"github.com/eliooooooot/picky/internal/tui"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLinksShowTheirTarget(t *testing.T) {
+	fs := pickyfs.NewMemFileSystem()
+	fs.WriteFile("/root/lib/util.go", []byte("package lib"), 0644)
+	fs.AddSymlink("/root/lib/self", "..")
+	fs.AddSymlink("/root/docs", "/elsewhere/docs")
+
+	tree, err := domain.BuildTreeWithOptions(fs, "/root", domain.TreeOptions{FollowSymlinks: true})
+	require.NoError(t, err)
+	ignores := make(map[string]struct{})
+	m := tui.NewModel(tree, &ignores)
+	m.SetFileSystem(fs)
+	m.Init()
+	press(m, "down", "l")
+
+	view := m.View()
+	assert.Contains(t, view, "self → .. (loop)")
+	assert.NotContains(t, view, "docs", "broken links are left out when following")
+
+	tree, err = domain.BuildTreeWithOptions(fs, "/root", domain.TreeOptions{})
+	require.NoError(t, err)
+	m = tui.NewModel(tree, &ignores)
+	m.Init()
+	assert.Contains(t, m.View(), "docs → /elsewhere/docs (0) [symlink]")
+}
@@ -2,10 +2,14 @@ package tui
 
 import (
 	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/eliooooooot/picky/internal/domain"
+	pickyfs "github.com/eliooooooot/picky/internal/fs"
+	"github.com/eliooooooot/picky/internal/generate"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestModel_TokenFunctionality(t *testing.T) {
@@ -130,7 +134,34 @@ func TestModel_TokenFunctionality(t *testing.T) {
 		model.state = model.state.SetSelected("/root/file1.txt", true)
 		model.state = model.state.SetSelected("/root/dir1/file2.txt", true)
 		view = model.View()
-		assert.Contains(t, view, "Tokens selected: ~300")
+		structure := generate.StructureTokens(tree, model.state, model.OutputOptions())
+		assert.Greater(t, structure, 0)
+		assert.Contains(t, view, fmt.Sprintf("Tokens selected: ~%d", 300+structure))
+		
+		// Without a structure section only the files count
+		model.SetOutputOptions(generate.Options{Structure: generate.StructureNone})
+		assert.Contains(t, model.View(), "Tokens selected: ~300")
+	})
+	
+	t.Run("structure tokens are counted once per state", func(t *testing.T) {
+		ignores := make(map[string]struct{})
+		model := NewModel(tree, &ignores)
+		model.SetTokens(tokens)
+		model.Init()
+		model.state = model.state.SetSelected("/root/file1.txt", true)
+		
+		model.View()
+		cached := model.structure
+		assert.True(t, cached.ok)
+		
+		model.state = model.state.SetCursor("/root/file1.txt")
+		model.View()
+		assert.Equal(t, cached, model.structure, "moving the cursor reuses the count")
+		
+		model.state = model.state.SetSelected("/root/dir1/file2.txt", true)
+		model.View()
+		assert.NotEqual(t, cached.version, model.structure.version, "a new selection is counted again")
+		assert.Equal(t, generate.StructureTokens(tree, model.state, model.OutputOptions()), model.structure.tokens)
 	})
 	
 	t.Run("excluded nodes don't affect token counts", func(t *testing.T) {
@@ -223,4 +254,32 @@ func TestModel_TokensWithLargeNumbers(t *testing.T) {
 	totalTokens := 12345 + 1234567 // = 1246912
 	expectedHeader := fmt.Sprintf("Tokens selected: ~%.1fM", float64(totalTokens)/1000000)
 	assert.Contains(t, view, expectedHeader)
-}
\ No newline at end of file
+}
+func TestModeCountsFollowTruncation(t *testing.T) {
+	fs := pickyfs.NewMemFileSystem()
+	fs.WriteFile("/root/notes.txt", []byte(strings.Repeat("one two three\n", 10)), 0644)
+	tree, err := domain.BuildTree(fs, "/root")
+	require.NoError(t, err)
+	node := domain.FindNodeByPath(tree.Root, "/root/notes.txt")
+
+	ignores := make(map[string]struct{})
+	m := NewModel(tree, &ignores)
+	m.SetFileSystem(fs)
+	m.SetTokens(map[string]int{"/root/notes.txt": 30})
+	m.Init()
+	m.state = m.state.SetSelected(node.Path, true).SetMode(node.Path, domain.IncludeHead)
+
+	_, ok := m.modeTokenCount(node)
+	assert.False(t, ok, "counts are made in the background")
+	m.Update(m.countModes()())
+	head, ok := m.modeTokenCount(node)
+	require.True(t, ok)
+
+	m.state = m.state.SetTruncated(node.Path, 5)
+	_, ok = m.modeTokenCount(node)
+	assert.False(t, ok, "a new truncation is counted again")
+	m.Update(m.countModes()())
+	truncated, ok := m.modeTokenCount(node)
+	require.True(t, ok)
+	assert.Less(t, truncated, head)
+}
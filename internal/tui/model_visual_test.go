@@ -0,0 +1,104 @@
+package tui_test
+
+import (
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/eliooooooot/picky/internal/domain"
+	pickyfs "github.com/eliooooooot/picky/internal/fs"
+	"github.com/eliooooooot/picky/internal/tui"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func visualTestModel(t *testing.T) *tui.Model {
+	fs := pickyfs.NewMemFileSystem()
+	fs.WriteFile("/root/a.txt", []byte("a"), 0644)
+	fs.WriteFile("/root/b.txt", []byte("b"), 0644)
+	fs.WriteFile("/root/c.txt", []byte("c"), 0644)
+	fs.WriteFile("/root/d.txt", []byte("d"), 0644)
+
+	tree, err := domain.BuildTree(fs, "/root")
+	require.NoError(t, err)
+
+	ignores := make(map[string]struct{})
+	model := tui.NewModel(tree, &ignores)
+	model.Init()
+	model.Update(tea.WindowSizeMsg{Width: 80, Height: 30})
+	return model
+}
+
+func keys(m *tui.Model, runes ...string) {
+	for _, r := range runes {
+		switch r {
+		case "space":
+			m.Update(tea.KeyMsg{Type: tea.KeySpace})
+		case "esc":
+			m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+		default:
+			m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(r)})
+		}
+	}
+}
+
+func TestVisualRangeSelect(t *testing.T) {
+	m := visualTestModel(t)
+
+	// Start on b.txt and extend down to c.txt
+	keys(m, "j", "j", "v", "j")
+	assert.Contains(t, m.View(), "-- VISUAL --")
+
+	keys(m, "space")
+	state := m.State()
+	assert.False(t, state.IsSelected("/root/a.txt"))
+	assert.True(t, state.IsSelected("/root/b.txt"))
+	assert.True(t, state.IsSelected("/root/c.txt"))
+	assert.False(t, state.IsSelected("/root/d.txt"))
+	assert.NotContains(t, m.View(), "-- VISUAL --", "an action ends visual mode")
+
+	// Deselect from c.txt back up to b.txt
+	keys(m, "v", "k", "u")
+	state = m.State()
+	assert.False(t, state.IsSelected("/root/b.txt"))
+	assert.False(t, state.IsSelected("/root/c.txt"))
+}
+
+func TestVisualRangeInvert(t *testing.T) {
+	m := visualTestModel(t)
+
+	keys(m, "j", "space", "v", "j", "j", "i")
+	state := m.State()
+	assert.False(t, state.IsSelected("/root/a.txt"))
+	assert.True(t, state.IsSelected("/root/b.txt"))
+	assert.True(t, state.IsSelected("/root/c.txt"))
+	assert.False(t, state.IsSelected("/root/d.txt"))
+}
+
+func TestVisualRangeIncludingRootSelectsEverything(t *testing.T) {
+	m := visualTestModel(t)
+
+	keys(m, "v", "j", "space")
+	state := m.State()
+	for _, path := range []string{"/root/a.txt", "/root/b.txt", "/root/c.txt", "/root/d.txt"} {
+		assert.True(t, state.IsSelected(path), path)
+	}
+}
+
+func TestVisualRangeExclude(t *testing.T) {
+	m := visualTestModel(t)
+
+	keys(m, "j", "j", "v", "j", "x")
+	assert.Contains(t, m.NewIgnores(), "b.txt")
+	assert.Contains(t, m.NewIgnores(), "c.txt")
+	assert.Nil(t, domain.FindNodeByPath(m.Tree().Root, "/root/b.txt"))
+	assert.Equal(t, "/root/a.txt", m.State().CursorPath)
+}
+
+func TestVisualRangeCancel(t *testing.T) {
+	m := visualTestModel(t)
+
+	keys(m, "j", "v", "j", "q", "esc")
+	assert.NotContains(t, m.View(), "-- VISUAL --")
+	assert.False(t, m.State().IsSelected("/root/a.txt"))
+	assert.False(t, m.State().IsSelected("/root/b.txt"))
+}
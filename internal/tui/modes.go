@@ -0,0 +1,204 @@
+package tui
+
+import (
+	"fmt"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/eliooooooot/picky/internal/domain"
+	"github.com/eliooooooot/picky/internal/generate"
+	"github.com/eliooooooot/picky/internal/token"
+)
+
+// cycleMode moves the node at the cursor to the next inclusion mode,
+// selecting it
+func (m *Model) cycleMode() tea.Cmd {
+	cursor := domain.FindNodeByPath(m.tree.Root, m.state.CursorPath)
+	if cursor == nil {
+		return nil
+	}
+	state, mode := domain.CycleMode(m.tree.Root, m.state)
+	if mode == "" {
+		return m.flashStatus(fmt.Sprintf("%s has no files to include", cursor.Name))
+	}
+	m.setSelection(state)
+	return m.flashStatus(fmt.Sprintf("%s: %s", cursor.Name, modeDescription(mode)))
+}
+
+// modeDescription says what an inclusion mode writes, for the status line
+func modeDescription(mode domain.InclusionMode) string {
+	switch mode {
+	case domain.IncludeOutline:
+		return "outline (signatures only)"
+	case domain.IncludeHead:
+		return "first lines only"
+	case domain.IncludeDiff:
+		return "uncommitted changes only"
+	case domain.IncludePath:
+		return "path only, no content"
+	}
+	return "full content"
+}
+
+// toggleOutline writes the file at the cursor as an outline of its
+// declarations, selecting it, or returns it to being written in full
+func (m *Model) toggleOutline() tea.Cmd {
+	cursor := domain.FindNodeByPath(m.tree.Root, m.state.CursorPath)
+	if cursor == nil || cursor.IsDir {
+		return nil
+	}
+	if domain.IsSkipped(cursor, m.state) {
+		return m.flashStatus(fmt.Sprintf("%s is not written as text", cursor.Name))
+	}
+
+	if m.state.Mode(cursor.Path) == domain.IncludeOutline {
+		m.state = m.state.SetMode(cursor.Path, domain.IncludeFull)
+		return m.flashStatus(fmt.Sprintf("%s will be written in full", cursor.Name))
+	}
+	m.setSelection(domain.SetRangeSelected([]*domain.Node{cursor}, m.state, true))
+	m.state = m.state.SetMode(cursor.Path, domain.IncludeOutline)
+	if n, ok := m.modeTokenCount(cursor); ok {
+		return m.flashStatus(fmt.Sprintf("Outlined %s: %s of %s tokens",
+			cursor.Name, m.formatTokens(n), m.formatTokens(m.tokenCount(cursor))))
+	}
+	return m.flashStatus(fmt.Sprintf("Outlined %s", cursor.Name))
+}
+
+// effectiveMode returns how much of a file is written: its own mode, or an
+// outline when every file without line ranges is outlined
+func (m *Model) effectiveMode(node *domain.Node) domain.InclusionMode {
+	if node.IsDir {
+		return domain.IncludeFull
+	}
+	if mode := m.state.Mode(node.Path); mode != domain.IncludeFull {
+		return mode
+	}
+	if m.output.Outline && len(m.state.LineRanges(node.Path)) == 0 {
+		return domain.IncludeOutline
+	}
+	return domain.IncludeFull
+}
+
+// modesCountedMsg carries the tokens of files in their inclusion modes,
+// counted in the background
+type modesCountedMsg struct {
+	gen    int
+	counts map[string]int
+}
+
+// modeJob is a file queued for counting in the state it was queued in
+type modeJob struct {
+	path  string
+	state domain.ViewState
+}
+
+// modeKey identifies a count of a file in one inclusion mode, with the
+// truncation and line ranges that also change what the mode writes
+func modeKey(node *domain.Node, mode domain.InclusionMode, state domain.ViewState) string {
+	limit, _ := state.TruncatedTokens(node.Path)
+	return fmt.Sprintf("%s:%d:%v:%s", mode, limit, state.LineRanges(node.Path), node.Path)
+}
+
+// modeTokenCount returns the tokens a file takes in its inclusion mode and
+// whether they are known yet. Reading, outlining and diffing the file take
+// too long to do while drawing, so files are queued for a background count
+// the first time they are needed. Counts are kept until the tokenizer
+// changes.
+func (m *Model) modeTokenCount(node *domain.Node) (int, bool) {
+	mode := m.effectiveMode(node)
+	if mode == domain.IncludePath {
+		return 0, true
+	}
+	key := modeKey(node, mode, m.state)
+	if n, ok := m.modeTokens[key]; ok {
+		return n, true
+	}
+	if !m.counting[key] {
+		if m.countQueue == nil {
+			m.countQueue = make(map[string]modeJob)
+		}
+		m.countQueue[key] = modeJob{path: node.Path, state: m.state}
+	}
+	return 0, false
+}
+
+// countModes starts counting the files queued since the last call
+func (m *Model) countModes() tea.Cmd {
+	if len(m.countQueue) == 0 {
+		return nil
+	}
+	if m.counting == nil {
+		m.counting = make(map[string]bool)
+	}
+	for key := range m.countQueue {
+		m.counting[key] = true
+	}
+	queue, gen, fsys, opts := m.countQueue, m.modeGen, m.fsys, m.output
+	m.countQueue = nil
+	tz := opts.Tokenizer
+	if tz == nil {
+		tz = token.NaiveTokenizer{}
+	}
+	return func() tea.Msg {
+		counts := make(map[string]int, len(queue))
+		for key, job := range queue {
+			counts[key] = tz.CountTokens(string(generate.FileContent(job.path, job.state, fsys, opts)))
+		}
+		return modesCountedMsg{gen: gen, counts: counts}
+	}
+}
+
+// addModeCounts stores background counts and redraws the tree with them.
+// Counts made with a tokenizer since replaced are dropped.
+func (m *Model) addModeCounts(msg modesCountedMsg) {
+	if msg.gen != m.modeGen {
+		return
+	}
+	if m.modeTokens == nil {
+		m.modeTokens = make(map[string]int)
+	}
+	for key, n := range msg.counts {
+		m.modeTokens[key] = n
+		delete(m.counting, key)
+	}
+	m.vp.SetContent(m.renderWholeTree())
+}
+
+// resetModeCounts forgets every mode count, including those in progress
+func (m *Model) resetModeCounts() {
+	m.modeTokens = nil
+	m.countQueue = nil
+	m.counting = nil
+	m.modeGen++
+}
+
+// selectionTokens returns the tokens each selected file takes in its
+// inclusion mode, for totals and budget fitting. Files written in full keep
+// their count from the token map.
+func (m *Model) selectionTokens() map[string]int {
+	if m.tokens == nil {
+		return nil
+	}
+	tokens := make(map[string]int)
+	for _, node := range domain.GetSelectedFiles(m.tree.Root, m.state) {
+		// Until a file's mode is counted its full count stands in for it
+		tokens[node.Path] = m.tokens[node.Path]
+		if domain.IsSkipped(node, m.state) || m.effectiveMode(node) == domain.IncludeFull {
+			continue
+		}
+		if n, ok := m.modeTokenCount(node); ok {
+			tokens[node.Path] = n
+		}
+	}
+	return tokens
+}
+
+// modeBadge is the marker shown beside a file with its own inclusion mode
+func modeBadge(mode domain.InclusionMode) string {
+	switch mode {
+	case domain.IncludeFull:
+		return ""
+	case domain.IncludePath:
+		return " [path only]"
+	}
+	return fmt.Sprintf(" [%s]", mode)
+}
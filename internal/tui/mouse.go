@@ -0,0 +1,93 @@
+package tui
+
+import (
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/eliooooooot/picky/internal/domain"
+)
+
+const (
+	// wheelLines is how far one wheel notch scrolls the tree
+	wheelLines = 3
+
+	// Tree rows start with a 4-column enumerator ("└── ") and indent 3 columns per level
+	treeEnumeratorWidth = 4
+	treeIndentWidth     = 3
+)
+
+// rowHit says which part of a tree row was clicked
+type rowHit int
+
+const (
+	hitLabel rowHit = iota
+	hitCheckbox
+	hitArrow
+)
+
+// updateMouse handles clicks and wheel scrolling in the tree view
+func (m *Model) updateMouse(msg tea.MouseMsg) (tea.Model, tea.Cmd) {
+	// Modals, prompt mode and the command and visual modes own the screen
+	if m.inPromptMode || m.inCommandMode || m.inSearchInput || m.isSettingsOpen || m.isHelpOpen || m.budgetPlan != nil || m.depsPlan != nil || m.searchResults != nil || m.visualAnchor != "" {
+		return m, nil
+	}
+	
+	switch msg.Button {
+	case tea.MouseButtonWheelUp:
+		m.vp.ScrollUp(wheelLines)
+		return m, nil
+	case tea.MouseButtonWheelDown:
+		m.vp.ScrollDown(wheelLines)
+		return m, nil
+	case tea.MouseButtonLeft:
+		if msg.Action != tea.MouseActionPress {
+			return m, nil
+		}
+	default:
+		return m, nil
+	}
+	
+	node := m.nodeAtRow(msg.Y)
+	if node == nil {
+		return m, nil
+	}
+	
+	m.state = m.state.SetCursor(node.Path)
+	switch m.hitTest(node, msg.X) {
+	case hitCheckbox:
+		m.toggleSelection()
+	case hitArrow:
+		if !node.IsLoaded() {
+			return m, m.loadDir(node)
+		}
+		m.state = m.state.SetOpen(node.Path, !m.state.IsOpen(node.Path))
+	}
+	m.ensureCursorVisible()
+	return m, nil
+}
+
+// nodeAtRow maps a screen row to a node using the flattened order,
+// which matches the rendered tree line for line
+func (m *Model) nodeAtRow(y int) *domain.Node {
+	row := y - m.treeTop
+	if row < 0 || row >= m.vp.Height {
+		return nil
+	}
+	flat := domain.Flatten(m.tree.Root, m.state)
+	idx := row + m.vp.YOffset
+	if idx < 0 || idx >= len(flat) {
+		return nil
+	}
+	return flat[idx]
+}
+
+// hitTest works out whether x falls on a row's checkbox, arrow or name
+func (m *Model) hitTest(node *domain.Node, x int) rowHit {
+	// Labels read "✓ ▶ name": checkbox, space, arrow (or 2-column emoji), space
+	labelCol := treeEnumeratorWidth + treeIndentWidth*getDepth(node)
+	switch {
+	case x == labelCol || x == labelCol-1:
+		return hitCheckbox
+	case node.IsDir && (x == labelCol+2 || x == labelCol+3):
+		return hitArrow
+	}
+	return hitLabel
+}
@@ -0,0 +1,63 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/eliooooooot/picky/internal/domain"
+)
+
+// toggleSelection toggles the cursor node
+func (m *Model) toggleSelection() {
+	m.setSelection(domain.ToggleSelection(m.tree.Root, m.state))
+}
+
+// setSelection replaces the view state after a selection change. With paired
+// tests enabled, every file that became selected brings its paired tests or
+// source along, however it was selected.
+func (m *Model) setSelection(next domain.ViewState) {
+	prev := m.state
+	m.state = next
+	if !m.settings.PairTests {
+		return
+	}
+	var paired []*domain.Node
+	for _, node := range domain.GetSelectedFiles(m.tree.Root, next) {
+		if !prev.IsSelected(node.Path) {
+			paired = append(paired, domain.PairedFiles(node, m.pairRules)...)
+		}
+	}
+	if len(paired) > 0 {
+		m.state = domain.SetRangeSelected(paired, m.state, true)
+	}
+}
+
+// togglePaired selects the files paired with the cursor's file, or
+// deselects them if they are all selected already
+func (m *Model) togglePaired() tea.Cmd {
+	cursor := domain.FindNodeByPath(m.tree.Root, m.state.CursorPath)
+	if cursor == nil || cursor.IsDir {
+		return nil
+	}
+	paired := domain.PairedFiles(cursor, m.pairRules)
+	if len(paired) == 0 {
+		return m.flashStatus(fmt.Sprintf("No paired file for %s", cursor.Name))
+	}
+
+	selected := false
+	names := make([]string, len(paired))
+	for i, node := range paired {
+		names[i] = node.Name
+		if !m.state.IsSelected(node.Path) {
+			selected = true
+		}
+	}
+	m.state = domain.SetRangeSelected(paired, m.state, selected)
+
+	verb := "Deselected"
+	if selected {
+		verb = "Selected"
+	}
+	return m.flashStatus(fmt.Sprintf("%s %s", verb, strings.Join(names, ", ")))
+}
@@ -0,0 +1,214 @@
+package tui
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/eliooooooot/picky/internal/domain"
+)
+
+// searchListHeight is how many results the search modal shows at once
+const searchListHeight = 10
+
+// newSearchInput creates the single-line input for content searches
+func newSearchInput() textinput.Model {
+	ti := textinput.New()
+	ti.Prompt = "/"
+	ti.Placeholder = "regular expression, e.g. RateLimiter"
+	ti.CharLimit = 512
+	return ti
+}
+
+// openSearch shows the search input
+func (m *Model) openSearch() tea.Cmd {
+	m.inSearchInput = true
+	m.searchInput.Reset()
+	return m.searchInput.Focus()
+}
+
+// updateSearchInput handles keyboard input while the search query is typed
+func (m *Model) updateSearchInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch {
+	case key.Matches(msg, m.keys.Cancel):
+		m.inSearchInput = false
+		m.searchInput.Blur()
+		return m, nil
+	case msg.Type == tea.KeyEnter:
+		m.inSearchInput = false
+		m.searchInput.Blur()
+		query := m.searchInput.Value()
+		if query == "" {
+			return m, nil
+		}
+		re, err := regexp.Compile(query)
+		if err != nil {
+			return m, m.flashStatus(fmt.Sprintf("Invalid regex: %v", err))
+		}
+		return m, m.startSearch(query, re)
+	}
+	var cmd tea.Cmd
+	m.searchInput, cmd = m.searchInput.Update(msg)
+	return m, cmd
+}
+
+// searchDoneMsg carries the results of a content search run in the background
+type searchDoneMsg struct {
+	gen     int
+	query   string
+	search  *domain.ContentSearch
+	results []domain.SearchResult
+}
+
+// startSearch reads the files for a content search in the background,
+// since a large or lazily loaded tree takes too long to search while
+// handling a key. A newer search replaces one still running.
+func (m *Model) startSearch(query string, re *regexp.Regexp) tea.Cmd {
+	m.searchGen++
+	gen := m.searchGen
+	search := domain.NewContentSearch(m.tree.Root, m.state, m.fsys, re, m.maxFileSize)
+	m.statusMessage = fmt.Sprintf("Searching for %q…", query)
+	m.statusMessageTimer = 1
+	return func() tea.Msg {
+		return searchDoneMsg{gen: gen, query: query, search: search, results: search.Run()}
+	}
+}
+
+// showSearchResults opens the results of the latest content search,
+// adding the directories leading to matches to the tree
+func (m *Model) showSearchResults(msg searchDoneMsg) tea.Cmd {
+	if msg.gen != m.searchGen {
+		return nil
+	}
+	results := msg.search.Attach(m.tree.Root, msg.results)
+	m.syncLoaded()
+	m.vp.SetContent(m.renderWholeTree())
+	if len(results) == 0 {
+		return m.flashStatus(fmt.Sprintf("No files match %q", msg.query))
+	}
+	m.statusMessage = ""
+	m.statusMessageTimer = 0
+	m.searchQuery = msg.query
+	m.searchResults = results
+	m.searchCursor = 0
+	return nil
+}
+
+// updateSearchResults handles keyboard input while search results are shown
+func (m *Model) updateSearchResults(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch {
+	case key.Matches(msg, m.keys.Up):
+		if m.searchCursor > 0 {
+			m.searchCursor--
+		}
+	case key.Matches(msg, m.keys.Down):
+		if m.searchCursor < len(m.searchResults)-1 {
+			m.searchCursor++
+		}
+	case key.Matches(msg, m.keys.Toggle):
+		// Toggle just the highlighted file and keep browsing
+		result := m.searchResults[m.searchCursor]
+		if m.state.IsSelected(result.Node.Path) {
+			m.state = domain.SetRangeSelected([]*domain.Node{result.Node}, m.state, false)
+			break
+		}
+		m.setSelection(domain.SelectMatches(m.searchResults[m.searchCursor:m.searchCursor+1], m.state, m.fsys, false))
+	case key.Matches(msg, m.keys.Confirm):
+		m.setSelection(domain.SelectMatches(m.searchResults, m.state, m.fsys, false))
+		n := len(m.searchResults)
+		m.searchResults = nil
+		return m, m.flashStatus(fmt.Sprintf("Selected %d files matching %q", n, m.searchQuery))
+	case key.Matches(msg, m.keys.SelectFuncs):
+		m.setSelection(domain.SelectMatches(m.searchResults, m.state, m.fsys, true))
+		n := len(m.searchResults)
+		m.searchResults = nil
+		return m, m.flashStatus(fmt.Sprintf("Selected matches in %d files (Go files by declaration)", n))
+	case key.Matches(msg, m.keys.Cancel, m.keys.Search):
+		m.searchResults = nil
+	}
+	return m, nil
+}
+
+// flashStatus shows a status message and clears it after two seconds
+func (m *Model) flashStatus(msg string) tea.Cmd {
+	m.statusMessage = msg
+	m.statusMessageTimer = 1
+	return tea.Tick(2*time.Second, func(time.Time) tea.Msg {
+		return clearStatusMsg{}
+	})
+}
+
+// renderSearchResults renders the matching files with counts and a preview
+func (m *Model) renderSearchResults() string {
+	scheme := m.settings.ColorScheme
+	modalStyle := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(scheme.promptBorder(false)).
+		Padding(1, 2).
+		Width(70)
+	const lineWidth = 64
+
+	total := 0
+	for _, r := range m.searchResults {
+		total += len(r.Matches)
+	}
+
+	var content strings.Builder
+	content.WriteString(scheme.headerStyle().Render(fmt.Sprintf("Search %q", m.searchQuery)))
+	content.WriteString("\n")
+	content.WriteString(fmt.Sprintf("%d matches in %d files", total, len(m.searchResults)))
+	content.WriteString("\n\n")
+
+	// Keep the highlighted result inside the visible window
+	start := 0
+	if m.searchCursor >= searchListHeight {
+		start = m.searchCursor - searchListHeight + 1
+	}
+	end := min(start+searchListHeight, len(m.searchResults))
+
+	previewStyle := scheme.helpStyle()
+	for i := start; i < end; i++ {
+		r := m.searchResults[i]
+		check := "[ ]"
+		if m.state.IsSelected(r.Node.Path) {
+			check = "[✓]"
+		}
+		line := truncateText(fmt.Sprintf("%s %s (%d)", check, m.relPath(r.Node.Path), len(r.Matches)), lineWidth)
+		if i == m.searchCursor {
+			line = scheme.modalSelectedStyle().Render(line)
+		}
+		content.WriteString(line)
+		content.WriteString("\n")
+
+		first := r.Matches[0]
+		preview := fmt.Sprintf("    %d: %s", first.Line, strings.TrimSpace(first.Text))
+		content.WriteString(previewStyle.Render(truncateText(preview, lineWidth)))
+		content.WriteString("\n")
+	}
+	if len(m.searchResults) > end {
+		content.WriteString(previewStyle.Render(fmt.Sprintf("… %d more", len(m.searchResults)-end)))
+		content.WriteString("\n")
+	}
+	content.WriteString("\n")
+
+	helpStyle := scheme.helpStyle().Italic(true)
+	content.WriteString(helpStyle.Render(fmt.Sprintf("%s/%s navigate  %s toggle one  %s select all  %s functions  %s close",
+		m.keys.Up.Help().Key, m.keys.Down.Help().Key, m.keys.Toggle.Help().Key,
+		m.keys.Confirm.Help().Key, m.keys.SelectFuncs.Help().Key, m.keys.Cancel.Help().Key)))
+
+	return modalStyle.Render(content.String())
+}
+
+// truncateText shortens s to at most width runes, marking the cut with an ellipsis
+func truncateText(s string, width int) string {
+	runes := []rune(s)
+	if len(runes) <= width {
+		return s
+	}
+	return string(runes[:width-1]) + "…"
+}
@@ -0,0 +1,92 @@
+package tui
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/eliooooooot/picky/internal/domain"
+	"github.com/eliooooooot/picky/internal/generate"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// secretsScannedMsg carries the credential counts of files scanned in the
+// background
+type secretsScannedMsg struct {
+	counts map[string]int
+}
+
+// secretCount returns how many credentials a file appears to contain. Files
+// are queued for a background scan the first time their row is drawn and
+// count as clean until the result arrives.
+func (m *Model) secretCount(node *domain.Node) int {
+	if node.IsDir || domain.IsSkipped(node, m.state) || domain.IsOversized(node, m.maxFileSize) {
+		return 0
+	}
+	if n, ok := m.secrets[node.Path]; ok {
+		return n
+	}
+	if !m.scanning[node.Path] {
+		if m.scanQueue == nil {
+			m.scanQueue = make(map[string]bool)
+		}
+		m.scanQueue[node.Path] = true
+	}
+	return 0
+}
+
+// scanSecrets starts scanning the files queued since the last call
+func (m *Model) scanSecrets() tea.Cmd {
+	if len(m.scanQueue) == 0 {
+		return nil
+	}
+	if m.scanning == nil {
+		m.scanning = make(map[string]bool)
+	}
+	var paths []string
+	for path := range m.scanQueue {
+		paths = append(paths, path)
+		m.scanning[path] = true
+	}
+	m.scanQueue = nil
+	fsys := m.fsys
+	return func() tea.Msg {
+		counts := make(map[string]int, len(paths))
+		for _, path := range paths {
+			// Unreadable files count as clean
+			counts[path] = 0
+			if content, err := fsys.ReadFile(path); err == nil {
+				counts[path] = len(generate.ScanSecrets(filepath.Base(path), content))
+			}
+		}
+		return secretsScannedMsg{counts: counts}
+	}
+}
+
+// addSecrets stores background scan results and redraws the flagged rows
+func (m *Model) addSecrets(msg secretsScannedMsg) {
+	if m.secrets == nil {
+		m.secrets = make(map[string]int)
+	}
+	for path, n := range msg.counts {
+		m.secrets[path] = n
+		delete(m.scanning, path)
+	}
+	m.vp.SetContent(m.renderWholeTree())
+}
+
+// secretBadge is the warning shown beside a file with credentials in it
+func (m *Model) secretBadge(node *domain.Node) string {
+	n := m.secretCount(node)
+	if n == 0 {
+		return ""
+	}
+	noun := "secrets"
+	if n == 1 {
+		noun = "secret"
+	}
+	if m.settings.Emoji {
+		return fmt.Sprintf(" ⚠️ %d %s", n, noun)
+	}
+	return fmt.Sprintf(" [! %d %s]", n, noun)
+}
@@ -1,27 +1,197 @@
 package tui
 
-import "github.com/charmbracelet/lipgloss"
+import (
+	"fmt"
+	"strings"
 
-// ColorScheme defines the three base colors for the tree UI
+	"github.com/charmbracelet/lipgloss"
+	"github.com/eliooooooot/picky/internal/domain"
+	"github.com/eliooooooot/picky/internal/generate"
+	"github.com/eliooooooot/picky/internal/token"
+)
+
+// ColorScheme defines the colors for the tree UI
+// Colors are ANSI 256 codes ("39") or truecolor hex values ("#1e90ff")
 type ColorScheme struct {
 	Name               string
 	Selected           lipgloss.Color
 	PartiallySelected  lipgloss.Color
 	Unselected         lipgloss.Color
+	
+	// CursorFg and CursorBg color the row under the cursor
+	// An empty CursorBg uses the row's selection color
+	CursorFg lipgloss.Color
+	CursorBg lipgloss.Color
+	
+	// Directory and File color unselected names; empty uses Unselected
+	Directory lipgloss.Color
+	File      lipgloss.Color
+	
+	Header             lipgloss.Color
+	Help               lipgloss.Color
+	Status             lipgloss.Color
+	PromptBorder       lipgloss.Color
+	PromptBorderActive lipgloss.Color
+	
+	// NoColor renders with text attributes only, for NO_COLOR terminals
+	NoColor bool
 }
 
+// TokenDisplay controls how token counts are shown next to tree labels
+type TokenDisplay string
+
+const (
+	TokenDisplayCompact TokenDisplay = "compact" // 1.2k
+	TokenDisplayExact   TokenDisplay = "exact"   // 1234
+	TokenDisplayOff     TokenDisplay = "off"
+)
+
+// TokenDisplays lists the token display modes in cycling order
+var TokenDisplays = []TokenDisplay{TokenDisplayCompact, TokenDisplayExact, TokenDisplayOff}
+
 // Settings stores user preferences for the TUI
 type Settings struct {
-	Emoji       bool
-	ColorScheme ColorScheme
+	Emoji         bool
+	ColorScheme   ColorScheme
+	Format        generate.Format
+	Tokenizer     string
+	ShowHidden    bool
+	SortOrder     domain.SortOrder
+	SortDirection domain.SortDirection
+	TokenDisplay  TokenDisplay
+	// Columns shows size, tokens and age in columns beside the tree
+	Columns bool
+	// PairTests selects a file's paired tests (or source) along with it
+	PairTests bool
 }
 
-// defaultSettings returns Settings with sane defaults
-func defaultSettings() Settings {
+// DefaultSettings returns Settings with sane defaults
+func DefaultSettings() Settings {
 	return Settings{
-		Emoji:       false,
-		ColorScheme: colorSchemes[0], // Default to first scheme
+		Emoji:         false,
+		ColorScheme:   colorSchemes[0], // Default to first scheme
+		Format:        generate.FormatText,
+		Tokenizer:     token.Names[0],
+		ShowHidden:    true,
+		SortOrder:     domain.SortByName,
+		SortDirection: domain.SortAscending,
+		TokenDisplay:  TokenDisplayCompact,
+	}
+}
+
+// Rows of the settings pane, in display order
+const (
+	settingEmoji = iota
+	settingColorScheme
+	settingFormat
+	settingTokenizer
+	settingShowHidden
+	settingSortOrder
+	settingSortDirection
+	settingTokenDisplay
+	settingColumns
+	settingPairTests
+	settingCount
+)
+
+// isToggleSetting reports whether a settings row is a checkbox rather than a list
+func isToggleSetting(item int) bool {
+	return item == settingEmoji || item == settingShowHidden || item == settingColumns || item == settingPairTests
+}
+
+// Toggle flips a checkbox setting
+func (s Settings) Toggle(item int) Settings {
+	switch item {
+	case settingEmoji:
+		s = s.ToggleEmoji()
+	case settingShowHidden:
+		s.ShowHidden = !s.ShowHidden
+	case settingColumns:
+		s.Columns = !s.Columns
+	case settingPairTests:
+		s.PairTests = !s.PairTests
+	}
+	return s
+}
+
+// Cycle moves a list setting forward (delta 1) or backward (delta -1)
+func (s Settings) Cycle(item, delta int) Settings {
+	switch item {
+	case settingColorScheme:
+		if delta < 0 {
+			return s.PrevColorScheme()
+		}
+		return s.NextColorScheme()
+	case settingFormat:
+		s.Format = cycle(generate.Formats, s.Format, delta)
+	case settingTokenizer:
+		s.Tokenizer = cycle(token.Names, s.Tokenizer, delta)
+	case settingSortOrder:
+		s.SortOrder = cycle(domain.SortOrders, s.SortOrder, delta)
+	case settingSortDirection:
+		s.SortDirection = cycle(domain.SortDirections, s.SortDirection, delta)
+	case settingTokenDisplay:
+		s.TokenDisplay = cycle(TokenDisplays, s.TokenDisplay, delta)
+	}
+	return s
+}
+
+// settingLine renders one row of the settings pane
+func (s Settings) settingLine(item int) string {
+	check := func(on bool, label string) string {
+		if on {
+			return "[x] " + label
+		}
+		return "[ ] " + label
+	}
+	choice := func(label string, value any) string {
+		return fmt.Sprintf("%s: ← %v →", label, value)
+	}
+	
+	switch item {
+	case settingEmoji:
+		return check(s.Emoji, "Emoji icons")
+	case settingColorScheme:
+		return choice("Color scheme", s.ColorScheme.Name)
+	case settingFormat:
+		return choice("Output format", s.Format)
+	case settingTokenizer:
+		return choice("Tokenizer", s.Tokenizer)
+	case settingShowHidden:
+		return check(s.ShowHidden, "Show hidden files")
+	case settingSortOrder:
+		return choice("Sort order", s.SortOrder)
+	case settingSortDirection:
+		return choice("Sort direction", s.SortDirection)
+	case settingTokenDisplay:
+		return choice("Token counts", s.TokenDisplay)
+	case settingColumns:
+		return check(s.Columns, "Size, token and age columns")
+	case settingPairTests:
+		return check(s.PairTests, "Select paired tests")
 	}
+	return ""
+}
+
+// cycle returns the value delta steps from current, wrapping around.
+// An unknown current value starts from the first entry.
+func cycle[T comparable](values []T, current T, delta int) T {
+	for i, v := range values {
+		if v == current {
+			return values[(i+delta+len(values))%len(values)]
+		}
+	}
+	return values[0]
+}
+
+// ColorSchemeByName looks up a built-in color scheme, ignoring case
+func ColorSchemeByName(name string) (ColorScheme, bool) {
+	for _, scheme := range colorSchemes {
+		if strings.EqualFold(scheme.Name, name) {
+			return scheme, true
+		}
+	}
+	return ColorScheme{}, false
 }
 
 // ToggleEmoji returns a copy with Emoji toggled
@@ -58,40 +228,57 @@ func (s Settings) PrevColorScheme() Settings {
 
 // Available color schemes
 var colorSchemes = []ColorScheme{
-	{
+	withDefaultRoles(ColorScheme{
 		Name:              "Ocean",
 		Selected:          lipgloss.Color("39"),  // Bright blue
 		PartiallySelected: lipgloss.Color("45"),  // Cyan
 		Unselected:        lipgloss.Color("243"), // Gray
-	},
-	{
+	}),
+	withDefaultRoles(ColorScheme{
 		Name:              "Classic",
 		Selected:          lipgloss.Color("237"), // Dark gray (original cursor bg)
 		PartiallySelected: lipgloss.Color("240"), // Medium gray
 		Unselected:        lipgloss.Color("245"), // Light gray
-	},
-	{
+		CursorFg:          lipgloss.Color("255"), // White text on the dark cursor
+	}),
+	withDefaultRoles(ColorScheme{
 		Name:              "Forest",
 		Selected:          lipgloss.Color("64"),  // Medium sage green
 		PartiallySelected: lipgloss.Color("107"), // Soft green
 		Unselected:        lipgloss.Color("243"), // Medium gray
-	},
-	{
+	}),
+	withDefaultRoles(ColorScheme{
 		Name:              "Sunset",
 		Selected:          lipgloss.Color("202"), // Orange
 		PartiallySelected: lipgloss.Color("214"), // Light orange
 		Unselected:        lipgloss.Color("244"), // Medium gray
-	},
-	{
+	}),
+	withDefaultRoles(ColorScheme{
 		Name:              "Monochrome",
 		Selected:          lipgloss.Color("255"), // White
 		PartiallySelected: lipgloss.Color("250"), // Light gray
 		Unselected:        lipgloss.Color("240"), // Dark gray
-	},
-	{
+	}),
+	withDefaultRoles(ColorScheme{
 		Name:              "Neon",
 		Selected:          lipgloss.Color("201"), // Bright magenta
 		PartiallySelected: lipgloss.Color("99"),  // Purple
 		Unselected:        lipgloss.Color("242"), // Gray
+	}),
+	withDefaultRoles(ColorScheme{
+		Name:              "Daylight",
+		Selected:          lipgloss.Color("25"),  // Deep blue
+		PartiallySelected: lipgloss.Color("30"),  // Teal
+		Unselected:        lipgloss.Color("242"), // Gray
+		CursorFg:          lipgloss.Color("255"), // White
+		Header:            lipgloss.Color("25"),
+		Help:              lipgloss.Color("244"),
+		Status:            lipgloss.Color("130"), // Brown
+		PromptBorder:      lipgloss.Color("250"),
+		PromptBorderActive: lipgloss.Color("25"),
+	}),
+	{
+		Name:    NoColorSchemeName,
+		NoColor: true,
 	},
-}
\ No newline at end of file
+}
@@ -2,104 +2,109 @@ package tui
 
 import "github.com/charmbracelet/lipgloss"
 
-// Color scheme using ANSI 256 color codes for better compatibility
+// Default colors for the roles a color scheme leaves unset
 var (
-	// Selection colors
-	FullySelectedColor    = lipgloss.Color("40")  // Bright green for fully selected
-	PartiallySelectedColor = lipgloss.Color("220") // Amber/yellow for partially selected
-	NotSelectedColor      = lipgloss.Color("245")  // Light gray for not selected
-	
-	// Cursor/focus colors
-	CursorBgColor        = lipgloss.Color("237")  // Dark gray background
-	CursorSelectedBg     = lipgloss.Color("22")   // Dark green background for selected+cursor
-	CursorPartialBg      = lipgloss.Color("94")   // Dark amber background for partial+cursor
-	
-	// Text colors
-	FileNameColor        = lipgloss.Color("252")  // Very light gray
-	DirectoryNameColor   = lipgloss.Color("33")   // Blue for directories
-	CursorTextColor      = lipgloss.Color("255")  // White for cursor text
-	
-	// UI element colors
-	HeaderColor          = lipgloss.Color("39")   // Bright blue
-	HelpTextColor        = lipgloss.Color("241")  // Gray
-	StatusMessageColor   = lipgloss.Color("214")  // Orange for status
-	
-	// Indicator colors
-	CheckmarkColor       = lipgloss.Color("40")   // Green checkmark
-	PartialCheckColor    = lipgloss.Color("220")  // Yellow dash
-	DirectoryArrowColor  = lipgloss.Color("245")  // Gray arrows
+	CursorTextColor    = lipgloss.Color("0")   // Black text on the cursor row
+	HeaderColor        = lipgloss.Color("6")   // Cyan
+	HelpTextColor      = lipgloss.Color("241") // Gray
+	StatusMessageColor = lipgloss.Color("3")   // Yellow
+
+	// Highlighted row inside modals
+	ModalSelectedBg = lipgloss.Color("238")
+	ModalSelectedFg = lipgloss.Color("255")
+
+	// Rows inside a visual range
+	VisualBgColor = lipgloss.Color("236")
+
+	// Fit-to-budget preview
+	DropColor     = lipgloss.Color("1") // Red for dropped files
+	TruncateColor = lipgloss.Color("3") // Yellow for truncated files
 )
 
-// Styles
+// Prompt mode styles
 var (
-	// Base styles
-	HeaderStyle = lipgloss.NewStyle().
-		Bold(true).
-		Foreground(HeaderColor)
-	
-	HelpStyle = lipgloss.NewStyle().
-		Foreground(HelpTextColor)
-	
-	StatusStyle = lipgloss.NewStyle().
-		Foreground(StatusMessageColor).
-		Bold(true)
-	
-	// File/Directory name styles
-	FileStyle = lipgloss.NewStyle().
-		Foreground(FileNameColor)
-	
-	DirectoryStyle = lipgloss.NewStyle().
-		Foreground(DirectoryNameColor).
-		Bold(true)
-	
-	// Selection indicator styles
-	SelectedIndicatorStyle = lipgloss.NewStyle().
-		Foreground(CheckmarkColor).
-		Bold(true)
-	
-	PartialIndicatorStyle = lipgloss.NewStyle().
-		Foreground(PartialCheckColor).
-		Bold(true)
-	
-	// Cursor styles (without selection)
-	CursorStyle = lipgloss.NewStyle().
-		Background(CursorBgColor).
-		Foreground(CursorTextColor)
-	
-	CursorFileStyle = CursorStyle.Copy()
-	
-	CursorDirectoryStyle = CursorStyle.Copy().
-		Foreground(DirectoryNameColor).
-		Bold(true)
-	
-	// Cursor + selection combination styles
-	CursorSelectedStyle = lipgloss.NewStyle().
-		Background(CursorSelectedBg).
-		Foreground(CursorTextColor).
-		Bold(true)
-	
-	CursorPartialStyle = lipgloss.NewStyle().
-		Background(CursorPartialBg).
-		Foreground(CursorTextColor)
-	
-	// Selected item styles (without cursor)
-	SelectedFileStyle = lipgloss.NewStyle().
-		Foreground(FullySelectedColor)
-	
-	SelectedDirectoryStyle = lipgloss.NewStyle().
-		Foreground(FullySelectedColor).
-		Bold(true)
-	
-	PartialDirectoryStyle = lipgloss.NewStyle().
-		Foreground(PartiallySelectedColor).
-		Bold(true)
-	
-	// Directory arrow style
-	ArrowStyle = lipgloss.NewStyle().
-		Foreground(DirectoryArrowColor)
-	
-	// Prompt mode styles
 	DimmedStyle     = lipgloss.NewStyle().Faint(true)
 	PromptBorderDim = lipgloss.Color("240")
 	PromptBorderLit = lipgloss.Color("33") // cyan, matches header
-)
\ No newline at end of file
+)
+
+// withDefaultRoles fills the UI roles a scheme leaves unset
+func withDefaultRoles(c ColorScheme) ColorScheme {
+	if c.CursorFg == "" {
+		c.CursorFg = CursorTextColor
+	}
+	if c.Header == "" {
+		c.Header = HeaderColor
+	}
+	if c.Help == "" {
+		c.Help = HelpTextColor
+	}
+	if c.Status == "" {
+		c.Status = StatusMessageColor
+	}
+	if c.PromptBorder == "" {
+		c.PromptBorder = PromptBorderDim
+	}
+	if c.PromptBorderActive == "" {
+		c.PromptBorderActive = PromptBorderLit
+	}
+	return c
+}
+
+// fg returns a style with the given foreground, or a plain style in NoColor mode
+func (c ColorScheme) fg(color lipgloss.Color) lipgloss.Style {
+	if c.NoColor || color == "" {
+		return lipgloss.NewStyle()
+	}
+	return lipgloss.NewStyle().Foreground(color)
+}
+
+// color returns a color usable for borders, or no color in NoColor mode
+func (c ColorScheme) color(color lipgloss.Color) lipgloss.TerminalColor {
+	if c.NoColor || color == "" {
+		return lipgloss.NoColor{}
+	}
+	return color
+}
+
+func (c ColorScheme) headerStyle() lipgloss.Style { return c.fg(c.Header).Bold(true) }
+
+func (c ColorScheme) helpStyle() lipgloss.Style { return c.fg(c.Help) }
+
+func (c ColorScheme) statusStyle() lipgloss.Style { return c.fg(c.Status) }
+
+// promptBorder returns the prompt box border color
+func (c ColorScheme) promptBorder(active bool) lipgloss.TerminalColor {
+	if active {
+		return c.color(c.PromptBorderActive)
+	}
+	return c.color(c.PromptBorder)
+}
+
+// cursorStyle styles the row under the cursor; state is the row's selection color
+func (c ColorScheme) cursorStyle(state lipgloss.Color) lipgloss.Style {
+	if c.NoColor {
+		return lipgloss.NewStyle().Reverse(true)
+	}
+	bg := c.CursorBg
+	if bg == "" {
+		bg = state
+	}
+	return lipgloss.NewStyle().Background(bg).Foreground(c.CursorFg)
+}
+
+// modalSelectedStyle styles the highlighted row inside modals
+func (c ColorScheme) modalSelectedStyle() lipgloss.Style {
+	if c.NoColor {
+		return lipgloss.NewStyle().Reverse(true)
+	}
+	return lipgloss.NewStyle().Background(ModalSelectedBg).Foreground(ModalSelectedFg)
+}
+
+// visualStyle marks rows inside a visual range; state is the row's selection color
+func (c ColorScheme) visualStyle(state lipgloss.Color) lipgloss.Style {
+	if c.NoColor {
+		return lipgloss.NewStyle().Underline(true)
+	}
+	return lipgloss.NewStyle().Background(VisualBgColor).Foreground(state)
+}
@@ -0,0 +1,81 @@
+package tui
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+const (
+	// AutoColorSchemeName picks a dark or light scheme from the terminal background
+	AutoColorSchemeName = "auto"
+	// NoColorSchemeName renders without colors, as NO_COLOR asks
+	NoColorSchemeName = "None"
+
+	darkColorSchemeName  = "Ocean"
+	lightColorSchemeName = "Daylight"
+)
+
+var hexColor = regexp.MustCompile(`^#([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+// ParseColor validates an ANSI 256 code ("39") or hex value ("#1e90ff").
+// The empty string is allowed and means "unset".
+func ParseColor(s string) (lipgloss.Color, error) {
+	s = strings.TrimSpace(s)
+	if s == "" || hexColor.MatchString(s) {
+		return lipgloss.Color(s), nil
+	}
+	if n, err := strconv.Atoi(s); err == nil && n >= 0 && n <= 255 {
+		return lipgloss.Color(s), nil
+	}
+	return "", fmt.Errorf("invalid color %q (want 0-255 or #rrggbb)", s)
+}
+
+// NewColorScheme fills the unset colors of scheme from base and the defaults
+func NewColorScheme(scheme, base ColorScheme) ColorScheme {
+	fill := func(c *lipgloss.Color, from lipgloss.Color) {
+		if *c == "" {
+			*c = from
+		}
+	}
+	fill(&scheme.Selected, base.Selected)
+	fill(&scheme.PartiallySelected, base.PartiallySelected)
+	fill(&scheme.Unselected, base.Unselected)
+	fill(&scheme.CursorFg, base.CursorFg)
+	fill(&scheme.CursorBg, base.CursorBg)
+	fill(&scheme.Directory, base.Directory)
+	fill(&scheme.File, base.File)
+	fill(&scheme.Header, base.Header)
+	fill(&scheme.Help, base.Help)
+	fill(&scheme.Status, base.Status)
+	fill(&scheme.PromptBorder, base.PromptBorder)
+	fill(&scheme.PromptBorderActive, base.PromptBorderActive)
+	return withDefaultRoles(scheme)
+}
+
+// RegisterColorScheme adds a scheme to the settings pane, replacing any
+// built-in or earlier scheme with the same name
+func RegisterColorScheme(scheme ColorScheme) {
+	for i, existing := range colorSchemes {
+		if strings.EqualFold(existing.Name, scheme.Name) {
+			colorSchemes[i] = scheme
+			return
+		}
+	}
+	// Keep the no-color scheme last
+	last := len(colorSchemes) - 1
+	colorSchemes = append(colorSchemes[:last], scheme, colorSchemes[last])
+}
+
+// AutoColorScheme returns the built-in scheme suited to the terminal background
+func AutoColorScheme(darkBackground bool) ColorScheme {
+	name := lightColorSchemeName
+	if darkBackground {
+		name = darkColorSchemeName
+	}
+	scheme, _ := ColorSchemeByName(name)
+	return scheme
+}
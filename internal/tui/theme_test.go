@@ -0,0 +1,50 @@
+package tui_test
+
+import (
+	"testing"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/eliooooooot/picky/internal/tui"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseColor(t *testing.T) {
+	for _, ok := range []string{"", "0", "255", "#abc", "#A0B1C2"} {
+		_, err := tui.ParseColor(ok)
+		assert.NoError(t, err, ok)
+	}
+	for _, bad := range []string{"256", "-1", "#12", "red", "#ggg"} {
+		_, err := tui.ParseColor(bad)
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestAutoColorScheme(t *testing.T) {
+	assert.Equal(t, "Ocean", tui.AutoColorScheme(true).Name)
+	assert.Equal(t, "Daylight", tui.AutoColorScheme(false).Name)
+}
+
+func TestNewColorSchemeFillsFromBase(t *testing.T) {
+	base, ok := tui.ColorSchemeByName("Forest")
+	require.True(t, ok)
+
+	scheme := tui.NewColorScheme(tui.ColorScheme{Name: "Mine", Selected: "#ff0000"}, base)
+	assert.Equal(t, lipgloss.Color("#ff0000"), scheme.Selected)
+	assert.Equal(t, base.Unselected, scheme.Unselected)
+	assert.Equal(t, base.Header, scheme.Header)
+	assert.NotEmpty(t, scheme.CursorFg)
+}
+
+func TestNoColorSchemeStillShowsCursor(t *testing.T) {
+	model := settingsTestModel()
+	s := model.Settings()
+	s.ColorScheme, _ = tui.ColorSchemeByName("none")
+	model.SetSettings(s)
+
+	// The cursor row is reverse video rather than a background color,
+	// which survives terminals that strip colors
+	view := model.View()
+	assert.NotContains(t, view, "\x1b[38;5;")
+	assert.NotContains(t, view, "\x1b[48;5;")
+}
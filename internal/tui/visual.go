@@ -0,0 +1,83 @@
+package tui
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/eliooooooot/picky/internal/domain"
+)
+
+// updateVisual handles keyboard input while a visual range is active.
+// Moving the cursor extends the range; every action applies to it and ends visual mode.
+func (m *Model) updateVisual(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	nodes := domain.NodesBetween(m.tree.Root, m.state, m.visualAnchor, m.state.CursorPath)
+
+	switch {
+	case key.Matches(msg, m.keys.Up):
+		m.state = domain.NavigateUp(m.tree.Root, m.state)
+		m.ensureCursorVisible()
+		return m, nil
+	case key.Matches(msg, m.keys.Down):
+		m.state = domain.NavigateDown(m.tree.Root, m.state)
+		m.ensureCursorVisible()
+		return m, nil
+	case key.Matches(msg, m.keys.Toggle):
+		m.setSelection(domain.SetRangeSelected(nodes, m.state, true))
+	case key.Matches(msg, m.keys.Deselect):
+		m.state = domain.SetRangeSelected(nodes, m.state, false)
+	case key.Matches(msg, m.keys.Invert):
+		m.setSelection(domain.InvertRange(nodes, m.state))
+	case key.Matches(msg, m.keys.Exclude):
+		m.visualAnchor = ""
+		return m, m.excludeRange(nodes)
+	case key.Matches(msg, m.keys.Cancel, m.keys.Visual):
+	default:
+		// Other keys are ignored so the range is not lost by accident
+		return m, nil
+	}
+	m.visualAnchor = ""
+	return m, nil
+}
+
+// excludeRange removes every node in the range from the tree and records them as ignored
+func (m *Model) excludeRange(nodes []*domain.Node) tea.Cmd {
+	if len(nodes) == 0 {
+		return nil
+	}
+
+	// Get the flattened list before exclusion to find next cursor position
+	flatBefore := domain.Flatten(m.tree.Root, m.state)
+	firstIdx := -1
+	for i, node := range flatBefore {
+		if node.Path == nodes[0].Path {
+			firstIdx = i
+			break
+		}
+	}
+
+	excluded := 0
+	for _, node := range domain.TopmostNodes(nodes) {
+		relPath, removed := m.tree.ExcludeNode(node.Path)
+		if removed == nil {
+			continue // the root cannot be excluded
+		}
+		m.newIgnores[relPath] = struct{}{}
+		m.state = m.state.Prune(removed.Path)
+		excluded++
+	}
+	if excluded == 0 {
+		return nil
+	}
+
+	flatAfter := domain.Flatten(m.tree.Root, m.state)
+	m.state = m.state.SetCursor(domain.NextCursorAfterRemoval(flatBefore, firstIdx, flatAfter))
+	m.ensureCursorVisible()
+
+	m.statusMessage = fmt.Sprintf("Excluded %d items", excluded)
+	m.statusMessageTimer = 1
+	return tea.Tick(2*time.Second, func(time.Time) tea.Msg {
+		return clearStatusMsg{}
+	})
+}